@@ -4,44 +4,349 @@
 package forge
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Allele defines the fdo Allele struct.
 type Allele struct {
-	ID     float64 // name of the allele (e.g. 9.3)
-	Area   float64 // peak area size
-	Height float64 // signal strength, i.e. peak height in rfu
-	Size   float64 // fragment length
+	ID     AlleleID // name of the allele (e.g. 9.3)
+	Area   float64  // peak area size
+	Height float64  // signal strength, i.e. peak height in rfu
+	Size   float64  // fragment length
+	// Comment on the allele (e.g. as entered in GeneMapper ID-X).
+	Comment string
+	// Seq holds the sequence of alleles typed by MPS; it is empty for CE data.
+	Seq Sequence
 }
 
 // NewAllele returns an Allele object wit ID id.
-func NewAllele(id float64) Allele {
+func NewAllele(id AlleleID) Allele {
 	return Allele{
 		ID: id,
 	}
 }
 
-// A2Float converts the allele ID from string to float.
-func A2Float(a string) float64 {
-	allele, err := strconv.ParseFloat(a, 64)
+// IsOffLadder returns true if allele a is an off-ladder (OL) call, i.e. a
+// peak that could not be assigned to an allele of the allelic ladder. Its
+// fragment length is still available in a.Size.
+func (a Allele) IsOffLadder() bool {
+	return a.ID.Cat == OFFLADDER
+}
+
+// sameAllele returns true if a and b designate the same allele. Isoalleles,
+// i.e. alleles of the same ID but of different sequence, are different
+// alleles. Off-ladder alleles carry no repeat number, hence they are told
+// apart by their size.
+func sameAllele(a, b Allele) bool {
+	if a.key(SEQUENCE) != b.key(SEQUENCE) {
+		return false
+	}
+	return !a.IsOffLadder() || a.Size == b.Size
+}
+
+// AlleleCategory classifies an allele designation.
+type AlleleCategory int
+
+const (
+	NOALLELE     AlleleCategory = iota // empty designation
+	REGULAR                            // full repeats only, e.g. 12
+	MICROVARIANT                       // partial repeat, e.g. 9.3
+	OFFLADDER                          // off-ladder call (OL)
+	BELOWRANGE                         // out of range below a bound, e.g. <8
+	ABOVERANGE                         // out of range above a bound, e.g. >24
+	XALLELE                            // X of amelogenin
+	YALLELE                            // Y of amelogenin
+	WILDCARD                           // any allele, i.e. possible drop-out (F)
+	NULLALLELE                         // null allele, i.e. primer binding site mutation
+)
+
+// String returns the allele category as string.
+func (c AlleleCategory) String() string {
+	switch c {
+	case REGULAR:
+		return "regular"
+	case MICROVARIANT:
+		return "microvariant"
+	case OFFLADDER:
+		return "off-ladder"
+	case BELOWRANGE, ABOVERANGE:
+		return "out-of-range"
+	case XALLELE:
+		return "X"
+	case YALLELE:
+		return "Y"
+	case WILDCARD:
+		return "wildcard"
+	case NULLALLELE:
+		return "null"
+	default: // NOALLELE
+		return "none"
+	}
+}
+
+// AlleleID is the designation of an allele, such as 12, 9.3, OL, <8, X, or F.
+// The zero value is the empty designation. AlleleIDs are comparable, i.e. they
+// can be compared with == and used as map keys.
+type AlleleID struct {
+	// Cat is the category of the designation.
+	Cat AlleleCategory
+	// Repeats is the number of full repeats of regular alleles and
+	// microvariants, and the bound of out-of-range designations.
+	Repeats int
+	// Partial is the number of bases of a partial repeat (e.g. 3 for 9.3).
+	Partial int
+}
+
+// ParseAlleleID parses the allele designation s. It accepts repeat numbers
+// (e.g. "12", "9.3"), out-of-range designations ("<8", ">24"), "OL", "X",
+// "Y", the wildcard "F", and "Null" (or "Q"). An empty string yields the empty
+// designation.
+func ParseAlleleID(s string) (AlleleID, error) {
+
+	s = strings.TrimSpace(s)
+	switch strings.ToUpper(s) {
+	case "":
+		return AlleleID{}, nil
+	case "X":
+		return AlleleID{Cat: XALLELE}, nil
+	case "Y":
+		return AlleleID{Cat: YALLELE}, nil
+	case "OL":
+		return AlleleID{Cat: OFFLADDER}, nil
+	case "F":
+		return AlleleID{Cat: WILDCARD}, nil
+	case "NULL", "Q":
+		return AlleleID{Cat: NULLALLELE}, nil
+	}
+
+	cat := REGULAR
+	switch s[0] {
+	case '<':
+		cat = BELOWRANGE
+		s = s[1:]
+	case '>':
+		cat = ABOVERANGE
+		s = s[1:]
+	}
+
+	id, err := parseRepeats(s)
 	if err != nil {
-		switch a {
-		case "":
-			allele = 0
-		case "X", "x":
-			allele = -2.0
-		case "Y", "y":
-			allele = -1.0
-		default: // nonsense id (e.g. 'OL')
-			allele = -999
+		return AlleleID{}, fmt.Errorf("invalid allele designation %q: %v", s, err)
+	}
+
+	if cat == REGULAR && id.Partial > 0 {
+		cat = MICROVARIANT
+	}
+	id.Cat = cat
+
+	return id, nil
+}
+
+// parseRepeats parses a repeat number such as 9.3 into the number of full
+// repeats and the bases of the partial repeat. Trailing zeros of the partial
+// repeat are ignored, i.e. 9.30 equals 9.3.
+func parseRepeats(s string) (AlleleID, error) {
+
+	full, partial, hasPartial := strings.Cut(s, ".")
+	if hasPartial {
+		partial = strings.TrimRight(partial, "0")
+	}
+
+	r, err := strconv.Atoi(full)
+	if err != nil || r < 0 || strings.HasPrefix(full, "+") {
+		return AlleleID{}, fmt.Errorf("no repeat number")
+	}
+
+	var p int
+	if partial != "" {
+		p, err = strconv.Atoi(partial)
+		if err != nil || p < 0 || len(partial) > 1 {
+			return AlleleID{}, fmt.Errorf("no partial repeat")
 		}
 	}
 
-	return allele
+	return AlleleID{Repeats: r, Partial: p}, nil
+}
+
+// A2ID converts the allele designation a to an AlleleID. Unlike
+// ParseAlleleID, it never fails: designations it cannot read (e.g. 'OL' with
+// a typo) are treated as off-ladder calls.
+func A2ID(a string) AlleleID {
+	id, err := ParseAlleleID(a)
+	if err != nil {
+		return AlleleID{Cat: OFFLADDER}
+	}
+	return id
+}
+
+// String returns the designation of id such that ParseAlleleID(id.String())
+// equals id.
+func (id AlleleID) String() string {
+	switch id.Cat {
+	case REGULAR:
+		return strconv.Itoa(id.Repeats)
+	case MICROVARIANT:
+		return strconv.Itoa(id.Repeats) + "." + strconv.Itoa(id.Partial)
+	case OFFLADDER:
+		return "OL"
+	case BELOWRANGE:
+		return "<" + id.repeatString()
+	case ABOVERANGE:
+		return ">" + id.repeatString()
+	case XALLELE:
+		return "X"
+	case YALLELE:
+		return "Y"
+	case WILDCARD:
+		return "F"
+	case NULLALLELE:
+		return "Null"
+	default: // NOALLELE
+		return ""
+	}
+}
+
+// repeatString returns the repeat number of id, e.g. 9 or 9.3.
+func (id AlleleID) repeatString() string {
+	if id.Partial == 0 {
+		return strconv.Itoa(id.Repeats)
+	}
+	return strconv.Itoa(id.Repeats) + "." + strconv.Itoa(id.Partial)
+}
+
+// IsZero returns true if id is the empty designation.
+func (id AlleleID) IsZero() bool {
+	return id.Cat == NOALLELE
+}
+
+// IsWildcard returns true if id is the wildcard F, i.e. any allele.
+func (id AlleleID) IsWildcard() bool {
+	return id.Cat == WILDCARD
+}
+
+// HasRepeats returns true if id is designated by its repeat number, i.e. it
+// is a regular allele or a microvariant.
+func (id AlleleID) HasRepeats() bool {
+	return id.Cat == REGULAR || id.Cat == MICROVARIANT
+}
+
+// IsTyped returns true if id designates an allele that can have a population
+// frequency, i.e. all designations except the empty one, off-ladder calls, the
+// wildcard F, and null alleles.
+func (id AlleleID) IsTyped() bool {
+	switch id.Cat {
+	case NOALLELE, OFFLADDER, WILDCARD, NULLALLELE:
+		return false
+	default:
+		return true
+	}
+}
+
+// Shift returns the designation n full repeats away from id (e.g. the -1
+// stutter position of 12 is 11). Only alleles with repeats can be shifted; for
+// all other designations and for negative repeat numbers the empty designation
+// is returned.
+func (id AlleleID) Shift(n int) AlleleID {
+	if !id.HasRepeats() || id.Repeats+n < 0 {
+		return AlleleID{}
+	}
+	id.Repeats += n
+	return id
+}
+
+// Float returns the repeat number of id as float64 (e.g. 9.3). It returns the
+// legacy encoding for all other designations: 0 if empty, -2 for X, -1 for Y,
+// and -999 otherwise.
+func (id AlleleID) Float() float64 {
+	switch id.Cat {
+	case NOALLELE:
+		return 0
+	case XALLELE:
+		return -2
+	case YALLELE:
+		return -1
+	case REGULAR, MICROVARIANT:
+		return float64(id.Repeats) + float64(id.Partial)/10
+	default:
+		return -999
+	}
+}
+
+// FloatID converts the legacy float64 encoding of an allele (see A2Float) to
+// an AlleleID.
+func FloatID(f float64) AlleleID {
+	switch f {
+	case 0:
+		return AlleleID{}
+	case -1:
+		return AlleleID{Cat: YALLELE}
+	case -2:
+		return AlleleID{Cat: XALLELE}
+	case -999:
+		return AlleleID{Cat: OFFLADDER}
+	default:
+		return A2ID(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+}
+
+// Less orders allele designations by repeat number. The amelogenin alleles X
+// and Y come first; out-of-range designations are ordered next to their bound
+// (<8 right before 8, >24 right after 24); off-ladder calls, wildcards and
+// null alleles come last.
+func (id AlleleID) Less(o AlleleID) bool {
+	if id.rank() != o.rank() {
+		return id.rank() < o.rank()
+	}
+	return id.position() < o.position()
+}
+
+// rank returns the position of the category of id in the order of Less.
+func (id AlleleID) rank() int {
+	switch id.Cat {
+	case NOALLELE:
+		return 0
+	case XALLELE:
+		return 1
+	case YALLELE:
+		return 2
+	case REGULAR, MICROVARIANT, BELOWRANGE, ABOVERANGE:
+		return 3
+	case OFFLADDER:
+		return 4
+	case WILDCARD:
+		return 5
+	default: // NULLALLELE
+		return 6
+	}
+}
+
+// position returns the position of id amongst the designations with repeats.
+func (id AlleleID) position() int {
+	p := id.Repeats*10 + id.Partial
+	switch id.Cat {
+	case BELOWRANGE:
+		return p*10 - 1
+	case ABOVERANGE:
+		return p*10 + 9
+	default:
+		return p * 10
+	}
+}
+
+// A2Float converts the allele ID from string to float.
+//
+// Deprecated: A2Float returns the legacy encoding of allele designations (X =
+// -2, Y = -1, empty = 0, anything else that is not a number = -999). Use A2ID
+// instead.
+func A2Float(a string) float64 {
+	return A2ID(a).Float()
 }
 
-// A2String converts the allele ID from float64 to string.
+// A2String converts a float64 (e.g. a peak height) to string. For the legacy
+// encoding of allele designations, 0 returns "", -1 "Y", -2 "X", and -999
+// "NaN". Allele designations are formatted by AlleleID.String.
 func A2String(a float64) string {
 	switch a {
 	case 0:
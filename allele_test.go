@@ -12,13 +12,13 @@ import (
 func Test_NewAllele(t *testing.T) {
 
 	type test struct {
-		inID float64
+		inID AlleleID
 		want Allele
 	}
 
 	tests := []test{
-		{9.3, Allele{ID: 9.3}},
-		{29, Allele{ID: 29}},
+		{A2ID("9.3"), Allele{ID: A2ID("9.3")}},
+		{A2ID("29"), Allele{ID: A2ID("29")}},
 	}
 
 	for i, tc := range tests {
@@ -78,3 +78,119 @@ func Test_A2String(t *testing.T) {
 		}
 	}
 }
+
+// =============================================================================
+func Test_ParseAlleleID(t *testing.T) {
+
+	type test struct {
+		in         string
+		want       AlleleID
+		wantString string
+		wantErr    bool
+	}
+
+	tests := []test{
+		{"12", AlleleID{Cat: REGULAR, Repeats: 12}, "12", false},
+		{"9.3", AlleleID{Cat: MICROVARIANT, Repeats: 9, Partial: 3}, "9.3", false},
+		{"9.30", AlleleID{Cat: MICROVARIANT, Repeats: 9, Partial: 3}, "9.3", false},
+		{"9.0", AlleleID{Cat: REGULAR, Repeats: 9}, "9", false},
+		{"OL", AlleleID{Cat: OFFLADDER}, "OL", false},
+		{"<8", AlleleID{Cat: BELOWRANGE, Repeats: 8}, "<8", false},
+		{">24.2", AlleleID{Cat: ABOVERANGE, Repeats: 24, Partial: 2}, ">24.2", false},
+		{"x", AlleleID{Cat: XALLELE}, "X", false},
+		{"Y", AlleleID{Cat: YALLELE}, "Y", false},
+		{"F", AlleleID{Cat: WILDCARD}, "F", false},
+		{"Null", AlleleID{Cat: NULLALLELE}, "Null", false},
+		{"", AlleleID{}, "", false},
+		{"9.12", AlleleID{}, "", true},
+		{"-2", AlleleID{}, "", true},
+		{"<", AlleleID{}, "", true},
+		{"abc", AlleleID{}, "", true},
+	}
+
+	for i, tc := range tests {
+		res, err := ParseAlleleID(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("test %d: unexpected error: %v", i+1, err)
+		}
+		if tc.want != res {
+			t.Fatalf("test %d: expected: %+v, got: %+v", i+1, tc.want, res)
+		}
+		if tc.wantString != res.String() {
+			t.Fatalf("test %d (string): expected: %v, got: %v", i+1,
+				tc.wantString, res.String())
+		}
+		if back, _ := ParseAlleleID(res.String()); back != res {
+			t.Fatalf("test %d (round trip): expected: %+v, got: %+v", i+1,
+				res, back)
+		}
+	}
+}
+
+// =============================================================================
+func TestAlleleID_Less(t *testing.T) {
+
+	want := []string{"X", "Y", "<8", "8", "9", "9.1", "9.3", "10", "24",
+		">24", "24.2", "OL", "F", "Null"}
+
+	for i := 0; i < len(want)-1; i++ {
+		a, b := A2ID(want[i]), A2ID(want[i+1])
+		if !a.Less(b) || b.Less(a) {
+			t.Fatalf("test %d: expected %v < %v", i+1, a, b)
+		}
+	}
+}
+
+// =============================================================================
+func Test_FloatID(t *testing.T) {
+
+	type test struct {
+		in   float64
+		want AlleleID
+	}
+
+	tests := []test{
+		{9.3, A2ID("9.3")},
+		{12, A2ID("12")},
+		{-2, A2ID("X")},
+		{-1, A2ID("Y")},
+		{-999, A2ID("OL")},
+		{0, AlleleID{}},
+	}
+
+	for i, tc := range tests {
+		res := FloatID(tc.in)
+		if tc.want != res {
+			t.Fatalf("test %d: expected: %+v, got: %+v", i+1, tc.want, res)
+		}
+		if tc.in != res.Float() {
+			t.Fatalf("test %d (float): expected: %v, got: %v", i+1, tc.in,
+				res.Float())
+		}
+	}
+}
+
+// =============================================================================
+func TestAlleleID_Shift(t *testing.T) {
+
+	type test struct {
+		in   AlleleID
+		n    int
+		want AlleleID
+	}
+
+	tests := []test{
+		{A2ID("12"), -1, A2ID("11")},
+		{A2ID("9.3"), 2, A2ID("11.3")},
+		{A2ID("OL"), 1, AlleleID{}},
+		{A2ID("X"), -1, AlleleID{}},
+		{A2ID("0"), -1, AlleleID{}},
+	}
+
+	for i, tc := range tests {
+		res := tc.in.Shift(tc.n)
+		if tc.want != res {
+			t.Fatalf("test %d: expected: %+v, got: %+v", i+1, tc.want, res)
+		}
+	}
+}
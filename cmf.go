@@ -0,0 +1,412 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CMFNamespace is the XML namespace of the CODIS Common Message Format.
+const CMFNamespace = "urn:CODISImportFile-schema"
+
+// Keys of Sample.Info holding the CMF attributes of a specimen. ReadCMF sets
+// them; NewCMF uses them in favour of the defaults of CMFOptions.
+const (
+	CMFCategory   = "Specimen Category"
+	CMFCaseID     = "Case ID"
+	CMFSourceLab  = "Source Lab"
+	CMFStringency = "Match Stringency"
+	CMFPartial    = "Partial"
+	CMFComment    = "Specimen Comment"
+)
+
+// Specimen categories of CODIS.
+const (
+	CMFForensicUnknown   = "Forensic Unknown"
+	CMFForensicMixture   = "Forensic Mixture"
+	CMFForensicPartial   = "Forensic Partial"
+	CMFConvictedOffender = "Convicted Offender"
+	CMFArrestee          = "Arrestee"
+	CMFDetainee          = "Detainee"
+	CMFLegal             = "Legal"
+	CMFSuspectKnown      = "Suspect, Known"
+	CMFVictimKnown       = "Victim, Known"
+	CMFMissingPerson     = "Missing Person"
+	CMFUnidentifiedHuman = "Unidentified Human (Remains)"
+	CMFBiologicalMother  = "Biological Mother"
+	CMFBiologicalFather  = "Biological Father"
+)
+
+// cmfCategories holds the valid specimen categories and whether they are
+// forensic, i.e. may hold more than one contributor.
+var cmfCategories = map[string]bool{
+	CMFForensicUnknown:   true,
+	CMFForensicMixture:   true,
+	CMFForensicPartial:   true,
+	CMFConvictedOffender: false,
+	CMFArrestee:          false,
+	CMFDetainee:          false,
+	CMFLegal:             false,
+	CMFSuspectKnown:      false,
+	CMFVictimKnown:       false,
+	CMFMissingPerson:     false,
+	CMFUnidentifiedHuman: false,
+	CMFBiologicalMother:  false,
+	CMFBiologicalFather:  false,
+}
+
+// Match stringencies of CODIS.
+const (
+	CMFHighStringency     = "High"
+	CMFModerateStringency = "Moderate"
+	CMFLowStringency      = "Low"
+)
+
+// CODISCore holds the 20 CODIS core loci. Profiles lacking any of them are
+// partial profiles.
+var CODISCore = []string{
+	"CSF1PO", "D1S1656", "D2S441", "D2S1338", "D3S1358", "D5S818", "D7S820",
+	"D8S1179", "D10S1248", "D12S391", "D13S317", "D16S539", "D18S51",
+	"D19S433", "D21S11", "D22S1045", "FGA", "TH01", "TPOX", "VWA",
+}
+
+// CMF is a CODIS Common Message Format import file.
+type CMF struct {
+	XMLName        xml.Name `xml:"CODISImportFile"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	HeaderVersion  string   `xml:"HEADERVERSION"`
+	MessageType    string   `xml:"MESSAGETYPE"`
+	DestinationORI string   `xml:"DESTINATIONORI"`
+	SourceLab      string   `xml:"SOURCELAB"`
+	SubmitByUserID string   `xml:"SUBMITBYUSERID"`
+	SubmitDateTime string   `xml:"SUBMITDATETIME"`
+	// slice of all specimens of the file
+	Specimens []CMFSpecimen `xml:"SPECIMEN"`
+}
+
+// CMFSpecimen is a DNA profile of a CMF file.
+type CMFSpecimen struct {
+	// true if the profile lacks any of the CODIS core loci
+	Partial bool `xml:"PARTIAL,attr"`
+	// ORI of the lab that typed the specimen, if it differs from SOURCELAB
+	SourceLab  string `xml:"SOURCELAB,attr,omitempty"`
+	CaseID     string `xml:"CASEID,attr,omitempty"`
+	Stringency string `xml:"MATCHSTRINGENCY,attr,omitempty"`
+	ID         string `xml:"SPECIMENID"`
+	Category   string `xml:"SPECIMENCATEGORY"`
+	Comment    string `xml:"SPECIMENCOMMENT,omitempty"`
+	// slice of all loci of the specimen
+	Loci []CMFLocus `xml:"LOCUS"`
+}
+
+// CMFLocus holds the alleles of a locus of a CMF specimen.
+type CMFLocus struct {
+	Name    string      `xml:"LOCUSNAME"`
+	Alleles []CMFAllele `xml:"ALLELE"`
+}
+
+// CMFAllele is an allele of a CMF locus.
+type CMFAllele struct {
+	Value string `xml:"ALLELEVALUE"`
+}
+
+// CMFOptions holds the header of a CMF file and the defaults of its
+// specimens.
+type CMFOptions struct {
+	SourceLab      string    // ORI of the submitting lab, e.g. WI0000000
+	DestinationORI string    // ORI of the receiving database
+	SubmitBy       string    // user ID of the submitter
+	Time           time.Time // submission time; the current time if zero
+	Category       string    // default specimen category
+	Stringency     string    // default match stringency
+}
+
+// ReadCMF reads a CMF file f. See ReadCMFFrom.
+func ReadCMF(f string) ([]Sample, error) {
+
+	return readFile(f, func(r io.Reader) ([]Sample, error) {
+		return ReadCMFFrom(r, f)
+	})
+}
+
+// ReadCMFFrom reads a CMF file from r and returns its specimens as samples.
+// The specimen attributes are stored in Sample.Info (see CMFCategory etc.).
+func ReadCMFFrom(r io.Reader, source string) ([]Sample, error) {
+
+	var c CMF
+	if err := xml.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("cannot decode CMF %v: %w", source, err)
+	}
+
+	return c.Samples(source)
+}
+
+// Samples returns the specimens of c as samples of source.
+func (c CMF) Samples(source string) ([]Sample, error) {
+
+	var samples []Sample
+	for _, sp := range c.Specimens {
+
+		s := NewSample(sp.ID, source)
+		s.Info[CMFCategory] = sp.Category
+		s.Info[CMFPartial] = strconv.FormatBool(sp.Partial)
+		s.Info[CMFSourceLab] = c.SourceLab
+		if sp.SourceLab != "" {
+			s.Info[CMFSourceLab] = sp.SourceLab
+		}
+		for k, v := range map[string]string{
+			CMFCaseID: sp.CaseID, CMFStringency: sp.Stringency, CMFComment: sp.Comment} {
+			if v != "" {
+				s.Info[k] = v
+			}
+		}
+
+		for _, cl := range sp.Loci {
+			l := NewLocus(cl.Name)
+			for _, ca := range cl.Alleles {
+				id, err := ParseAlleleID(ca.Value)
+				if err != nil {
+					return nil, fmt.Errorf("specimen %v, locus %v: %w", sp.ID, cl.Name, err)
+				}
+				l.AddAllele(NewAllele(id))
+			}
+			s.AddLocus(l)
+		}
+
+		s.UnknownKit()
+		samples = append(samples, s)
+	}
+
+	return samples, nil
+}
+
+// NewCMF builds a CMF file of samples. The attributes of each specimen are
+// taken from Sample.Info (see CMFCategory etc.) and fall back to the defaults
+// of o. The partial-profile flag is set if a sample lacks any of the CODIS
+// core loci. Off-ladder alleles are dropped as CODIS does not accept them.
+func NewCMF(samples []Sample, o CMFOptions) CMF {
+
+	t := o.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	c := CMF{
+		Xmlns:          CMFNamespace,
+		HeaderVersion:  "3.2",
+		MessageType:    "Import",
+		DestinationORI: o.DestinationORI,
+		SourceLab:      o.SourceLab,
+		SubmitByUserID: o.SubmitBy,
+		SubmitDateTime: t.Format("2006-01-02T15:04:05"),
+	}
+
+	for _, s := range samples {
+		sp := CMFSpecimen{
+			ID:         s.ID,
+			Category:   infoOr(s, CMFCategory, o.Category),
+			Stringency: infoOr(s, CMFStringency, o.Stringency),
+			CaseID:     s.Info[CMFCaseID],
+			Comment:    s.Info[CMFComment],
+		}
+		if lab := s.Info[CMFSourceLab]; lab != o.SourceLab {
+			sp.SourceLab = lab
+		}
+
+		for _, l := range s.Loci {
+			cl := CMFLocus{Name: l.ID}
+			for _, a := range l.WithoutOffLadder().Alleles {
+				cl.Alleles = append(cl.Alleles, CMFAllele{Value: a.ID.String()})
+			}
+			if len(cl.Alleles) > 0 {
+				sp.Loci = append(sp.Loci, cl)
+			}
+		}
+
+		for _, core := range CODISCore {
+			if !sp.hasLocus(core) {
+				sp.Partial = true
+				break
+			}
+		}
+
+		c.Specimens = append(c.Specimens, sp)
+	}
+
+	return c
+}
+
+// infoOr returns the info value of key k of sample s or def if it is empty.
+func infoOr(s Sample, k, def string) string {
+	if v := s.Info[k]; v != "" {
+		return v
+	}
+	return def
+}
+
+// hasLocus returns true if specimen sp holds alleles of locus id.
+func (sp CMFSpecimen) hasLocus(id string) bool {
+	for _, l := range sp.Loci {
+		if l.Name == id && len(l.Alleles) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// oriPattern matches an ORI, i.e. the 9 character originating agency
+// identifier.
+var oriPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{7}$`)
+
+// specimenIDPattern matches a valid specimen ID of up to 24 characters.
+var specimenIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-]{0,23}$`)
+
+// Validate checks c against the constraints of the CMF elements: ORIs are 9
+// characters, specimen IDs are unique and of up to 24 characters, category and
+// match stringency are known to CODIS, each locus occurs once, and alleles are
+// valid designations (no OL, wildcards, or null alleles; X and Y only at
+// AMEL). Single source specimens hold up to 3 alleles per locus, forensic
+// specimens up to 4. All violations are returned.
+func (c CMF) Validate() error {
+
+	var errs []error
+	fail := func(format string, a ...any) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	if !oriPattern.MatchString(c.SourceLab) {
+		fail("invalid source lab ORI %q", c.SourceLab)
+	}
+	if !oriPattern.MatchString(c.DestinationORI) {
+		fail("invalid destination ORI %q", c.DestinationORI)
+	}
+	if c.SubmitByUserID == "" {
+		fail("missing submitting user ID")
+	}
+	if len(c.Specimens) == 0 {
+		fail("no specimens")
+	}
+
+	ids := make(map[string]bool)
+	for _, sp := range c.Specimens {
+
+		if !specimenIDPattern.MatchString(sp.ID) {
+			fail("specimen %q: invalid specimen ID", sp.ID)
+		}
+		if ids[sp.ID] {
+			fail("specimen %q: duplicate specimen ID", sp.ID)
+		}
+		ids[sp.ID] = true
+
+		forensic, ok := cmfCategories[sp.Category]
+		if !ok {
+			fail("specimen %q: invalid specimen category %q", sp.ID, sp.Category)
+		}
+		switch sp.Stringency {
+		case "", CMFHighStringency, CMFModerateStringency, CMFLowStringency:
+		default:
+			fail("specimen %q: invalid match stringency %q", sp.ID, sp.Stringency)
+		}
+		if sp.SourceLab != "" && !oriPattern.MatchString(sp.SourceLab) {
+			fail("specimen %q: invalid source lab ORI %q", sp.ID, sp.SourceLab)
+		}
+		if len(sp.Loci) == 0 {
+			fail("specimen %q: no loci", sp.ID)
+		}
+
+		maxAlleles := 3
+		if forensic {
+			maxAlleles = 4
+		}
+
+		loci := make(map[string]bool)
+		for _, l := range sp.Loci {
+			if l.Name == "" {
+				fail("specimen %q: missing locus name", sp.ID)
+			}
+			if loci[l.Name] {
+				fail("specimen %q: duplicate locus %v", sp.ID, l.Name)
+			}
+			loci[l.Name] = true
+
+			if len(l.Alleles) == 0 || len(l.Alleles) > maxAlleles {
+				fail("specimen %q, locus %v: %v alleles, expect 1 to %v",
+					sp.ID, l.Name, len(l.Alleles), maxAlleles)
+			}
+			for _, a := range l.Alleles {
+				if err := validateAlleleValue(l.Name, a.Value); err != nil {
+					fail("specimen %q, locus %v: CMF: %v", sp.ID, l.Name, err)
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateAlleleValue checks whether v is a valid allele value at locus lID
+// for the exchange with a DNA database. The error does not name the format;
+// callers add it.
+func validateAlleleValue(lID, v string) error {
+
+	id, err := ParseAlleleID(v)
+	if err != nil {
+		return err
+	}
+
+	amel := strings.EqualFold(lID, "AMEL")
+	switch {
+	case !id.IsTyped(), amel != (id.Cat == XALLELE || id.Cat == YALLELE):
+		return fmt.Errorf("invalid allele designation %q", v)
+	}
+
+	return nil
+}
+
+// Write validates c and writes it to w. Nothing is written if the validation
+// fails.
+func (c CMF) Write(w io.Writer) error {
+
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("invalid CMF: %w", err)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ExportCMF writes samples as CMF file f. See NewCMF and CMF.Validate. The
+// file is removed if the validation or the write fails.
+func ExportCMF(samples []Sample, f string, o CMFOptions) error {
+
+	cmfF, err := os.Create(f)
+	if err != nil {
+		return fmt.Errorf("cannot create %v: %w", f, err)
+	}
+
+	err = NewCMF(samples, o).Write(cmfF)
+	if cErr := cmfF.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(f)
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+func Test_ReadCMF(t *testing.T) {
+
+	res, err := ReadCMF("testdata/cmf.xml")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("expected 2 samples, got: %v", len(res))
+	}
+
+	wantInfo := Info{CMFCategory: CMFForensicUnknown, CMFPartial: "true",
+		CMFSourceLab: "XX0000001", CMFCaseID: "C-17", CMFStringency: CMFModerateStringency}
+	if res[0].ID != "S-0001" || !reflect.DeepEqual(res[0].Info, wantInfo) {
+		t.Fatalf("test 1: expected: %v, got: %v %v", wantInfo, res[0].ID, res[0].Info)
+	}
+
+	wantLoci := []Locus{
+		{ID: "VWA", Alleles: []Allele{{ID: A2ID("15")}, {ID: A2ID("17")}}},
+		{ID: "TH01", Alleles: []Allele{{ID: A2ID("9.3")}}},
+		{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+	}
+	if !reflect.DeepEqual(res[0].Loci, wantLoci) {
+		t.Fatalf("test 1: expected: %v, got: %v", wantLoci, res[0].Loci)
+	}
+
+	if res[1].Info[CMFSourceLab] != "XX0000002" || res[1].Loci[0].Alleles[0].ID != A2ID("<9") {
+		t.Fatalf("test 2: unexpected sample %v", res[1])
+	}
+}
+
+// =============================================================================
+func TestCMF_Write(t *testing.T) {
+
+	s := NewSample("S-0002", "test")
+	s.Info[CMFCaseID] = "C-18"
+	s.Loci = []Locus{
+		{ID: "VWA", Alleles: []Allele{{ID: A2ID("15")}, {ID: A2ID("17")}}},
+		{ID: "FGA", Alleles: []Allele{{ID: A2ID("22")}, {ID: A2ID("OL"), Size: 250}}},
+	}
+
+	o := CMFOptions{SourceLab: "XX0000001", DestinationORI: "XX0000000", SubmitBy: "analyst",
+		Time: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC), Category: CMFForensicUnknown}
+
+	var buf bytes.Buffer
+	if err := NewCMF([]Sample{s}, o).Write(&buf); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	res, err := ReadCMFFrom(&buf, "upload")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := []Locus{ // OL is not exported
+		{ID: "VWA", Alleles: []Allele{{ID: A2ID("15")}, {ID: A2ID("17")}}},
+		{ID: "FGA", Alleles: []Allele{{ID: A2ID("22")}}},
+	}
+	if len(res) != 1 || !reflect.DeepEqual(res[0].Loci, want) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+	if res[0].Info[CMFPartial] != "true" || res[0].Info[CMFCaseID] != "C-18" ||
+		res[0].Info[CMFCategory] != CMFForensicUnknown {
+		t.Fatalf("unexpected info: %v", res[0].Info)
+	}
+}
+
+// =============================================================================
+func TestCMF_Validate(t *testing.T) {
+
+	valid := func() CMF {
+		return CMF{
+			DestinationORI: "XX0000000", SourceLab: "XX0000001", SubmitByUserID: "analyst",
+			Specimens: []CMFSpecimen{{ID: "S-1", Category: CMFConvictedOffender, Loci: []CMFLocus{
+				{Name: "VWA", Alleles: []CMFAllele{{"15"}, {"17"}}},
+				{Name: "AMEL", Alleles: []CMFAllele{{"X"}}},
+			}}},
+		}
+	}
+
+	type test struct {
+		modify func(c *CMF)
+		want   string // part of the error message, empty if valid
+	}
+
+	tests := []test{
+		{func(c *CMF) {}, ""},
+		{func(c *CMF) { c.SourceLab = "lab" }, "source lab ORI"},
+		{func(c *CMF) { c.Specimens[0].ID = "S 1" }, "invalid specimen ID"},
+		{func(c *CMF) { c.Specimens = append(c.Specimens, c.Specimens[0]) }, "duplicate specimen ID"},
+		{func(c *CMF) { c.Specimens[0].Category = "Suspect" }, "specimen category"},
+		{func(c *CMF) { c.Specimens[0].Stringency = "Medium" }, "match stringency"},
+		{func(c *CMF) { c.Specimens[0].Loci[0].Alleles[0].Value = "OL" }, `CMF: invalid allele designation "OL"`},
+		{func(c *CMF) { c.Specimens[0].Loci[0].Alleles[0].Value = "X" }, "invalid allele"},
+		{func(c *CMF) {
+			c.Specimens[0].Loci[0].Alleles = []CMFAllele{{"14"}, {"15"}, {"16"}, {"17"}}
+		}, "4 alleles"},
+		{func(c *CMF) {
+			c.Specimens[0].Category = CMFForensicMixture
+			c.Specimens[0].Loci[0].Alleles = []CMFAllele{{"14"}, {"15"}, {"16"}, {"17"}}
+		}, ""},
+	}
+
+	for i, tc := range tests {
+		c := valid()
+		tc.modify(&c)
+		err := c.Validate()
+		if tc.want == "" && err != nil {
+			t.Fatalf("test %d: expected no error, got: %v", i+1, err)
+		}
+		if tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Fatalf("test %d: expected error containing %q, got: %v", i+1, tc.want, err)
+		}
+	}
+
+	// invalid files are not written
+	c := valid()
+	c.DestinationORI = ""
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err == nil || buf.Len() > 0 {
+		t.Fatalf("expected error and no output, got: %v, %q", err, buf.String())
+	}
+
+	// invalid exports leave no file
+	f := filepath.Join(t.TempDir(), "invalid.xml")
+	if err := ExportCMF([]Sample{peaks("S-1", "VWA:15")}, f, CMFOptions{}); err == nil {
+		t.Fatalf("expected error for missing ORIs")
+	}
+	if _, err := os.Stat(f); !os.IsNotExist(err) {
+		t.Fatalf("expected no file %v, got: %v", f, err)
+	}
+}
@@ -27,19 +27,33 @@ func (c Concat) String() string {
 
 // Composite returns the composite profile of samples with the name id.
 func Composite(samples []Sample, link LocusLinkage) Sample {
-	return concatSamples(samples, link, COMPOSITE)
+	return concatSamples(samples, link, COMPOSITE, LENGTH)
 }
 
 // Consensus returns the consensus profile of samples with the name id.
 func Consensus(samples []Sample, link LocusLinkage) Sample {
-	return concatSamples(samples, link, CONSENSUS)
+	return concatSamples(samples, link, CONSENSUS, LENGTH)
+}
+
+// CompositeMode returns the composite profile of samples, comparing alleles
+// under match mode m. In SEQUENCE mode isoalleles remain distinct.
+func CompositeMode(samples []Sample, link LocusLinkage, m MatchMode) Sample {
+	return concatSamples(samples, link, COMPOSITE, m)
+}
+
+// ConsensusMode returns the consensus profile of samples, comparing alleles
+// under match mode m. In SEQUENCE mode an allele is only part of the consensus
+// if all samples share its sequence.
+func ConsensusMode(samples []Sample, link LocusLinkage, m MatchMode) Sample {
+	return concatSamples(samples, link, CONSENSUS, m)
 }
 
 // concatSamples concatenates samples according to mode and link into a sample.
 // Empty concat loci will not be added to the concat sample. The ID of the
 // return sample is the mode of the concat; the source is a string. If link is
-// ALLLINKAGE all loci will be considered.
-func concatSamples(samples []Sample, link LocusLinkage, mode Concat) Sample {
+// ALLLINKAGE all loci will be considered. Alleles are compared under match
+// mode m.
+func concatSamples(samples []Sample, link LocusLinkage, mode Concat, m MatchMode) Sample {
 
 	if len(samples) == 0 {
 		return Sample{}
@@ -73,18 +87,20 @@ func concatSamples(samples []Sample, link LocusLinkage, mode Concat) Sample {
 			loci = append(loci, s.Locus(locusID))
 		}
 
-		cs.AddLocus(concatLoci(loci, mode))
+		cs.AddLocus(concatLoci(loci, mode, m))
 	}
 
 	return cs
 }
 
 // concatLocus concatenates loci. If m = "consensus" it builds a
-// consensus locus, if it is "composite" it builds a composite locus.
-func concatLoci(loci []Locus, mode Concat) Locus {
+// consensus locus, if it is "composite" it builds a composite locus. Alleles
+// are compared under match mode m.
+func concatLoci(loci []Locus, mode Concat, m MatchMode) Locus {
 
 	var lID string
-	aIDs := make(map[float64]int)
+	aIDs := make(map[alleleKey]int)
+	seqs := make(map[alleleKey]Sequence) // full sequence info of the key
 
 	for _, l := range loci {
 		if l.ID == "" {
@@ -94,15 +110,24 @@ func concatLoci(loci []Locus, mode Concat) Locus {
 			lID = l.ID
 		}
 
+		seen := make(map[alleleKey]bool) // isoalleles count once per locus
 		for _, a := range l.Alleles {
-			aIDs[a.ID]++
+			if !seen[a.key(m)] {
+				aIDs[a.key(m)]++
+				seen[a.key(m)] = true
+			}
+			if _, ok := seqs[a.key(m)]; !ok && m == SEQUENCE {
+				seqs[a.key(m)] = a.Seq
+			}
 		}
 	}
 
 	cl := NewLocus(lID)
 	for aID, count := range aIDs {
 		if (mode == CONSENSUS && count == len(loci)) || mode == COMPOSITE {
-			cl.AddAllele(NewAllele(aID))
+			a := NewAllele(aID.ID)
+			a.Seq = seqs[aID]
+			cl.AddAllele(a)
 		}
 	}
 
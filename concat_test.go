@@ -21,20 +21,20 @@ func Test_concatLoci(t *testing.T) {
 	tests := []test{
 		{ // 1
 			[]Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 9.2}, {ID: 18}, {ID: 23}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 9.2}, {ID: 18}, {ID: 23}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 23}, {ID: 25.2}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("9.2")}, {ID: A2ID("18")}, {ID: A2ID("23")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("9.2")}, {ID: A2ID("18")}, {ID: A2ID("23")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("23")}, {ID: A2ID("25.2")}}},
 			},
-			Locus{ID: "SE33", Alleles: []Allele{{ID: 9.2}, {ID: 18}, {ID: 23}, {ID: 25.2}}},
-			Locus{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 23}}},
+			Locus{ID: "SE33", Alleles: []Allele{{ID: A2ID("9.2")}, {ID: A2ID("18")}, {ID: A2ID("23")}, {ID: A2ID("25.2")}}},
+			Locus{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("23")}}},
 		},
 		{ // 2
 			[]Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 9.2}, {ID: 18}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 11.1}, {ID: 23}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 25.2}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("9.2")}, {ID: A2ID("18")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("11.1")}, {ID: A2ID("23")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("25.2")}}},
 			},
-			Locus{ID: "SE33", Alleles: []Allele{{ID: 9.2}, {ID: 11.1}, {ID: 18}, {ID: 23}, {ID: 25.2}}},
+			Locus{ID: "SE33", Alleles: []Allele{{ID: A2ID("9.2")}, {ID: A2ID("11.1")}, {ID: A2ID("18")}, {ID: A2ID("23")}, {ID: A2ID("25.2")}}},
 			Locus{ID: "SE33"},
 		},
 		{ // 3
@@ -47,12 +47,12 @@ func Test_concatLoci(t *testing.T) {
 	}
 
 	for i, tc := range tests {
-		gotComp := concatLoci(tc.inLoci, COMPOSITE)
+		gotComp := concatLoci(tc.inLoci, COMPOSITE, LENGTH)
 		if !reflect.DeepEqual(tc.wantComp, gotComp) {
 			t.Fatalf("test %d (composite): expected: %v, got: %v", i+1, tc.wantComp, gotComp)
 		}
 
-		gotCons := concatLoci(tc.inLoci, CONSENSUS)
+		gotCons := concatLoci(tc.inLoci, CONSENSUS, LENGTH)
 		if !reflect.DeepEqual(tc.wantCons, gotCons) {
 			t.Fatalf("test %d (consensus): expected: %v, got: %v", i+1, tc.wantCons, gotCons)
 		}
@@ -60,6 +60,69 @@ func Test_concatLoci(t *testing.T) {
 	}
 }
 
+// =============================================================================
+func Test_concatLoci_SEQUENCE(t *testing.T) {
+
+	loci := []Locus{
+		{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")}, d12iso1}},
+		{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")}, d12iso2}},
+	}
+
+	type test struct {
+		inMode   MatchMode
+		wantComp Locus
+		wantCons Locus
+	}
+
+	tests := []test{
+		{
+			LENGTH,
+			Locus{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("19")}}},
+			Locus{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("19")}}},
+		},
+		{
+			SEQUENCE,
+			Locus{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")},
+				{ID: A2ID("19"), Seq: d12iso2.Seq},
+				{ID: A2ID("19"), Seq: d12iso1.Seq}}},
+			Locus{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")}}},
+		},
+	}
+
+	for i, tc := range tests {
+		gotComp := concatLoci(loci, COMPOSITE, tc.inMode)
+		if !reflect.DeepEqual(tc.wantComp, gotComp) {
+			t.Fatalf("test %d (composite): expected: %v, got: %v", i+1, tc.wantComp, gotComp)
+		}
+
+		gotCons := concatLoci(loci, CONSENSUS, tc.inMode)
+		if !reflect.DeepEqual(tc.wantCons, gotCons) {
+			t.Fatalf("test %d (consensus): expected: %v, got: %v", i+1, tc.wantCons, gotCons)
+		}
+	}
+}
+
+// =============================================================================
+func Test_concatLoci_isoalleles(t *testing.T) {
+
+	// two isoalleles 19 in one sample, none in the other
+	loci := []Locus{
+		{ID: "D12S391", Alleles: []Allele{d12iso1, d12iso2}},
+		{ID: "D12S391", Alleles: []Allele{{ID: A2ID("20")}}},
+	}
+
+	gotCons := concatLoci(loci, CONSENSUS, LENGTH)
+	if len(gotCons.Alleles) != 0 {
+		t.Fatalf("consensus: expected no alleles, got: %v", gotCons)
+	}
+
+	wantComp := Locus{ID: "D12S391", Alleles: []Allele{{ID: A2ID("19")}, {ID: A2ID("20")}}}
+	gotComp := concatLoci(loci, COMPOSITE, LENGTH)
+	if !reflect.DeepEqual(wantComp, gotComp) {
+		t.Fatalf("composite: expected: %v, got: %v", wantComp, gotComp)
+	}
+}
+
 // =============================================================================
 func Test_concatSamples(t *testing.T) {
 
@@ -76,26 +139,26 @@ func Test_concatSamples(t *testing.T) {
 					ID:   "test1",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "SE33", Alleles: []Allele{{ID: 17.2}, {ID: 18}, {ID: 23}}},
-						{ID: "vWA", Alleles: []Allele{{ID: 6}, {ID: 9}, {ID: 9.3}}},
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}}},
+						{ID: "SE33", Alleles: []Allele{{ID: A2ID("17.2")}, {ID: A2ID("18")}, {ID: A2ID("23")}}},
+						{ID: "vWA", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("9")}, {ID: A2ID("9.3")}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}}},
 					},
 				},
 				{
 					ID:   "test2",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "SE33", Alleles: []Allele{{ID: 11}, {ID: 18}, {ID: 23}}},
-						{ID: "vWA", Alleles: []Allele{{ID: 7}}},
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
+						{ID: "SE33", Alleles: []Allele{{ID: A2ID("11")}, {ID: A2ID("18")}, {ID: A2ID("23")}}},
+						{ID: "vWA", Alleles: []Allele{{ID: A2ID("7")}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
 					},
 				},
 				{
 					ID:   "test3",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "SE33", Alleles: []Allele{{ID: 11}, {ID: 18}, {ID: 23}, {ID: 28}}},
-						{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 31}}},
+						{ID: "SE33", Alleles: []Allele{{ID: A2ID("11")}, {ID: A2ID("18")}, {ID: A2ID("23")}, {ID: A2ID("28")}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("31")}}},
 					},
 				},
 			},
@@ -103,9 +166,9 @@ func Test_concatSamples(t *testing.T) {
 				ID:   "test1::test2::test3",
 				Info: make(map[string]string),
 				Loci: []Locus{
-					{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
-					{ID: "SE33", Alleles: []Allele{{ID: 11}, {ID: 17.2}, {ID: 18}, {ID: 23}, {ID: 28}}},
-					{ID: "VWA", Alleles: []Allele{{ID: 6}, {ID: 7}, {ID: 9}, {ID: 9.3}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
+					{ID: "SE33", Alleles: []Allele{{ID: A2ID("11")}, {ID: A2ID("17.2")}, {ID: A2ID("18")}, {ID: A2ID("23")}, {ID: A2ID("28")}}},
+					{ID: "VWA", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("7")}, {ID: A2ID("9")}, {ID: A2ID("9.3")}}},
 				},
 				Source: "composite::all_linkage::test1::test2::test3",
 			},
@@ -113,8 +176,8 @@ func Test_concatSamples(t *testing.T) {
 				ID:   "test1::test2::test3",
 				Info: make(map[string]string),
 				Loci: []Locus{
-					{ID: "FGA", Alleles: []Allele{{ID: 31}}},
-					{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 23}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("31")}}},
+					{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("23")}}},
 					{ID: "VWA"},
 				},
 				Source: "consensus::all_linkage::test1::test2::test3",
@@ -150,21 +213,21 @@ func Test_concatSamples(t *testing.T) {
 					ID:   "test1",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}}},
 					},
 				},
 				{
 					ID:   "test2",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
 					},
 				},
 				{
 					ID:   "test3",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 31}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("31")}}},
 					},
 				},
 			},
@@ -172,7 +235,7 @@ func Test_concatSamples(t *testing.T) {
 				ID:   "test1::test2::test3",
 				Info: make(map[string]string),
 				Loci: []Locus{
-					{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
 				},
 				Source: "composite::all_linkage::test1::test2::test3",
 			},
@@ -180,7 +243,7 @@ func Test_concatSamples(t *testing.T) {
 				ID:   "test1::test2::test3",
 				Info: make(map[string]string),
 				Loci: []Locus{
-					{ID: "FGA", Alleles: []Allele{{ID: 31}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("31")}}},
 				},
 				Source: "consensus::all_linkage::test1::test2::test3",
 			},
@@ -191,24 +254,24 @@ func Test_concatSamples(t *testing.T) {
 					ID:   "test1",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}}},
-						{ID: "DYS518", Alleles: []Allele{{ID: 10}, {ID: 11}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}}},
+						{ID: "DYS518", Alleles: []Allele{{ID: A2ID("10")}, {ID: A2ID("11")}}},
 					},
 				},
 				{
 					ID:   "test2",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
-						{ID: "DYS518", Alleles: []Allele{{ID: 9}, {ID: 11}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
+						{ID: "DYS518", Alleles: []Allele{{ID: A2ID("9")}, {ID: A2ID("11")}}},
 					},
 				},
 				{
 					ID:   "test3",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 31}}},
-						{ID: "DYS518", Alleles: []Allele{{ID: 11}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("31")}}},
+						{ID: "DYS518", Alleles: []Allele{{ID: A2ID("11")}}},
 					},
 				},
 			},
@@ -216,8 +279,8 @@ func Test_concatSamples(t *testing.T) {
 				ID:   "test1::test2::test3",
 				Info: make(map[string]string),
 				Loci: []Locus{
-					{ID: "DYS518", Alleles: []Allele{{ID: 9}, {ID: 10}, {ID: 11}}},
-					{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
+					{ID: "DYS518", Alleles: []Allele{{ID: A2ID("9")}, {ID: A2ID("10")}, {ID: A2ID("11")}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
 				},
 				Source: "composite::all_linkage::test1::test2::test3",
 			},
@@ -225,8 +288,8 @@ func Test_concatSamples(t *testing.T) {
 				ID:   "test1::test2::test3",
 				Info: make(map[string]string),
 				Loci: []Locus{
-					{ID: "DYS518", Alleles: []Allele{{ID: 11}}},
-					{ID: "FGA", Alleles: []Allele{{ID: 31}}},
+					{ID: "DYS518", Alleles: []Allele{{ID: A2ID("11")}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("31")}}},
 				},
 				Source: "consensus::all_linkage::test1::test2::test3",
 			},
@@ -237,26 +300,26 @@ func Test_concatSamples(t *testing.T) {
 					ID:   "test1",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}}},
-						{ID: "DYS518", Alleles: []Allele{{ID: 10}, {ID: 11}}},
-						{ID: "DYS393", Alleles: []Allele{{ID: 6}, {ID: 7}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}}},
+						{ID: "DYS518", Alleles: []Allele{{ID: A2ID("10")}, {ID: A2ID("11")}}},
+						{ID: "DYS393", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("7")}}},
 					},
 				},
 				{
 					ID:   "test2",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
-						{ID: "DYS518", Alleles: []Allele{{ID: 9}, {ID: 11}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
+						{ID: "DYS518", Alleles: []Allele{{ID: A2ID("9")}, {ID: A2ID("11")}}},
 					},
 				},
 				{
 					ID:   "test3",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 31}}},
-						{ID: "DYS518", Alleles: []Allele{{ID: 7}, {ID: 11}}},
-						{ID: "DYS393", Alleles: []Allele{{ID: 6}, {ID: 9.3}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("31")}}},
+						{ID: "DYS518", Alleles: []Allele{{ID: A2ID("7")}, {ID: A2ID("11")}}},
+						{ID: "DYS393", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("9.3")}}},
 					},
 				},
 			},
@@ -264,9 +327,9 @@ func Test_concatSamples(t *testing.T) {
 				ID:   "test1::test2::test3",
 				Info: make(map[string]string),
 				Loci: []Locus{
-					{ID: "DYS393", Alleles: []Allele{{ID: 6}, {ID: 7}, {ID: 9.3}}},
-					{ID: "DYS518", Alleles: []Allele{{ID: 7}, {ID: 9}, {ID: 10}, {ID: 11}}},
-					{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
+					{ID: "DYS393", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("7")}, {ID: A2ID("9.3")}}},
+					{ID: "DYS518", Alleles: []Allele{{ID: A2ID("7")}, {ID: A2ID("9")}, {ID: A2ID("10")}, {ID: A2ID("11")}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
 				},
 				Source: "composite::all_linkage::test1::test2::test3",
 			},
@@ -275,8 +338,8 @@ func Test_concatSamples(t *testing.T) {
 				Info: make(map[string]string),
 				Loci: []Locus{
 					{ID: "DYS393"},
-					{ID: "DYS518", Alleles: []Allele{{ID: 11}}},
-					{ID: "FGA", Alleles: []Allele{{ID: 31}}},
+					{ID: "DYS518", Alleles: []Allele{{ID: A2ID("11")}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("31")}}},
 				},
 				Source: "consensus::all_linkage::test1::test2::test3",
 			},
@@ -287,23 +350,23 @@ func Test_concatSamples(t *testing.T) {
 					ID:   "test1",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}}},
-						{ID: "DYS518", Alleles: []Allele{{ID: 10}, {ID: 11}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}}},
+						{ID: "DYS518", Alleles: []Allele{{ID: A2ID("10")}, {ID: A2ID("11")}}},
 					},
 				},
 				{
 					ID:   "test2",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
-						{ID: "DYS518", Alleles: []Allele{{ID: 9}, {ID: 11}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
+						{ID: "DYS518", Alleles: []Allele{{ID: A2ID("9")}, {ID: A2ID("11")}}},
 					},
 				},
 				{
 					ID:   "test3",
 					Info: make(map[string]string),
 					Loci: []Locus{
-						{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 31}}},
+						{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("31")}}},
 					},
 				},
 			},
@@ -311,8 +374,8 @@ func Test_concatSamples(t *testing.T) {
 				ID:   "test1::test2::test3",
 				Info: make(map[string]string),
 				Loci: []Locus{
-					{ID: "DYS518", Alleles: []Allele{{ID: 9}, {ID: 10}, {ID: 11}}},
-					{ID: "FGA", Alleles: []Allele{{ID: 29}, {ID: 30}, {ID: 31}, {ID: 33}, {ID: 34}}},
+					{ID: "DYS518", Alleles: []Allele{{ID: A2ID("9")}, {ID: A2ID("10")}, {ID: A2ID("11")}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("30")}, {ID: A2ID("31")}, {ID: A2ID("33")}, {ID: A2ID("34")}}},
 				},
 				Source: "composite::all_linkage::test1::test2::test3",
 			},
@@ -321,7 +384,7 @@ func Test_concatSamples(t *testing.T) {
 				Info: make(map[string]string),
 				Loci: []Locus{
 					{ID: "DYS518"},
-					{ID: "FGA", Alleles: []Allele{{ID: 31}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("31")}}},
 				},
 				Source: "consensus::all_linkage::test1::test2::test3",
 			},
@@ -0,0 +1,551 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// ContinuousConf holds the parameters of the continuous peak height model.
+// Zero values of AT, DropInLambda and Starts are replaced by the values of
+// DefaultContinuousConf.
+type ContinuousConf struct {
+	AT           float64 // analytical threshold; lower peaks are dropped out
+	DropIn       float64 // drop-in probability per replicate and locus
+	DropInLambda float64 // rate of the exponential drop-in peak heights above AT
+	Theta        float64 // Balding–Nichols correction
+	Degradation  bool    // estimate the degradation slope
+	Stutter      bool    // estimate the back-stutter proportion
+	Starts       int     // number of starts of the likelihood maximization
+	Seed         int64   // seed of the random starting points
+}
+
+// DefaultContinuousConf holds the default parameters of the continuous model.
+var DefaultContinuousConf = ContinuousConf{
+	AT:           50,
+	DropIn:       0.05,
+	DropInLambda: 0.01,
+	Degradation:  true,
+	Stutter:      true,
+	Starts:       3,
+}
+
+// withDefaults returns c with zero values replaced by the defaults.
+func (c ContinuousConf) withDefaults() ContinuousConf {
+	if c.AT == 0 {
+		c.AT = DefaultContinuousConf.AT
+	}
+	if c.DropInLambda == 0 {
+		c.DropInLambda = DefaultContinuousConf.DropInLambda
+	}
+	if c.Starts == 0 {
+		c.Starts = DefaultContinuousConf.Starts
+	}
+	return c
+}
+
+// ContinuousFit holds the maximum likelihood estimates of the parameters of
+// the continuous model under a hypothesis.
+type ContinuousFit struct {
+	LogLik float64 // maximum log likelihood
+	// Mixture proportions of the contributors: first the known contributors
+	// in the order of Hypothesis.Known, then the unknowns in decreasing order.
+	Mixture    []float64
+	Mu         float64 // expected peak height of a heterozygous allele
+	Omega      float64 // coefficient of variation of the peak heights
+	Beta       float64 // degradation slope per 100 bp; 1 without degradation
+	Xi         float64 // back-stutter proportion
+	Validation ModelValidation
+}
+
+// ContinuousResult holds the likelihood ratio of the continuous model and the
+// fits under both hypotheses. The likelihoods of the loci are those at the
+// maximum likelihood estimates.
+type ContinuousResult struct {
+	LRResult
+	Hp, Hd ContinuousFit
+}
+
+// PeakValidation compares an observed peak with the peak height expected
+// under the fitted model.
+type PeakValidation struct {
+	Sample   string
+	Locus    string
+	Allele   AlleleID
+	Height   float64 // observed peak height
+	Expected float64 // expected peak height given the evidence
+	// CDF is the probability of a peak height up to Height given that the
+	// peak is above the analytical threshold; it is uniformly distributed if
+	// the model fits.
+	CDF     float64
+	Outlier bool // CDF outside the Bonferroni-corrected bounds
+}
+
+// ModelValidation holds the validation of the fitted model: the peaks whose
+// CDF value is below Level/2N or above 1-Level/2N for N peaks are outliers.
+type ModelValidation struct {
+	Level float64
+	Peaks []PeakValidation
+	Pass  bool // true if there are no outliers
+}
+
+// validationLevel is the significance level of the model validation.
+const validationLevel = 0.01
+
+// ContinuousLR returns the likelihood ratio of the evidence under the
+// hypotheses hp and hd by a gamma model of the peak heights as implemented in
+// EuroForMix (Bleka et al. 2016). The height of a peak is gamma distributed
+// with shape α/ω² and scale μω², where α is the sum of the mixture proportions
+// of all copies of the allele, reduced by the degradation β^((size-125)/100)
+// and the back-stutter proportion ξ that is added to the position one repeat
+// below. Peaks below c.AT are drop-outs with the probability of a height
+// below the threshold; peaks not explained by the contributors are drop-ins
+// with probability c.DropIn times the allele frequency times the exponential
+// density of the height above c.AT. The parameters μ, ω, β, ξ and the mixture
+// proportions are maximum likelihood estimates under each hypothesis;
+// Hypothesis.DropOut is not used.
+//
+// Each sample of evidence is a replicate with peak heights; replicates share
+// all parameters. Loci are excluded as by SemiContinuousLR.
+func ContinuousLR(evidence []Sample, hp, hd Hypothesis, f Freqs, c ContinuousConf) (ContinuousResult, error) {
+
+	for i, h := range []Hypothesis{hp, hd} {
+		if h.contributors() == 0 {
+			return ContinuousResult{}, fmt.Errorf("%v: no contributors", []string{"hp", "hd"}[i])
+		}
+	}
+
+	m, err := newContModel(evidence, knownPersons(hp, hd), f, c)
+	if err != nil {
+		return ContinuousResult{}, err
+	}
+
+	var r ContinuousResult
+	var lHp, lHd []float64
+	r.Hp, lHp = m.fit(hp)
+	r.Hd, lHd = m.fit(hd)
+	if math.IsInf(r.Hd.LogLik, -1) {
+		return ContinuousResult{}, fmt.Errorf("evidence impossible under hd")
+	}
+
+	// loci in order of the evidence, as by SemiContinuousLR
+	var i, j int
+	for _, lID := range evidenceLoci(evidence) {
+		switch {
+		case i < len(m.loci) && m.loci[i].id == lID:
+			r.Loci = append(r.Loci, LocusLR{Locus: lID, Hp: math.Exp(lHp[i]), Hd: math.Exp(lHd[i]),
+				LR: math.Exp(lHp[i] - lHd[i])})
+			i++
+		case j < len(m.excluded) && m.excluded[j].Locus == lID:
+			r.Loci = append(r.Loci, m.excluded[j])
+			j++
+		}
+	}
+	r.LR = math.Exp(r.Hp.LogLik - r.Hd.LogLik)
+
+	return r, nil
+}
+
+// FitContinuous returns the maximum likelihood estimates of the parameters of
+// the continuous model (see ContinuousLR) of the evidence under hypothesis h,
+// e.g. the mixture proportions of the contributors. The model is conditioned
+// on the known persons of h and of the alternative hypotheses alt, and loci
+// are excluded as by ContinuousLR, so FitContinuous(evidence, hp, f, c, hd)
+// is the fit Hp of ContinuousLR(evidence, hp, hd, f, c).
+func FitContinuous(evidence []Sample, h Hypothesis, f Freqs, c ContinuousConf, alt ...Hypothesis) (ContinuousFit, error) {
+
+	if h.contributors() == 0 {
+		return ContinuousFit{}, fmt.Errorf("no contributors")
+	}
+
+	typed := h.Known
+	for _, a := range alt {
+		typed = knownPersons(Hypothesis{Known: typed}, a)
+	}
+
+	m, err := newContModel(evidence, typed, f, c)
+	if err != nil {
+		return ContinuousFit{}, err
+	}
+
+	r, _ := m.fit(h)
+	return r, nil
+}
+
+// contModel holds the included loci of the continuous model.
+type contModel struct {
+	loci     []contLocus
+	excluded []LocusLR
+	samples  []string // IDs of the replicates
+	conf     ContinuousConf
+	mu0      float64 // initial estimate of μ
+}
+
+// newContModel prepares the continuous model of the evidence, conditioned on
+// the known persons typed.
+func newContModel(evidence, typed []Sample, f Freqs, c ContinuousConf) (contModel, error) {
+
+	c = c.withDefaults()
+	switch {
+	case len(evidence) == 0:
+		return contModel{}, fmt.Errorf("no evidence")
+	case c.Theta < 0 || c.Theta >= 1 || c.DropIn < 0 || c.DropIn >= 1:
+		return contModel{}, fmt.Errorf("theta %v or drop-in rate %v not in [0, 1)", c.Theta, c.DropIn)
+	case c.AT < 0 || c.DropInLambda < 0:
+		return contModel{}, fmt.Errorf("negative analytical threshold %v or drop-in rate %v",
+			c.AT, c.DropInLambda)
+	}
+
+	m := contModel{conf: c}
+	for _, s := range evidence {
+		m.samples = append(m.samples, s.ID)
+	}
+
+	var sum float64
+	var n int
+	for _, lID := range evidenceLoci(evidence) {
+		if e := mixExcluded(lID, f, typed); e != "" {
+			m.excluded = append(m.excluded, LocusLR{Locus: lID, Excluded: e})
+			continue
+		}
+		l, err := newContLocus(evidence, typed, lID, f, c)
+		if err != nil {
+			return contModel{}, err
+		}
+		m.loci = append(m.loci, l)
+
+		for _, y := range l.heights {
+			for _, h := range y {
+				sum += h
+			}
+			n++
+		}
+	}
+
+	if len(m.loci) == 0 {
+		return contModel{}, fmt.Errorf("no locus with frequency data")
+	}
+	m.mu0 = math.Max(sum/float64(n)/2, c.AT)
+
+	return m, nil
+}
+
+// contParams holds the parameters of the continuous model.
+type contParams struct {
+	mixture   []float64
+	mu, omega float64
+	beta, xi  float64
+}
+
+// params maps the unconstrained vector x of the maximization to the
+// parameters of k contributors: the mixture proportions by softmax, μ and ω
+// by exp, β and ξ by the logistic function.
+func (m contModel) params(x []float64, k int) contParams {
+
+	p := contParams{mixture: make([]float64, k), beta: 1}
+	var sum float64
+	for i := range p.mixture {
+		if i < k-1 {
+			p.mixture[i] = math.Exp(x[i])
+		} else {
+			p.mixture[i] = 1
+		}
+		sum += p.mixture[i]
+	}
+	for i := range p.mixture {
+		p.mixture[i] /= sum
+	}
+
+	x = x[k-1:]
+	p.mu, p.omega = math.Exp(x[0]), math.Exp(x[1])
+	x = x[2:]
+	if m.conf.Degradation {
+		p.beta = 1 / (1 + math.Exp(-x[0]))
+		x = x[1:]
+	}
+	if m.conf.Stutter {
+		p.xi = 1 / (1 + math.Exp(-x[0]))
+	}
+
+	return p
+}
+
+// fit returns the maximum likelihood fit of the model under hypothesis h and
+// the log likelihoods of the loci at the maximum.
+func (m contModel) fit(h Hypothesis) (ContinuousFit, []float64) {
+
+	k := h.contributors()
+	configs := make([][]genoConfig, len(m.loci))
+	for i, l := range m.loci {
+		configs[i] = l.configs(h)
+	}
+
+	negLogLik := func(x []float64) float64 {
+		p := m.params(x, k)
+		var r float64
+		for i, l := range m.loci {
+			r += l.logLik(configs[i], p, m.conf)
+		}
+		if math.IsNaN(r) {
+			return math.Inf(1)
+		}
+		return -r
+	}
+
+	// the starting point: equal mixture proportions, 30% variation, little
+	// degradation and stutter
+	x0 := make([]float64, k-1)
+	x0 = append(x0, math.Log(m.mu0), math.Log(0.3))
+	if m.conf.Degradation {
+		x0 = append(x0, 2)
+	}
+	if m.conf.Stutter {
+		x0 = append(x0, -3)
+	}
+	step := make([]float64, len(x0))
+	for i := range step {
+		step[i] = 0.5
+	}
+
+	rng := rand.New(rand.NewSource(m.conf.Seed))
+	best, bestVal := x0, math.Inf(1)
+	for s := 0; s < m.conf.Starts; s++ {
+		start := append([]float64(nil), x0...)
+		if s > 0 {
+			for i := range start {
+				start[i] += rng.NormFloat64() * step[i]
+			}
+		}
+		// restart at the minimum to escape a collapsed simplex
+		x, v := nelderMead(negLogLik, start, step, 200*len(x0), 1e-10)
+		x, v = nelderMead(negLogLik, x, step, 200*len(x0), 1e-10)
+		if v < bestVal {
+			best, bestVal = x, v
+		}
+	}
+
+	p := m.params(best, k)
+	r := ContinuousFit{LogLik: -bestVal, Mu: p.mu, Omega: p.omega, Beta: p.beta, Xi: p.xi}
+
+	// the unknowns are exchangeable
+	sort.Sort(sort.Reverse(sort.Float64Slice(p.mixture[len(h.Known):])))
+	r.Mixture = p.mixture
+
+	loci := make([]float64, len(m.loci))
+	for i, l := range m.loci {
+		loci[i] = l.logLik(configs[i], p, m.conf)
+	}
+	r.Validation = m.validate(configs, p)
+
+	return r, loci
+}
+
+// validate compares the observed peak heights with the heights expected
+// given the genotype configurations weighted by their posterior probability.
+func (m contModel) validate(configs [][]genoConfig, p contParams) ModelValidation {
+
+	c := m.conf
+	shape, scale := 1/(p.omega*p.omega), p.mu*p.omega*p.omega
+
+	r := ModelValidation{Level: validationLevel}
+	for i, l := range m.loci {
+
+		// posterior weights of the configurations
+		w := make([]float64, len(configs[i]))
+		for j, g := range configs[i] {
+			w[j] = math.Log(g.prob)
+			for _, y := range l.heights {
+				w[j] += l.replicateLogLik(l.alpha(g.genotypes, p), y, p, c)
+			}
+		}
+		norm := logSumExp(w)
+		for j := range w {
+			w[j] = math.Exp(w[j] - norm)
+		}
+
+		for rep, y := range l.heights {
+			for pos, h := range y {
+				if h == 0 {
+					continue
+				}
+				pv := PeakValidation{Sample: m.samples[l.replicates[rep]], Locus: l.id,
+					Allele: l.ids[pos], Height: h}
+				for j, g := range configs[i] {
+					a := l.alpha(g.genotypes, p)[pos]
+					pv.Expected += w[j] * p.mu * a
+					if a > 0 {
+						fAT := gammaCDF(c.AT, a*shape, scale)
+						pv.CDF += w[j] * (gammaCDF(h, a*shape, scale) - fAT) / (1 - fAT)
+					} else {
+						pv.CDF += w[j] * (1 - math.Exp(-c.DropInLambda*(h-c.AT)))
+					}
+				}
+				r.Peaks = append(r.Peaks, pv)
+			}
+		}
+	}
+
+	r.Pass = true
+	bound := r.Level / float64(2*len(r.Peaks))
+	for i := range r.Peaks {
+		if r.Peaks[i].CDF < bound || r.Peaks[i].CDF > 1-bound {
+			r.Peaks[i].Outlier = true
+			r.Pass = false
+		}
+	}
+
+	return r
+}
+
+// contLocus holds the data of the continuous model at a locus. Positions are
+// the alleles of mixLocus (including Q), followed by the back-stutter
+// positions of the alleles that are not alleles themselves.
+type contLocus struct {
+	mixLocus
+	id         string
+	heights    [][]float64 // peak heights per replicate and position; 0 if dropped out
+	replicates []int       // index of the sample of each replicate
+	size       []float64   // fragment size of each allele
+	stutter    []int       // back-stutter position of each allele; -1 for none
+	positions  int
+}
+
+// newContLocus prepares the continuous model of locus lID.
+func newContLocus(evidence, typed []Sample, lID string, f Freqs, c ContinuousConf) (contLocus, error) {
+
+	m := contLocus{mixLocus: newMixLocus(evidence, typed, lID, f, c.Theta), id: lID}
+
+	// sizes of the evidence alleles; all others get the mean size
+	m.size = make([]float64, len(m.p))
+	var sum float64
+	var n int
+	for _, s := range evidence {
+		for _, a := range s.Locus(lID).Alleles {
+			if i := m.index(a.ID); i >= 0 && a.Size > 0 && m.size[i] == 0 {
+				m.size[i] = a.Size
+				sum += a.Size
+				n++
+			}
+		}
+	}
+	mean := 125.0
+	if n > 0 {
+		mean = sum / float64(n)
+	}
+	for i := range m.size {
+		if m.size[i] == 0 {
+			m.size[i] = mean
+		}
+	}
+
+	// back-stutter positions
+	m.positions = len(m.p)
+	m.stutter = make([]int, len(m.p))
+	var extra []AlleleID
+	for i := range m.stutter {
+		m.stutter[i] = -1
+		if i == len(m.ids) { // Q
+			continue
+		}
+		st := m.ids[i].Shift(-1)
+		switch {
+		case st.IsZero():
+		case m.index(st) >= 0:
+			m.stutter[i] = m.index(st)
+		default:
+			m.stutter[i] = m.positions
+			for j, e := range extra {
+				if e == st {
+					m.stutter[i] = len(m.p) + j
+				}
+			}
+			if m.stutter[i] == m.positions {
+				extra = append(extra, st)
+				m.positions++
+			}
+		}
+	}
+
+	for rep, s := range evidence {
+		if !s.HasLocus(lID) { // the locus failed in this replicate
+			continue
+		}
+		y := make([]float64, m.positions)
+		for _, a := range s.Locus(lID).Alleles {
+			if !a.ID.IsTyped() {
+				continue
+			}
+			if a.Height <= 0 {
+				return contLocus{}, fmt.Errorf("sample %v, locus %v: allele %v without peak height",
+					s.ID, lID, a.ID)
+			}
+			if a.Height >= c.AT {
+				y[m.index(a.ID)] += a.Height
+			}
+		}
+		m.heights = append(m.heights, y)
+		m.replicates = append(m.replicates, rep)
+	}
+
+	return m, nil
+}
+
+// alpha returns the expected peak heights of all positions in units of μ
+// given the genotypes of the contributors.
+func (m contLocus) alpha(genotypes [][2]int, p contParams) []float64 {
+	a := make([]float64, m.positions)
+	for k, g := range genotypes {
+		for _, x := range g {
+			share := p.mixture[k] * math.Pow(p.beta, (m.size[x]-125)/100)
+			a[x] += (1 - p.xi) * share
+			if m.stutter[x] >= 0 {
+				a[m.stutter[x]] += p.xi * share
+			}
+		}
+	}
+	return a
+}
+
+// logLik returns the log likelihood of the peak heights of all replicates
+// summed over the genotype configurations.
+func (m contLocus) logLik(configs []genoConfig, p contParams, c ContinuousConf) float64 {
+	l := make([]float64, len(configs))
+	for i, g := range configs {
+		a := m.alpha(g.genotypes, p)
+		l[i] = math.Log(g.prob)
+		for _, y := range m.heights {
+			l[i] += m.replicateLogLik(a, y, p, c)
+		}
+	}
+	return logSumExp(l)
+}
+
+// replicateLogLik returns the log likelihood of the peak heights y of a
+// replicate given the expected heights a.
+func (m contLocus) replicateLogLik(a, y []float64, p contParams, c ContinuousConf) float64 {
+
+	shape, scale := 1/(p.omega*p.omega), p.mu*p.omega*p.omega
+
+	var r float64
+	dropIns := false
+	for pos := range a {
+		switch {
+		case a[pos] > 0 && y[pos] > 0:
+			r += logGammaPDF(y[pos], a[pos]*shape, scale)
+		case a[pos] > 0:
+			r += math.Log(gammaCDF(c.AT, a[pos]*shape, scale))
+		case y[pos] > 0:
+			r += math.Log(c.DropIn*m.p[pos]*c.DropInLambda) - c.DropInLambda*(y[pos]-c.AT)
+			dropIns = true
+		}
+	}
+	if !dropIns {
+		r += math.Log(1 - c.DropIn)
+	}
+
+	return r
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// contFreqs are the allele frequencies of the continuous model tests.
+var contFreqs = Freqs{Fmin: 0.001, Floci: []Flocus{
+	{ID: "VWA", Falleles: []Fallele{
+		{ID: A2ID("14"), Freq: 0.1}, {ID: A2ID("15"), Freq: 0.2}, {ID: A2ID("16"), Freq: 0.3},
+		{ID: A2ID("17"), Freq: 0.25}, {ID: A2ID("18"), Freq: 0.15},
+	}},
+	{ID: "TH01", Falleles: []Fallele{
+		{ID: A2ID("6"), Freq: 0.25}, {ID: A2ID("7"), Freq: 0.2}, {ID: A2ID("8"), Freq: 0.1},
+		{ID: A2ID("9"), Freq: 0.15}, {ID: A2ID("9.3"), Freq: 0.3},
+	}},
+	{ID: "D8S1179", Falleles: []Fallele{
+		{ID: A2ID("10"), Freq: 0.1}, {ID: A2ID("12"), Freq: 0.15}, {ID: A2ID("13"), Freq: 0.3},
+		{ID: A2ID("14"), Freq: 0.2}, {ID: A2ID("15"), Freq: 0.15},
+	}},
+}}
+
+// peaks returns a sample with ID id and the alleles given as
+// "locus:allele[:height]".
+func peaks(id string, alleles ...string) Sample {
+	s := Sample{ID: id}
+	for _, x := range alleles {
+		f := strings.Split(x, ":")
+		a := Allele{ID: A2ID(f[1])}
+		if len(f) > 2 {
+			a.Height, _ = strconv.ParseFloat(f[2], 64)
+		}
+		if !s.HasLocus(f[0]) {
+			s.Loci = append(s.Loci, Locus{ID: f[0]})
+		}
+		for i := range s.Loci {
+			if s.Loci[i].ID == f[0] {
+				s.Loci[i].Alleles = append(s.Loci[i].Alleles, a)
+			}
+		}
+	}
+	return s
+}
+
+// =============================================================================
+func Test_ContinuousLR_SingleSource(t *testing.T) {
+
+	suspect := peaks("suspect", "VWA:14", "VWA:17", "TH01:9.3", "D8S1179:13")
+	stain := peaks("stain", "VWA:14:1020", "VWA:17:950", "TH01:9.3:2050",
+		"D8S1179:13:1890", "AMEL:X:2100")
+
+	c := ContinuousConf{AT: 50, DropIn: 0.05, Starts: 1}
+	r, err := ContinuousLR([]Sample{stain}, Hypothesis{Known: []Sample{suspect}},
+		Hypothesis{Unknowns: 1}, contFreqs, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a high-template single source stain: LR = 1/RMP
+	want := 1 / (2 * 0.1 * 0.25 * 0.3 * 0.3 * 0.3 * 0.3)
+	if math.Abs(r.LR-want)/want > 0.01 {
+		t.Fatalf("expected: %v, got: %v", want, r.LR)
+	}
+	if len(r.Loci) != 4 || r.Loci[3].Excluded != "amelogenin" {
+		t.Fatalf("expected 4 loci with AMEL excluded, got: %+v", r.Loci)
+	}
+	// the loci are in the order of SemiContinuousLR
+	sc, err := SemiContinuousLR([]Sample{stain}, Hypothesis{Known: []Sample{suspect}, DropOut: []float64{0.1}},
+		Hypothesis{Unknowns: 1, DropOut: []float64{0.1}}, contFreqs, 0, 0.05)
+	if err != nil || len(sc.Loci) != len(r.Loci) {
+		t.Fatalf("unexpected semi-continuous LR: %+v (%v)", sc, err)
+	}
+	for i := range sc.Loci {
+		if sc.Loci[i].Locus != r.Loci[i].Locus || sc.Loci[i].Excluded != r.Loci[i].Excluded {
+			t.Fatalf("locus %d: expected: %v, got: %v", i+1, sc.Loci[i], r.Loci[i])
+		}
+	}
+	if math.Abs(r.Hp.Mu-1000)/1000 > 0.05 {
+		t.Fatalf("expected: μ ≈ 1000, got: %v", r.Hp.Mu)
+	}
+	if !r.Hp.Validation.Pass || len(r.Hp.Validation.Peaks) != 4 {
+		t.Fatalf("expected validation of 4 peaks to pass, got: %+v", r.Hp.Validation)
+	}
+}
+
+// =============================================================================
+func Test_ContinuousLR_Mixture(t *testing.T) {
+
+	// 70:30 mixture of the suspect and another person, μ = 1000
+	suspect := peaks("suspect", "VWA:14", "VWA:17", "TH01:6", "TH01:9.3", "D8S1179:12", "D8S1179:13")
+	stain := peaks("stain",
+		"VWA:14:720", "VWA:15:290", "VWA:17:680", "VWA:18:320",
+		"TH01:6:690", "TH01:7:610", "TH01:9.3:710",
+		"D8S1179:12:1020", "D8S1179:13:700", "D8S1179:14:310")
+
+	c := ContinuousConf{AT: 50, DropIn: 0.05, Starts: 1}
+	hp := Hypothesis{Known: []Sample{suspect}, Unknowns: 1}
+	hd := Hypothesis{Unknowns: 2}
+
+	r, err := ContinuousLR([]Sample{stain}, hp, hd, contFreqs, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, fit := range []ContinuousFit{r.Hp, r.Hd} {
+		if len(fit.Mixture) != 2 || math.Abs(fit.Mixture[0]-0.7) > 0.05 {
+			t.Fatalf("test %d: expected: mixture ≈ [0.7 0.3], got: %v", i+1, fit.Mixture)
+		}
+		if !fit.Validation.Pass {
+			t.Fatalf("test %d: expected validation to pass, got: %+v", i+1, fit.Validation)
+		}
+	}
+	if r.LR < 100 {
+		t.Fatalf("expected LR > 100, got: %v", r.LR)
+	}
+
+	// the suspect cannot be excluded but is not the major contributor
+	r, err = ContinuousLR([]Sample{stain}, Hypothesis{Known: []Sample{
+		peaks("minor", "VWA:15", "VWA:18", "TH01:7", "TH01:9.3", "D8S1179:12", "D8S1179:14")},
+		Unknowns: 1}, hd, contFreqs, c)
+	if err != nil || math.Abs(r.Hp.Mixture[0]-0.3) > 0.05 {
+		t.Fatalf("expected: mixture ≈ [0.3 0.7], got: %v (%v)", r.Hp.Mixture, err)
+	}
+}
+
+// =============================================================================
+func Test_FitContinuous(t *testing.T) {
+
+	// the peak of allele 17 of the first replicate is much too low for a
+	// heterozygous single source
+	stain := peaks("stain", "VWA:14:1000", "VWA:17:60", "TH01:6:1010", "TH01:9.3:990",
+		"D8S1179:12:980", "D8S1179:13:1020")
+	rep := peaks("replicate", "VWA:14:1030", "VWA:17:970", "TH01:6:1000", "TH01:9.3:990",
+		"D8S1179:12:960", "D8S1179:13:1040")
+
+	fit, err := FitContinuous([]Sample{stain, rep, rep}, Hypothesis{Known: []Sample{
+		peaks("suspect", "VWA:14", "VWA:17", "TH01:6", "TH01:9.3", "D8S1179:12", "D8S1179:13")}},
+		contFreqs, ContinuousConf{DropIn: 0.05, Starts: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fit.Validation.Pass {
+		t.Fatalf("expected an outlier, got: %+v", fit.Validation)
+	}
+
+	// the fit under hp conditioned on the known persons of hd is the fit of
+	// the LR; the victim is not typed at D8S1179
+	victim := peaks("victim", "VWA:14", "VWA:17", "TH01:6", "TH01:9.3")
+	hp := Hypothesis{Known: []Sample{peaks("suspect", "VWA:14", "VWA:17", "TH01:6", "TH01:9.3",
+		"D8S1179:12", "D8S1179:13")}}
+	hd := Hypothesis{Known: []Sample{victim}}
+	c := ContinuousConf{DropIn: 0.05, Starts: 1}
+	r, err := ContinuousLR([]Sample{rep}, hp, hd, contFreqs, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fit, err = FitContinuous([]Sample{rep}, hp, contFreqs, c, hd)
+	if err != nil || !reflect.DeepEqual(fit, r.Hp) {
+		t.Fatalf("expected: %+v, got: %+v (%v)", r.Hp, fit, err)
+	}
+	if fit, _ = FitContinuous([]Sample{rep}, hp, contFreqs, c); reflect.DeepEqual(fit, r.Hp) {
+		t.Fatalf("expected a different fit with D8S1179")
+	}
+
+	stain.Loci[0].Alleles[0].Height = 0
+	if _, err := FitContinuous([]Sample{stain}, Hypothesis{Unknowns: 1}, contFreqs,
+		ContinuousConf{}); err == nil {
+		t.Fatalf("expected error for allele without peak height")
+	}
+}
@@ -8,7 +8,8 @@ import (
 )
 
 // PI estimates the combined probability of inclusion for locus l, given the
-// allele frequencies f of population f.Pop.
+// allele frequencies f of population f.Pop. Off-ladder alleles, wildcards
+// and null alleles are not considered; rare alleles follow f.MinRule.
 func (l Locus) PI(f Freqs, theta float64) float64 {
 
 	// no freq info for this locus, CPI() tests for this but if PI() is called
@@ -17,17 +18,14 @@ func (l Locus) PI(f Freqs, theta float64) float64 {
 		return 0
 	}
 
-	floc := f.Flocus(l.ID)
-
 	var fSum float64
 	for _, a := range l.Alleles {
 
-		if !floc.HasFallele(a.ID) { // no freq info for this allele
-			fSum = fSum + f.Fmin
+		if !a.ID.IsTyped() { // e.g. OL, not an allele, hence no frequency
 			continue
 		}
 
-		fSum = fSum + floc.Fallele(a.ID).Freq
+		fSum = fSum + f.Freq(l.ID, a.ID) // Fmin if no freq info for this allele
 	}
 
 	return math.Pow(fSum, 2) + theta*fSum*(1-fSum)
@@ -47,7 +45,8 @@ func (l Locus) PE(f Freqs, theta float64) float64 {
 }
 
 // CPI estimates the combined probability of inclusion for stain s, given the
-// allele frequencies f of population f.Pop.
+// allele frequencies f of population f.Pop. Loci flagged as low quality or
+// with possible drop-out are excluded, as required by the SWGDAM guidelines.
 func (s Sample) CPI(f Freqs, theta float64) float64 {
 
 	var CPI float64
@@ -55,7 +54,12 @@ func (s Sample) CPI(f Freqs, theta float64) float64 {
 
 		// If the marker is unknown, we exclude it entirely. If the locus has no
 		// alleles we exclude it too.
-		if !f.HasFlocus(l.ID) || len(l.Alleles) == 0 {
+		if !f.HasFlocus(l.ID) || len(l.WithoutOffLadder().Alleles) == 0 {
+			continue
+		}
+
+		// Low quality loci cannot be trusted to show all alleles.
+		if l.IsLowQuality() || l.PossibleDropOut() {
 			continue
 		}
 
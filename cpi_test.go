@@ -22,12 +22,12 @@ func TestLocus_PI_PE(t *testing.T) {
 		{
 			Locus{
 				ID:      "VWA",
-				Alleles: []Allele{{ID: 17}, {ID: 21}},
+				Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21")}},
 			},
 			Freqs{
 				Fmin: 0.001,
 				Floci: []Flocus{
-					{ID: "VWA", Falleles: []Fallele{{ID: 17, Freq: 0.1}, {ID: 21, Freq: 0.03}}},
+					{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17"), Freq: 0.1}, {ID: A2ID("21"), Freq: 0.03}}},
 				},
 			},
 			0,
@@ -37,12 +37,12 @@ func TestLocus_PI_PE(t *testing.T) {
 		{
 			Locus{
 				ID:      "VWA",
-				Alleles: []Allele{{ID: 17}, {ID: 21}, {ID: 27.1}},
+				Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21")}, {ID: A2ID("27.1")}},
 			},
 			Freqs{
 				Fmin: 0.001,
 				Floci: []Flocus{
-					{ID: "VWA", Falleles: []Fallele{{ID: 17, Freq: 0.1}, {ID: 21, Freq: 0.03}}},
+					{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17"), Freq: 0.1}, {ID: A2ID("21"), Freq: 0.03}}},
 				},
 			},
 			0,
@@ -52,7 +52,7 @@ func TestLocus_PI_PE(t *testing.T) {
 		{
 			Locus{
 				ID:      "VWA",
-				Alleles: []Allele{{ID: 17}, {ID: 21}},
+				Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21")}},
 			},
 			Freqs{
 				Fmin:  0.001,
@@ -65,12 +65,12 @@ func TestLocus_PI_PE(t *testing.T) {
 		{
 			Locus{
 				ID:      "SE33",
-				Alleles: []Allele{{ID: 17}, {ID: 21}, {ID: 21.3}},
+				Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21")}, {ID: A2ID("21.3")}},
 			},
 			Freqs{
 				Fmin: 0.001,
 				Floci: []Flocus{
-					{ID: "SE33", Falleles: []Fallele{{ID: 17, Freq: 0.1}, {ID: 21, Freq: 0.2}, {ID: 21.3, Freq: 0.3}}},
+					{ID: "SE33", Falleles: []Fallele{{ID: A2ID("17"), Freq: 0.1}, {ID: A2ID("21"), Freq: 0.2}, {ID: A2ID("21.3"), Freq: 0.3}}},
 				},
 			},
 			0.01,
@@ -80,12 +80,12 @@ func TestLocus_PI_PE(t *testing.T) {
 		{
 			Locus{
 				ID:      "SE33",
-				Alleles: []Allele{{ID: 17}, {ID: 21}, {ID: 21.3}},
+				Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21")}, {ID: A2ID("21.3")}},
 			},
 			Freqs{
 				Fmin: 0.01,
 				Floci: []Flocus{
-					{ID: "SE33", Falleles: []Fallele{{ID: 17, Freq: 0.1}, {ID: 21, Freq: 0.2}, {ID: 21.3, Freq: 0.3}}},
+					{ID: "SE33", Falleles: []Fallele{{ID: A2ID("17"), Freq: 0.1}, {ID: A2ID("21"), Freq: 0.2}, {ID: A2ID("21.3"), Freq: 0.3}}},
 				},
 			},
 			0.03,
@@ -125,15 +125,15 @@ func TestLocus_CPI_CPE_RMNE(t *testing.T) {
 		{
 			Sample{
 				Loci: []Locus{
-					{ID: "VWA", Alleles: []Allele{{ID: 17}, {ID: 21}}},
-					{ID: "FGA", Alleles: []Allele{{ID: 20.1}, {ID: 31}}},
-					{ID: "DYS391", Alleles: []Allele{{ID: 12}}}},
+					{ID: "VWA", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21")}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("20.1")}, {ID: A2ID("31")}}},
+					{ID: "DYS391", Alleles: []Allele{{ID: A2ID("12")}}}},
 			},
 			Freqs{
 				Fmin: 0.001,
 				Floci: []Flocus{
-					{ID: "VWA", Falleles: []Fallele{{ID: 17, Freq: 0.1}, {ID: 21, Freq: 0.03}}},
-					{ID: "FGA", Falleles: []Fallele{{ID: 20.1, Freq: 0.21}, {ID: 31, Freq: 0.17}}},
+					{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17"), Freq: 0.1}, {ID: A2ID("21"), Freq: 0.03}}},
+					{ID: "FGA", Falleles: []Fallele{{ID: A2ID("20.1"), Freq: 0.21}, {ID: A2ID("31"), Freq: 0.17}}},
 				},
 			},
 			0,
@@ -144,13 +144,13 @@ func TestLocus_CPI_CPE_RMNE(t *testing.T) {
 		{
 			Sample{
 				Loci: []Locus{
-					{ID: "DYS391", Alleles: []Allele{{ID: 12}}}},
+					{ID: "DYS391", Alleles: []Allele{{ID: A2ID("12")}}}},
 			},
 			Freqs{
 				Fmin: 0.001,
 				Floci: []Flocus{
-					{ID: "VWA", Falleles: []Fallele{{ID: 17, Freq: 0.1}, {ID: 21, Freq: 0.03}}},
-					{ID: "FGA", Falleles: []Fallele{{ID: 20.1, Freq: 0.21}, {ID: 31, Freq: 0.17}}},
+					{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17"), Freq: 0.1}, {ID: A2ID("21"), Freq: 0.03}}},
+					{ID: "FGA", Falleles: []Fallele{{ID: A2ID("20.1"), Freq: 0.21}, {ID: A2ID("31"), Freq: 0.17}}},
 				},
 			},
 			0,
@@ -158,6 +158,26 @@ func TestLocus_CPI_CPE_RMNE(t *testing.T) {
 			1,
 			0,
 		},
+		{ // off-ladder alleles and low quality loci are not considered
+			Sample{
+				Loci: []Locus{
+					{ID: "VWA", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21")}, {ID: A2ID("OL"), Size: 170.2}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("20.1")}, {ID: A2ID("31")}}},
+					{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}}, PQVs: PQVs{"ADO": LOWQUALITY}}},
+			},
+			Freqs{
+				Fmin: 0.001,
+				Floci: []Flocus{
+					{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17"), Freq: 0.1}, {ID: A2ID("21"), Freq: 0.03}}},
+					{ID: "FGA", Falleles: []Fallele{{ID: A2ID("20.1"), Freq: 0.21}, {ID: A2ID("31"), Freq: 0.17}}},
+					{ID: "TH01", Falleles: []Fallele{{ID: A2ID("6"), Freq: 0.2}}},
+				},
+			},
+			0,
+			0.0024403600000000004,
+			0.9975596399999999996,
+			409.77560687767374,
+		},
 	}
 
 	for i, tc := range tests {
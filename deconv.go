@@ -0,0 +1,262 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// DeconvolutionConf holds the parameters of the deconvolution of a mixture.
+type DeconvolutionConf struct {
+	// Mixture holds the number of contributors and the parameters of the
+	// estimation of the mixture proportions. Genotype combinations with a root
+	// mean square residual above Mixture.Tolerance are rejected.
+	Mixture   MixtureConf
+	Hb        float64 // smallest heterozygote balance of a contributor
+	Sigma     float64 // standard deviation of the peak height proportions
+	MinWeight float64 // smallest weight of a reported genotype combination
+}
+
+// DefaultDeconvolutionConf holds the default parameters of the deconvolution.
+var DefaultDeconvolutionConf = DeconvolutionConf{
+	Mixture:   DefaultMixtureConf,
+	Hb:        0.6,
+	Sigma:     0.05,
+	MinWeight: 0.001,
+}
+
+// withDefaults returns c with zero values replaced by the defaults.
+func (c DeconvolutionConf) withDefaults() DeconvolutionConf {
+	c.Mixture = c.Mixture.withDefaults()
+	if c.Hb == 0 {
+		c.Hb = DefaultDeconvolutionConf.Hb
+	}
+	if c.Sigma == 0 {
+		c.Sigma = DefaultDeconvolutionConf.Sigma
+	}
+	if c.MinWeight == 0 {
+		c.MinWeight = DefaultDeconvolutionConf.MinWeight
+	}
+	return c
+}
+
+// GenotypeSet is a combination of the genotypes of all contributors at a
+// locus.
+type GenotypeSet struct {
+	Genotypes [][2]AlleleID // genotypes of the contributors in order of Mixture
+	Residual  float64       // root mean square difference of the peak height proportions
+	Weight    float64
+}
+
+// GenotypeWeight is a genotype with its weight.
+type GenotypeWeight struct {
+	Genotype [2]AlleleID
+	Weight   float64
+}
+
+// LocusDeconvolution holds the genotype combinations of a locus ranked by
+// decreasing weight.
+type LocusDeconvolution struct {
+	Locus string
+	Sets  []GenotypeSet
+}
+
+// Deconvolution holds the deconvolution of a mixture.
+type Deconvolution struct {
+	Sample  string
+	Mixture MixtureEstimate
+	Loci    []LocusDeconvolution
+}
+
+// DeconvolutionMatch holds the comparison of a reference with a contributor
+// of a deconvolution.
+type DeconvolutionMatch struct {
+	Reference   string
+	Contributor int     // index of the contributor in Mixture
+	Loci        int     // number of deconvolved loci typed in the reference
+	Matches     int     // loci with the genotype of the reference amongst the genotypes
+	TopMatches  int     // loci with the genotype of the reference ranked first
+	Weight      float64 // product of the weights of the genotypes of the reference
+}
+
+// Deconvolve deconvolves the 2 or 3 contributors of mixture s. The mixture
+// proportions are estimated by EstimateMixture. At each locus, every
+// combination of genotypes explaining the alleles is scored by the
+// differences d of the observed and expected peak height proportions for
+// these mixture proportions with a weight proportional to
+// exp(-Σd²/(2 c.Sigma²)). Combinations are rejected if the heterozygote
+// balance of a contributor is below c.Hb, when the peak heights are
+// apportioned to the contributors by their expected shares, or if their root
+// mean square difference exceeds c.Mixture.Tolerance. The weights of a locus
+// are normalized over the combinations with a weight of at least
+// c.MinWeight. The deconvolution is conditioned on the known contributors
+// c.Mixture.Known: their genotypes are fixed, and their alleles remain
+// available to the unknown contributors.
+func (s Sample) Deconvolve(c DeconvolutionConf) (Deconvolution, error) {
+
+	c = c.withDefaults()
+	if c.Hb < 0 || c.Hb > 1 || c.Sigma < 0 {
+		return Deconvolution{}, fmt.Errorf("heterozygote balance %v not in [0, 1] or negative sigma %v", c.Hb, c.Sigma)
+	}
+
+	m, err := s.EstimateMixture(c.Mixture)
+	if err != nil {
+		return Deconvolution{}, err
+	}
+
+	d := Deconvolution{Sample: s.ID, Mixture: m}
+	for _, lm := range m.Loci {
+		rl, _ := newRatioLocus(s.Locus(lm.Locus), m.Contributors, c.Mixture.Known)
+		d.Loci = append(d.Loci, rl.deconvolve(m.Mixture, c))
+	}
+
+	return d, nil
+}
+
+// deconvolve returns the weighted genotype combinations of rl for mixture
+// proportions phi.
+func (rl ratioLocus) deconvolve(phi []float64, c DeconvolutionConf) LocusDeconvolution {
+
+	nk := len(c.Mixture.Known)
+	ld := LocusDeconvolution{Locus: rl.id}
+	var sum float64
+	for _, combo := range rl.combos {
+		ss := rl.residual(combo, phi)
+		rms := math.Sqrt(ss / float64(len(rl.alleles)))
+		if rms > c.Mixture.Tolerance || !rl.balanced(combo[nk:], phi[nk:], rl.expected(combo, phi), c.Hb) {
+			continue
+		}
+
+		gs := GenotypeSet{Residual: rms, Weight: math.Exp(-ss / (2 * c.Sigma * c.Sigma))}
+		for _, gt := range combo {
+			gs.Genotypes = append(gs.Genotypes, orderedGenotype(rl.alleles[gt[0]], rl.alleles[gt[1]]))
+		}
+		ld.Sets = append(ld.Sets, gs)
+		sum += gs.Weight
+	}
+
+	// normalize, drop negligible combinations and normalize again
+	var kept []GenotypeSet
+	var keptSum float64
+	for _, gs := range ld.Sets {
+		if gs.Weight/sum >= c.MinWeight {
+			kept = append(kept, gs)
+			keptSum += gs.Weight
+		}
+	}
+	for i := range kept {
+		kept[i].Weight /= keptSum
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Weight > kept[j].Weight })
+	ld.Sets = kept
+
+	return ld
+}
+
+// balanced reports whether the heterozygous genotypes gts with the mixture
+// proportions phi have a heterozygote balance of at least hb, when the peak
+// heights are apportioned by their shares of the expected proportions exp.
+func (rl ratioLocus) balanced(gts [][2]int, phi, exp []float64, hb float64) bool {
+	for k, gt := range gts {
+		if gt[0] == gt[1] {
+			continue
+		}
+		share := func(i int) float64 { return rl.obs[i] * phi[k] / 2 / exp[i] }
+		h1, h2 := share(gt[0]), share(gt[1])
+		if math.Min(h1, h2) < hb*math.Max(h1, h2) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contributor returns the genotypes of contributor k at all loci ranked by
+// decreasing weight. The weight of a genotype is the sum of the weights of
+// the genotype combinations containing it.
+func (d Deconvolution) Contributor(k int) map[string][]GenotypeWeight {
+
+	r := make(map[string][]GenotypeWeight)
+	for _, ld := range d.Loci {
+		var gws []GenotypeWeight
+		for _, gs := range ld.Sets {
+			i := 0
+			for i < len(gws) && gws[i].Genotype != gs.Genotypes[k] {
+				i++
+			}
+			if i == len(gws) {
+				gws = append(gws, GenotypeWeight{Genotype: gs.Genotypes[k]})
+			}
+			gws[i].Weight += gs.Weight
+		}
+		sort.SliceStable(gws, func(i, j int) bool { return gws[i].Weight > gws[j].Weight })
+		r[ld.Locus] = gws
+	}
+	return r
+}
+
+// Compare compares reference ref with all contributors of d at the loci
+// with genotype combinations that are typed in ref.
+func (d Deconvolution) Compare(ref Sample) []DeconvolutionMatch {
+
+	var r []DeconvolutionMatch
+	for k := 0; k < d.Mixture.Contributors; k++ {
+		wm := d.Profile(k).Compare(ref)
+		r = append(r, DeconvolutionMatch{Reference: ref.ID, Contributor: k,
+			Loci: wm.Loci, Matches: wm.Matches, TopMatches: wm.TopMatches, Weight: wm.Weight})
+	}
+	return r
+}
+
+// refGenotype returns the genotype of reference locus l, with the smaller
+// allele first. It returns false if l has not one or two typed alleles.
+func refGenotype(l Locus) ([2]AlleleID, bool) {
+	ids := typedAlleles(l)
+	switch len(ids) {
+	case 1:
+		return [2]AlleleID{ids[0], ids[0]}, true
+	case 2:
+		return [2]AlleleID{ids[0], ids[1]}, true
+	}
+	return [2]AlleleID{}, false
+}
+
+// orderedGenotype returns the genotype of alleles a and b with the smaller allele
+// first. The wildcard F is always second.
+func orderedGenotype(a, b AlleleID) [2]AlleleID {
+	if a.IsWildcard() || (!b.IsWildcard() && b.Less(a)) {
+		return [2]AlleleID{b, a}
+	}
+	return [2]AlleleID{a, b}
+}
+
+// ExportDeconvolution writes the genotype combinations of d to file f,
+// separated by sep.
+func ExportDeconvolution(d Deconvolution, f string, sep rune) error {
+	return write2CSV(buildDeconvolution(d), f, sep)
+}
+
+// buildDeconvolution builds the rows of the deconvolution table. See
+// ExportDeconvolution.
+func buildDeconvolution(d Deconvolution) [][]string {
+	header := []string{"Sample Name", "Marker", "Rank", "Weight", "Residual"}
+	for k, phi := range d.Mixture.Mixture {
+		header = append(header, fmt.Sprintf("Contributor %d (%.2f)", k+1, phi))
+	}
+	r := [][]string{header}
+	for _, ld := range d.Loci {
+		for i, gs := range ld.Sets {
+			row := []string{d.Sample, ld.Locus, strconv.Itoa(i + 1),
+				strconv.FormatFloat(gs.Weight, 'g', 6, 64),
+				strconv.FormatFloat(gs.Residual, 'g', 6, 64)}
+			for _, gt := range gs.Genotypes {
+				row = append(row, gt[0].String()+","+gt[1].String())
+			}
+			r = append(r, row)
+		}
+	}
+	return r
+}
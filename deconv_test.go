@@ -0,0 +1,112 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"testing"
+)
+
+// =============================================================================
+func TestSample_Deconvolve(t *testing.T) {
+
+	d, err := mixStain.Deconvolve(DeconvolutionConf{Mixture: MixtureConf{Seed: 1, Bootstrap: 200}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top := map[string][2][2]AlleleID{
+		"VWA":     {{A2ID("12"), A2ID("14")}, {A2ID("13"), A2ID("13")}},
+		"D3S1358": {{A2ID("15"), A2ID("16")}, {A2ID("17"), A2ID("18")}},
+		"TH01":    {{A2ID("9"), A2ID("9.3")}, {A2ID("6"), A2ID("9.3")}},
+		"D8S1179": {{A2ID("11"), A2ID("13")}, {A2ID("11"), A2ID("11")}},
+		"D21S11":  {{A2ID("20"), A2ID("21")}, {A2ID("22"), A2ID("24")}},
+	}
+	for _, ld := range d.Loci {
+		want, ok := top[ld.Locus]
+		if !ok {
+			if len(ld.Sets) != 0 {
+				t.Fatalf("%v: expected: no genotype combinations, got: %v", ld.Locus, ld.Sets)
+			}
+			continue
+		}
+		var sum float64
+		for i, gs := range ld.Sets {
+			sum += gs.Weight
+			if i > 0 && gs.Weight > ld.Sets[i-1].Weight {
+				t.Fatalf("%v: expected: decreasing weights, got: %v", ld.Locus, ld.Sets)
+			}
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Fatalf("%v: expected: weights sum to 1, got: %v", ld.Locus, sum)
+		}
+		if got := [2][2]AlleleID{ld.Sets[0].Genotypes[0], ld.Sets[0].Genotypes[1]}; got != want {
+			t.Fatalf("%v: expected: %v, got: %v", ld.Locus, want, got)
+		}
+	}
+
+	// the minor contributor at D8S1179 is 11,11 or 11,13
+	minor := d.Contributor(1)["D8S1179"]
+	if len(minor) == 0 || minor[0].Genotype != [2]AlleleID{A2ID("11"), A2ID("11")} {
+		t.Fatalf("expected: 11,11 first, got: %v", minor)
+	}
+
+	major := peaks("major", "VWA:12", "VWA:14", "D3S1358:15", "D3S1358:16",
+		"TH01:9", "TH01:9.3", "D8S1179:11", "D8S1179:13", "D21S11:20", "D21S11:21", "FGA:20")
+	m := d.Compare(major)
+	if len(m) != 2 || m[0].Loci != 5 || m[0].TopMatches != 5 || m[0].Weight <= m[1].Weight {
+		t.Fatalf("expected: major contributor matches at 5 loci, got: %v", m)
+	}
+
+	rows := buildDeconvolution(d)
+	if len(rows[0]) != 7 || rows[0][5] != "Contributor 1 (0.75)" || rows[1][5] != "12,14" {
+		t.Fatalf("unexpected table %v", rows[:2])
+	}
+
+	// three contributors
+	c3 := DeconvolutionConf{Mixture: MixtureConf{Contributors: 3, Step: 0.02, Bootstrap: 50}}
+	d3, err := mixStain.Deconvolve(c3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d3.Mixture.Mixture) != 3 || len(d3.Loci) != 6 || len(d3.Loci[0].Sets[0].Genotypes) != 3 {
+		t.Fatalf("unexpected deconvolution %v", d3)
+	}
+
+	if _, err := mixStain.Deconvolve(DeconvolutionConf{Hb: 2}); err == nil {
+		t.Fatalf("expected: error for heterozygote balance 2")
+	}
+}
+
+// =============================================================================
+func TestSample_DeconvolveConditioned(t *testing.T) {
+
+	c := DeconvolutionConf{Mixture: MixtureConf{Seed: 1, Bootstrap: 100, Known: []Sample{mixMajor}}}
+	d, err := mixStain.Deconvolve(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// alleles shared with the victim remain available to the perpetrator
+	want := map[string][2]AlleleID{
+		"VWA":     {A2ID("13"), A2ID("13")},
+		"D3S1358": {A2ID("17"), A2ID("18")},
+		"TH01":    {A2ID("6"), A2ID("9.3")},
+		"D8S1179": {A2ID("11"), A2ID("11")},
+		"D21S11":  {A2ID("22"), A2ID("24")},
+	}
+	for lID, gws := range d.Contributor(1) {
+		if len(gws) == 0 {
+			continue
+		}
+		if gws[0].Genotype != want[lID] {
+			t.Fatalf("%v: expected: %v, got: %v", lID, want[lID], gws)
+		}
+	}
+	for lID, gws := range d.Contributor(0) {
+		if lID != "FGA" && (len(gws) != 1 || math.Abs(gws[0].Weight-1) > 1e-9) {
+			t.Fatalf("%v: expected: the genotype of the victim, got: %v", lID, gws)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Family is the reference pedigree of a missing person for disaster victim
+// identification (DVI).
+type Family struct {
+	ID       string
+	Pedigree Pedigree
+	Missing  string   // ID of the missing person in Pedigree
+	Members  []Sample // the profiles of the typed relatives
+}
+
+// NewFamily returns the family id with the pedigree p of the missing person.
+// The typed relatives are the samples of refs (e.g. read by ReadGMRefs) with
+// the IDs of persons of p; all other samples are ignored.
+func NewFamily(id string, p Pedigree, missing string, refs []Sample) (Family, error) {
+
+	if err := p.Validate(); err != nil {
+		return Family{}, fmt.Errorf("family %v: %w", id, err)
+	}
+	if _, ok := p.Person(missing); !ok {
+		return Family{}, fmt.Errorf("family %v: missing person %v not in pedigree", id, missing)
+	}
+
+	fam := Family{ID: id, Pedigree: p, Missing: missing}
+	for _, s := range refs {
+		if _, ok := p.Person(s.ID); !ok {
+			continue
+		}
+		if s.ID == missing {
+			return Family{}, fmt.Errorf("family %v: missing person %v is typed", id, missing)
+		}
+		fam.Members = append(fam.Members, s)
+	}
+	if len(fam.Members) == 0 {
+		return Family{}, fmt.Errorf("family %v: no typed relatives", id)
+	}
+
+	return fam, nil
+}
+
+// DVIMatch holds the LR of a victim being the missing person of a family.
+type DVIMatch struct {
+	Victim     string
+	Family     string
+	Missing    string
+	LR         float64
+	FamilyRank int // rank of the victim amongst all victims of the family
+	VictimRank int // rank of the family amongst all families of the victim
+}
+
+// DVIConflict is a victim that is the best match of several families.
+type DVIConflict struct {
+	Victim   string
+	Families []string
+}
+
+// LR returns the likelihood ratio of the hypotheses 'victim is the missing
+// person of fam' vs. 'victim is unrelated to fam' (see KinshipLR).
+func (fam Family) LR(victim Sample, f Freqs, c KinshipConf) (LRResult, error) {
+
+	// under hd the missing person is untyped and the victim unrelated
+	hd := renamePerson(fam.Pedigree, fam.Missing, "(missing person)")
+	hd.Persons = append(hd.Persons, PedPerson{ID: fam.Missing})
+
+	victim.ID = fam.Missing
+	r, err := KinshipLR(append([]Sample{victim}, fam.Members...), fam.Pedigree, hd, f, c)
+	if err != nil {
+		return LRResult{}, fmt.Errorf("family %v: %w", fam.ID, err)
+	}
+	return r, nil
+}
+
+// renamePerson returns pedigree p with person old renamed to to.
+func renamePerson(p Pedigree, old, to string) Pedigree {
+	r := Pedigree{Name: p.Name}
+	for _, x := range p.Persons {
+		if x.ID == old {
+			x.ID = to
+		}
+		if x.Father == old {
+			x.Father = to
+		}
+		if x.Mother == old {
+			x.Mother = to
+		}
+		r.Persons = append(r.Persons, x)
+	}
+	return r
+}
+
+// DVIMatches returns the LRs of all victims being the missing person of all
+// families, sorted by decreasing LR.
+func DVIMatches(victims []Sample, families []Family, f Freqs, c KinshipConf) ([]DVIMatch, error) {
+
+	var r []DVIMatch
+	for _, v := range victims {
+		for _, fam := range families {
+			lr, err := fam.LR(v, f, c)
+			if err != nil {
+				return nil, fmt.Errorf("victim %v: %w", v.ID, err)
+			}
+			r = append(r, DVIMatch{Victim: v.ID, Family: fam.ID, Missing: fam.Missing, LR: lr.LR})
+		}
+	}
+
+	sort.SliceStable(r, func(i, j int) bool { return r[i].LR > r[j].LR })
+
+	// matches are sorted, so ranks increase in order
+	famRank := make(map[string]int)
+	victimRank := make(map[string]int)
+	for i := range r {
+		famRank[r[i].Family]++
+		victimRank[r[i].Victim]++
+		r[i].FamilyRank = famRank[r[i].Family]
+		r[i].VictimRank = victimRank[r[i].Victim]
+	}
+
+	return r, nil
+}
+
+// DVIConflicts returns the victims that are the best match of more than one
+// family with an LR of at least threshold.
+func DVIConflicts(matches []DVIMatch, threshold float64) []DVIConflict {
+
+	var r []DVIConflict
+	idx := make(map[string]int)
+	for _, m := range matches {
+		if m.FamilyRank != 1 || m.LR < threshold {
+			continue
+		}
+		i, ok := idx[m.Victim]
+		if !ok {
+			i = len(r)
+			idx[m.Victim] = i
+			r = append(r, DVIConflict{Victim: m.Victim})
+		}
+		r[i].Families = append(r[i].Families, m.Family)
+	}
+
+	var conflicts []DVIConflict
+	for _, c := range r {
+		if len(c.Families) > 1 {
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts
+}
+
+// ExportDVIMatches writes the match table to file f, separated by sep.
+func ExportDVIMatches(matches []DVIMatch, f string, sep rune) error {
+	return write2CSV(buildDVIMatches(matches), f, sep)
+}
+
+// buildDVIMatches builds the rows of the match table. See ExportDVIMatches.
+func buildDVIMatches(matches []DVIMatch) [][]string {
+	d := [][]string{{"Victim", "Family", "Missing Person", "LR", "Family Rank", "Victim Rank"}}
+	for _, m := range matches {
+		d = append(d, []string{m.Victim, m.Family, m.Missing,
+			strconv.FormatFloat(m.LR, 'g', 6, 64),
+			strconv.Itoa(m.FamilyRank), strconv.Itoa(m.VictimRank)})
+	}
+	return d
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+func Test_DVIMatches(t *testing.T) {
+
+	in := "Sample Name\tPanel\tMarker\tAlleles\n" +
+		"F1\tNGM\tVWA\t14, 15\nF1\tNGM\tTH01\t6, 7\nF1\tNGM\tD8S1179\t10, 12\n" +
+		"M1\tNGM\tVWA\t16, 17\nM1\tNGM\tTH01\t8, 9\nM1\tNGM\tD8S1179\t13, 14\n" +
+		"F2\tNGM\tVWA\t18\nF2\tNGM\tTH01\t9.3\nF2\tNGM\tD8S1179\t15\n"
+	refs, err := ReadGMRefsFrom(strings.NewReader(in), "refs")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	famA, err := NewFamily("A", Trio("C1", "F1", "M1"), "C1", refs)
+	if err != nil || len(famA.Members) != 2 {
+		t.Fatalf("family A: unexpected members %v (%v)", famA.Members, err)
+	}
+	famB, err := NewFamily("B", Trio("C2", "F2", "M2"), "C2", refs)
+	if err != nil || len(famB.Members) != 1 {
+		t.Fatalf("family B: unexpected members %v (%v)", famB.Members, err)
+	}
+
+	victims := []Sample{
+		peaks("V1", "VWA:14", "VWA:17", "TH01:6", "TH01:9", "D8S1179:12", "D8S1179:13"),
+		peaks("V2", "VWA:15", "VWA:18", "TH01:6", "TH01:9.3", "D8S1179:13", "D8S1179:15"),
+		peaks("V3", "VWA:16", "TH01:7", "D8S1179:10"),
+	}
+
+	c := KinshipConf{Model: NOMUTATION}
+	matches, err := DVIMatches(victims, []Family{famA, famB}, contFreqs, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 6 {
+		t.Fatalf("expected 6 matches, got: %v", matches)
+	}
+
+	best := make(map[string]string)
+	for _, m := range matches {
+		if m.FamilyRank == 1 {
+			best[m.Family] = m.Victim
+		}
+		if m.Victim == "V3" && m.Family == "A" && m.LR != 0 {
+			t.Fatalf("expected exclusion of V3 from family A, got: %v", m.LR)
+		}
+	}
+	if want := map[string]string{"A": "V1", "B": "V2"}; !reflect.DeepEqual(best, want) {
+		t.Fatalf("expected: %v, got: %v", want, best)
+	}
+
+	// V1 is a child of both typed parents: 1/(4 2pq) per locus
+	lr, err := famA.LR(victims[0], contFreqs, c)
+	want := 1 / (8 * 0.1 * 0.25) / (8 * 0.25 * 0.15) / (8 * 0.15 * 0.3)
+	if err != nil || lr.LR/want < 0.999 || lr.LR/want > 1.001 {
+		t.Fatalf("expected: %v, got: %v (%v)", want, lr.LR, err)
+	}
+
+	if c := DVIConflicts(matches, 1); len(c) != 0 {
+		t.Fatalf("expected no conflicts, got: %v", c)
+	}
+
+	// a second family with the references of family A
+	famC := famA
+	famC.ID = "C"
+	matches, _ = DVIMatches(victims, []Family{famA, famB, famC}, contFreqs, c)
+	conflicts := DVIConflicts(matches, 1)
+	if len(conflicts) != 1 || conflicts[0].Victim != "V1" || len(conflicts[0].Families) != 2 {
+		t.Fatalf("expected conflict of V1 in families A and C, got: %v", conflicts)
+	}
+
+	if rows := buildDVIMatches(matches[:1]); len(rows) != 2 || rows[1][0] != "V1" {
+		t.Fatalf("unexpected match table: %v", rows)
+	}
+}
+
+// =============================================================================
+func Test_NewFamily(t *testing.T) {
+
+	refs := []Sample{peaks("F", "VWA:14"), peaks("C", "VWA:14")}
+
+	if _, err := NewFamily("A", Trio("C", "F", "M"), "C", refs); err == nil {
+		t.Fatalf("test 1: expected error for typed missing person")
+	}
+	if _, err := NewFamily("A", Trio("C", "F", "M"), "X", refs); err == nil {
+		t.Fatalf("test 2: expected error for unknown missing person")
+	}
+	if _, err := NewFamily("A", Trio("X", "Y", "Z"), "X", refs); err == nil {
+		t.Fatalf("test 3: expected error without typed relatives")
+	}
+}
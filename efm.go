@@ -0,0 +1,212 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// The functions in this file export evidence, reference profiles, and allele
+// frequencies in the table layouts imported by EuroForMix and LRmix Studio.
+// Locus names are mapped by EFMLocusName in all tables, so that the tools
+// match the loci of the evidence, the references, and the frequencies.
+
+// efmAliases maps locus names (normalised by NormalizeESS) to the names of
+// the kit definitions of EuroForMix and LRmix Studio.
+var efmAliases = map[string]string{
+	"PENTAD": "PENTA D",
+	"PENTAE": "PENTA E",
+}
+
+// EFMLocusName returns the name of locus id as expected by EuroForMix and
+// LRmix Studio, i.e. in upper case and with the ESS spelling (e.g. VWA for
+// vWA, TH01 for THO1, AMEL for Amelogenin).
+func EFMLocusName(id string) string {
+	n, _ := NormalizeESS(id)
+	if a, ok := efmAliases[n]; ok {
+		return a
+	}
+	return n
+}
+
+// ExportEFMEvidence writes the evidence samples to file f, separated by sep,
+// in the layout 'Sample Name, Marker, Allele 1, ..., Height 1, ...'. If
+// heights is false, the height columns are omitted as for LRmix Studio.
+// Off-ladder alleles are not exported.
+func ExportEFMEvidence(samples []Sample, f string, sep rune, heights bool) error {
+	return write2CSV(buildEFMEvidence(samples, heights), f, sep)
+}
+
+// buildEFMEvidence builds the rows of an evidence table. See
+// ExportEFMEvidence.
+func buildEFMEvidence(samples []Sample, heights bool) [][]string {
+
+	var max int
+	for _, s := range samples {
+		for _, l := range s.Loci {
+			if n := len(l.WithoutOffLadder().Alleles); n > max {
+				max = n
+			}
+		}
+	}
+
+	header := []string{"Sample Name", "Marker"}
+	for i := 1; i <= max; i++ {
+		header = append(header, "Allele "+strconv.Itoa(i))
+	}
+	if heights {
+		for i := 1; i <= max; i++ {
+			header = append(header, "Height "+strconv.Itoa(i))
+		}
+	}
+
+	d := [][]string{header}
+	for _, s := range samples {
+		for _, l := range s.Loci {
+			alleles := l.WithoutOffLadder().Alleles
+
+			row := []string{s.ID, EFMLocusName(l.ID)}
+			for i := 0; i < max; i++ {
+				row = append(row, efmCell(alleles, i, func(a Allele) string {
+					return a.ID.String()
+				}))
+			}
+			if heights {
+				for i := 0; i < max; i++ {
+					row = append(row, efmCell(alleles, i, func(a Allele) string {
+						return A2String(a.Height)
+					}))
+				}
+			}
+			d = append(d, row)
+		}
+	}
+
+	return d
+}
+
+// efmCell returns the value v of allele i of alleles or an empty string if
+// there is no such allele.
+func efmCell(alleles []Allele, i int, v func(a Allele) string) string {
+	if i < len(alleles) {
+		return v(alleles[i])
+	}
+	return ""
+}
+
+// ExportEFMReferences writes the reference profiles refs (e.g. as read by
+// ReadGMRefs) to file f, separated by sep, in the layout 'Sample Name,
+// Marker, Allele 1, Allele 2'. Homozygous loci are written with the allele
+// twice, as both tools expect. Loci with more than two alleles cannot be
+// references and return an error.
+func ExportEFMReferences(refs []Sample, f string, sep rune) error {
+
+	d, err := buildEFMReferences(refs)
+	if err != nil {
+		return err
+	}
+
+	return write2CSV(d, f, sep)
+}
+
+// buildEFMReferences builds the rows of a reference table. See
+// ExportEFMReferences.
+func buildEFMReferences(refs []Sample) ([][]string, error) {
+
+	d := [][]string{{"Sample Name", "Marker", "Allele 1", "Allele 2"}}
+	for _, s := range refs {
+		for _, l := range s.Loci {
+			alleles := l.WithoutOffLadder().Alleles
+
+			switch len(alleles) {
+			case 0:
+				continue
+			case 1:
+				alleles = append(alleles, alleles[0])
+			case 2:
+			default:
+				return nil, fmt.Errorf("reference %v, locus %v: %v alleles",
+					s.ID, l.ID, len(alleles))
+			}
+
+			d = append(d, []string{s.ID, EFMLocusName(l.ID),
+				alleles[0].ID.String(), alleles[1].ID.String()})
+		}
+	}
+
+	return d, nil
+}
+
+// ExportEFMFreqs writes the frequencies fr (e.g. as built by
+// STRiderFreqs.BuildPop) to file f, separated by sep, as a table with a
+// column 'Allele' and one column per locus; alleles without a frequency at a
+// locus are left empty. Alleles of samples that are missing from fr are added
+// with frequency fr.Fmin, so that EuroForMix and LRmix Studio use the same
+// minimum frequency as forge rather than their own defaults. Amelogenin and
+// alleles without repeat number are not exported.
+func ExportEFMFreqs(fr Freqs, f string, sep rune, samples ...Sample) error {
+	return write2CSV(buildEFMFreqs(fr, samples), f, sep)
+}
+
+// buildEFMFreqs builds the rows of a frequency table. See ExportEFMFreqs.
+func buildEFMFreqs(fr Freqs, samples []Sample) [][]string {
+
+	freqs := make(map[string]map[AlleleID]float64)
+	var loci []string
+	add := func(lID string, id AlleleID, freq float64) {
+		lID = EFMLocusName(lID)
+		if lID == "AMEL" || !id.HasRepeats() {
+			return
+		}
+		if _, ok := freqs[lID]; !ok {
+			freqs[lID] = make(map[AlleleID]float64)
+			loci = append(loci, lID)
+		}
+		if _, ok := freqs[lID][id]; !ok {
+			freqs[lID][id] = freq
+		}
+	}
+
+	for _, fl := range fr.Floci {
+		for _, fa := range fl.Falleles {
+			add(fl.ID, fa.ID, fl.Fallele(fa.ID).Freq)
+		}
+	}
+	for _, s := range samples {
+		for _, l := range s.Loci {
+			for _, a := range l.Alleles {
+				add(l.ID, a.ID, fr.Fmin)
+			}
+		}
+	}
+
+	var alleles []AlleleID
+	seen := make(map[AlleleID]bool)
+	for _, l := range loci {
+		for id := range freqs[l] {
+			if !seen[id] {
+				seen[id] = true
+				alleles = append(alleles, id)
+			}
+		}
+	}
+	sort.Slice(alleles, func(i, j int) bool { return alleles[i].Less(alleles[j]) })
+
+	d := [][]string{append([]string{"Allele"}, loci...)}
+	for _, id := range alleles {
+		row := []string{id.String()}
+		for _, l := range loci {
+			if freq, ok := freqs[l][id]; ok {
+				row = append(row, strconv.FormatFloat(freq, 'f', -1, 64))
+			} else {
+				row = append(row, "")
+			}
+		}
+		d = append(d, row)
+	}
+
+	return d
+}
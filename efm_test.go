@@ -0,0 +1,108 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"reflect"
+	"testing"
+)
+
+// =============================================================================
+func Test_EFMLocusName(t *testing.T) {
+
+	tests := map[string]string{
+		"vWA":        "VWA",
+		"THO1":       "TH01",
+		"Amelogenin": "AMEL",
+		"Penta D":    "PENTA D",
+		"SE33":       "SE33",
+	}
+
+	for in, want := range tests {
+		if res := EFMLocusName(in); res != want {
+			t.Fatalf("%v: expected: %v, got: %v", in, want, res)
+		}
+	}
+}
+
+// =============================================================================
+func Test_buildEFMEvidence(t *testing.T) {
+
+	s := Sample{ID: "stain", Loci: []Locus{
+		{ID: "vWA", Alleles: []Allele{{ID: A2ID("14"), Height: 800},
+			{ID: A2ID("OL"), Height: 90, Size: 160}, {ID: A2ID("17"), Height: 750}}},
+		{ID: "TH01", Alleles: []Allele{{ID: A2ID("9.3"), Height: 1200}}},
+	}}
+
+	want := [][]string{
+		{"Sample Name", "Marker", "Allele 1", "Allele 2", "Height 1", "Height 2"},
+		{"stain", "VWA", "14", "17", "800", "750"},
+		{"stain", "TH01", "9.3", "", "1200", ""},
+	}
+	if res := buildEFMEvidence([]Sample{s}, true); !reflect.DeepEqual(res, want) {
+		t.Fatalf("test 1 (EuroForMix): expected: %v, got: %v", want, res)
+	}
+
+	want = [][]string{
+		{"Sample Name", "Marker", "Allele 1", "Allele 2"},
+		{"stain", "VWA", "14", "17"},
+		{"stain", "TH01", "9.3", ""},
+	}
+	if res := buildEFMEvidence([]Sample{s}, false); !reflect.DeepEqual(res, want) {
+		t.Fatalf("test 2 (LRmix Studio): expected: %v, got: %v", want, res)
+	}
+}
+
+// =============================================================================
+func Test_buildEFMReferences(t *testing.T) {
+
+	refs := []Sample{{ID: "suspect", Loci: []Locus{
+		{ID: "VWA", Alleles: []Allele{{ID: A2ID("14")}, {ID: A2ID("17")}}},
+		{ID: "THO1", Alleles: []Allele{{ID: A2ID("6")}}},
+		{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}}},
+	}}}
+
+	want := [][]string{
+		{"Sample Name", "Marker", "Allele 1", "Allele 2"},
+		{"suspect", "VWA", "14", "17"},
+		{"suspect", "TH01", "6", "6"},
+		{"suspect", "AMEL", "X", "X"},
+	}
+
+	res, err := buildEFMReferences(refs)
+	if err != nil || !reflect.DeepEqual(res, want) {
+		t.Fatalf("expected: %v, got: %v (%v)", want, res, err)
+	}
+
+	refs[0].Loci[0].Alleles = append(refs[0].Loci[0].Alleles, Allele{ID: A2ID("18")})
+	if _, err := buildEFMReferences(refs); err == nil {
+		t.Fatalf("expected error for tri-allelic reference")
+	}
+}
+
+// =============================================================================
+func Test_buildEFMFreqs(t *testing.T) {
+
+	fr := Freqs{Fmin: 0.001, Floci: []Flocus{
+		{ID: "vWA", Falleles: []Fallele{{ID: A2ID("14"), Freq: 0.1}, {ID: A2ID("17"), Freq: 0.25}}},
+		{ID: "TH01", Falleles: []Fallele{{ID: A2ID("6"), Freq: 0.2}, {ID: A2ID("9.3"), Freq: 0.35}}},
+	}}
+	s := Sample{ID: "stain", Loci: []Locus{
+		{ID: "VWA", Alleles: []Allele{{ID: A2ID("14")}, {ID: A2ID("21")}}},
+		{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}}},
+	}}
+
+	want := [][]string{
+		{"Allele", "VWA", "TH01"},
+		{"6", "", "0.2"},
+		{"9.3", "", "0.35"},
+		{"14", "0.1", ""},
+		{"17", "0.25", ""},
+		{"21", "0.001", ""},
+	}
+
+	if res := buildEFMFreqs(fr, []Sample{s}); !reflect.DeepEqual(res, want) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+}
@@ -13,7 +13,9 @@ import (
 // ExportCSV writes a sample s as CSV to a file of name f, separated by sep.
 // If collate is true, the alleles of a marker will be sorted as Allele 1,
 // Height 1, ..., Allele 2, Height 2, ... etc. If collate is false, they will be
-// sorted as Allele 1, Allele 2, ..., Height 1, Height 2, ... etc.
+// sorted as Allele 1, Allele 2, ..., Height 1, Height 2, ... etc. Allele IDs
+// are written as designations (e.g. "9.3", "OL", "<8"). The dye and the PQVs
+// of GeneMapper ID-X are written if the sample holds them.
 func (s Sample) ExportCSV(f string, sep rune, collate bool) error {
 
 	d, err := buildCSV(s, collate)
@@ -42,6 +44,7 @@ func buildCSV(s Sample, collated bool) ([][]string, error) {
 
 	csvData := [][]string{header}
 
+	pqvs := s.pqvNames()
 	for _, l := range s.Loci {
 		var locus []string
 		if collated {
@@ -49,7 +52,19 @@ func buildCSV(s Sample, collated bool) ([][]string, error) {
 		} else {
 			locus = uncollatedRow(l, s.MaxAlleles(), s.alleleFields())
 		}
-		row := append([]string{s.ID, l.ID}, locus...)
+
+		row := []string{s.ID, l.ID}
+		if s.hasDye() {
+			row = append(row, l.Dye)
+		}
+		row = append(row, locus...)
+		for _, n := range pqvs {
+			q := l.PQVs[n]
+			if q == NOQUALITY {
+				q = s.PQVs[n]
+			}
+			row = append(row, q.String())
+		}
 
 		csvData = append(csvData, row)
 	}
@@ -58,9 +73,9 @@ func buildCSV(s Sample, collated bool) ([][]string, error) {
 }
 
 // collatedHeader returns a collated header, i.e. the first line, of the CSV
-//file (i.e. in the form of A1, S1, H1, A2, S2, H2).
+// file (i.e. in the form of A1, S1, H1, A2, S2, H2).
 func collatedHeader(s Sample) []string {
-	a := []string{"Sample Name", "Marker"}
+	a := leadingHeader(s)
 
 	for i := 0; i < s.MaxAlleles(); i++ {
 		for _, f := range s.alleleFields() {
@@ -68,13 +83,13 @@ func collatedHeader(s Sample) []string {
 			a = append(a, f+" "+strconv.Itoa(i+1))
 		}
 	}
-	return a
+	return append(a, s.pqvNames()...)
 }
 
 // uncollatedHeader returns a uncollated header, i.e. the first line, of the CSV
 // file (i.e. in the form of A1, A2, A3, H1, H2, H3).
 func uncollatedHeader(s Sample) []string {
-	a := []string{"Sample Name", "Marker"}
+	a := leadingHeader(s)
 
 	for _, f := range s.alleleFields() {
 		for i := 0; i < s.MaxAlleles(); i++ {
@@ -83,7 +98,43 @@ func uncollatedHeader(s Sample) []string {
 		}
 	}
 
-	return a
+	return append(a, s.pqvNames()...)
+}
+
+// leadingHeader returns the columns of the header preceding the allele
+// fields.
+func leadingHeader(s Sample) []string {
+	if s.hasDye() {
+		return []string{"Sample Name", "Marker", "Dye"}
+	}
+	return []string{"Sample Name", "Marker"}
+}
+
+// hasDye returns true if any locus of sample s holds its dye.
+func (s Sample) hasDye() bool {
+	for _, l := range s.Loci {
+		if l.Dye != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// pqvNames returns the names of all sample and locus PQVs of sample s, sample
+// PQVs first.
+func (s Sample) pqvNames() []string {
+	names := s.PQVs.names()
+
+	locus := make(PQVs)
+	for _, l := range s.Loci {
+		for n := range l.PQVs {
+			if _, ok := s.PQVs[n]; !ok {
+				locus[n] = NOQUALITY
+			}
+		}
+	}
+
+	return append(names, locus.names()...)
 }
 
 // (e.g. A1, S1, H1, A2, S2, H2)
@@ -94,13 +145,15 @@ func collatedRow(l Locus, max int, fields alleleFields) []string {
 		for _, f := range fields {
 			switch f {
 			case "Allele":
-				row = append(row, A2String(a.ID))
+				row = append(row, a.ID.String())
 			case "Height":
 				row = append(row, A2String(a.Height))
 			case "Area":
 				row = append(row, A2String(a.Area))
 			case "Size":
 				row = append(row, A2String(a.Size))
+			case "Comment":
+				row = append(row, a.Comment)
 			}
 
 		}
@@ -127,13 +180,15 @@ func uncollatedRow(l Locus, max int, fields alleleFields) []string {
 		for _, a := range l.Alleles {
 			switch f {
 			case "Allele":
-				row = append(row, A2String(a.ID))
+				row = append(row, a.ID.String())
 			case "Height":
 				row = append(row, A2String(a.Height))
 			case "Size":
 				row = append(row, A2String(a.Size))
 			case "Area":
 				row = append(row, A2String(a.Area))
+			case "Comment":
+				row = append(row, a.Comment)
 			}
 		}
 
@@ -150,12 +205,14 @@ func uncollatedRow(l Locus, max int, fields alleleFields) []string {
 type alleleFields []string
 
 // alleleFields returns the allele field names present in a Sample object s.
+// The numeric fields are taken from the first allele; comments are written if
+// any allele of s has one.
 func (s Sample) alleleFields() alleleFields {
 
 	var f alleleFields
 	for _, l := range s.Loci {
 		for _, a := range l.Alleles {
-			if a.ID != 0 { // can be -2 or -1 in AMEL
+			if !a.ID.IsZero() {
 				f = append(f, "Allele")
 			}
 			if a.Height > 0 {
@@ -167,6 +224,9 @@ func (s Sample) alleleFields() alleleFields {
 			if a.Size > 0 {
 				f = append(f, "Size")
 			}
+			if s.hasComments() {
+				f = append(f, "Comment")
+			}
 
 			return f
 		}
@@ -175,6 +235,18 @@ func (s Sample) alleleFields() alleleFields {
 	return nil
 }
 
+// hasComments returns true if any allele of sample s has a comment.
+func (s Sample) hasComments() bool {
+	for _, l := range s.Loci {
+		for _, a := range l.Alleles {
+			if a.Comment != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // write2CSV writes the 2d data d to a file with name f.
 func write2CSV(d [][]string, f string, sep rune) error {
 
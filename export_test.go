@@ -23,12 +23,12 @@ func Test_header(t *testing.T) {
 				ID: "TestID",
 				Loci: []Locus{
 					{ID: "VWA", Alleles: []Allele{
-						{ID: 17, Height: 2039, Size: 111.2},
-						{ID: 21, Height: 1993, Size: 122.1}}},
+						{ID: A2ID("17"), Height: 2039, Size: 111.2},
+						{ID: A2ID("21"), Height: 1993, Size: 122.1}}},
 					{ID: "FGA", Alleles: []Allele{
-						{ID: 20.1, Height: 322, Size: 343},
-						{ID: 31, Height: 8923, Size: 422.3},
-						{ID: -999, Height: 3432, Size: 723}}},
+						{ID: A2ID("20.1"), Height: 322, Size: 343},
+						{ID: A2ID("31"), Height: 8923, Size: 422.3},
+						{ID: A2ID("OL"), Height: 3432, Size: 723}}},
 				},
 			},
 			[]string{"Sample Name", "Marker",
@@ -44,8 +44,8 @@ func Test_header(t *testing.T) {
 			Sample{
 				ID: "TestID",
 				Loci: []Locus{
-					{ID: "VWA", Alleles: []Allele{{ID: 17}, {ID: 21}}},
-					{ID: "FGA", Alleles: []Allele{{ID: 20.1}, {ID: 31}}}},
+					{ID: "VWA", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21")}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("20.1")}, {ID: A2ID("31")}}}},
 			},
 			[]string{"Sample Name", "Marker", "Allele 1", "Allele 2"},
 			[]string{"Sample Name", "Marker", "Allele 1", "Allele 2"},
@@ -82,11 +82,11 @@ func Test_alleleFields(t *testing.T) {
 				Loci: []Locus{
 					{ID: "SE33", Alleles: []Allele{}},
 					{ID: "VWA", Alleles: []Allele{
-						{ID: 17, Height: 2039, Size: 111.2},
-						{ID: 21, Height: 1993, Size: 122.1}}},
+						{ID: A2ID("17"), Height: 2039, Size: 111.2},
+						{ID: A2ID("21"), Height: 1993, Size: 122.1}}},
 					{ID: "FGA", Alleles: []Allele{
-						{ID: 20.1, Height: 322, Size: 343},
-						{ID: 31, Height: 8923, Size: 422.3}}},
+						{ID: A2ID("20.1"), Height: 322, Size: 343},
+						{ID: A2ID("31"), Height: 8923, Size: 422.3}}},
 				},
 			},
 			alleleFields{"Allele", "Height", "Size"},
@@ -95,8 +95,8 @@ func Test_alleleFields(t *testing.T) {
 			Sample{
 				ID: "TestID",
 				Loci: []Locus{
-					{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-					{ID: "FGA", Alleles: []Allele{{ID: 20.1}, {ID: 31}}}},
+					{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+					{ID: "FGA", Alleles: []Allele{{ID: A2ID("20.1")}, {ID: A2ID("31")}}}},
 			},
 			alleleFields{"Allele"},
 		},
@@ -105,9 +105,9 @@ func Test_alleleFields(t *testing.T) {
 				ID: "TestID",
 				Loci: []Locus{
 					{ID: "VWA", Alleles: []Allele{
-						{ID: 17, Height: 232, Area: 3432.7, Size: 567.1}}},
+						{ID: A2ID("17"), Height: 232, Area: 3432.7, Size: 567.1}}},
 					{ID: "FGA", Alleles: []Allele{
-						{ID: 9.3, Height: 675, Area: 432.37, Size: 2324}}},
+						{ID: A2ID("9.3"), Height: 675, Area: 432.37, Size: 2324}}},
 				},
 			},
 			alleleFields{"Allele", "Height", "Area", "Size"},
@@ -137,12 +137,12 @@ func Test_collatedCSV_uncollatedCSV(t *testing.T) {
 				ID: "TestID",
 				Loci: []Locus{
 					{ID: "VWA", Alleles: []Allele{
-						{ID: 17, Height: 2039, Size: 111.2},
-						{ID: 27.1, Height: 122, Size: 326}}},
+						{ID: A2ID("17"), Height: 2039, Size: 111.2},
+						{ID: A2ID("27.1"), Height: 122, Size: 326}}},
 					{ID: "FGA", Alleles: []Allele{
-						{ID: 20.1, Height: 322, Size: 343},
-						{ID: 21, Height: 1993, Size: 382.1},
-						{ID: -999, Height: 8923, Size: 433.3}}},
+						{ID: A2ID("20.1"), Height: 322, Size: 343},
+						{ID: A2ID("21"), Height: 1993, Size: 382.1},
+						{ID: A2ID("OL"), Height: 8923, Size: 433.3}}},
 				},
 			},
 			[][]string{
@@ -159,7 +159,7 @@ func Test_collatedCSV_uncollatedCSV(t *testing.T) {
 				{"TestID", "FGA",
 					"20.1", "322", "343",
 					"21", "1993", "382.1",
-					"NaN", "8923", "433.3",
+					"OL", "8923", "433.3",
 				},
 			},
 			[][]string{
@@ -174,7 +174,7 @@ func Test_collatedCSV_uncollatedCSV(t *testing.T) {
 					"111.2", "326", "",
 				},
 				{"TestID", "FGA",
-					"20.1", "21", "NaN",
+					"20.1", "21", "OL",
 					"322", "1993", "8923",
 					"343", "382.1", "433.3",
 				},
@@ -202,3 +202,42 @@ func Test_collatedCSV_uncollatedCSV(t *testing.T) {
 		}
 	}
 }
+
+// =============================================================================
+func Test_buildCSV_IDX(t *testing.T) {
+
+	in := Sample{
+		ID:   "TestID",
+		PQVs: PQVs{"SQ": PASS},
+		Loci: []Locus{
+			{ID: "VWA", Dye: "B", PQVs: PQVs{"AN": CHECK}, Alleles: []Allele{
+				{ID: A2ID("17"), Height: 2039},
+				{ID: A2ID("OL"), Height: 122, Comment: "pull-up"}}},
+			{ID: "TH01", Dye: "G", Alleles: []Allele{
+				{ID: A2ID("6"), Height: 322}}},
+		},
+	}
+
+	want := [][]string{
+		{"Sample Name", "Marker", "Dye",
+			"Allele 1", "Height 1", "Comment 1",
+			"Allele 2", "Height 2", "Comment 2",
+			"SQ", "AN"},
+		{"TestID", "VWA", "B",
+			"17", "2039", "",
+			"OL", "122", "pull-up",
+			"Pass", "Check"},
+		{"TestID", "TH01", "G",
+			"6", "322", "",
+			"", "", "",
+			"Pass", ""},
+	}
+
+	res, err := buildCSV(in, true)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(want, res) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// FamilialConf holds the parameters of a familial search.
+type FamilialConf struct {
+	Kinship KinshipConf
+	TopN    int // number of candidates returned; 0 returns all
+	// YFilter drops candidates whose Y-STR haplotype differs from that of the
+	// unknown person at more than MaxYMismatches shared Y loci.
+	YFilter        bool
+	MaxYMismatches int
+}
+
+// FamilialCandidate holds the kinship indices of a reference and an unknown
+// person.
+type FamilialCandidate struct {
+	Reference    string
+	PO           LRResult // parent/child index
+	FS           LRResult // full sibling index
+	KI           float64  // the larger kinship index
+	Relationship string   // relationship of the larger kinship index
+	YLoci        int      // number of shared Y loci
+	YMismatches  int      // number of shared Y loci with different alleles
+}
+
+// FamilialSearch ranks the references refs as relatives of the unknown
+// person up, e.g. of a stain without a direct hit, by the larger of their
+// parent/child and full sibling indices (see MotherlessPaternityIndex and
+// FullSiblingIndex). With c.YFilter, references that share Y loci with up are
+// dropped if their haplotypes differ at more than c.MaxYMismatches loci. The
+// c.TopN candidates with the largest kinship indices are returned.
+func FamilialSearch(up Sample, refs []Sample, f Freqs, c FamilialConf) ([]FamilialCandidate, error) {
+
+	var r []FamilialCandidate
+	for _, ref := range refs {
+		if ref.ID == up.ID {
+			return nil, fmt.Errorf("reference %v has the ID of the unknown person", ref.ID)
+		}
+
+		fc := FamilialCandidate{Reference: ref.ID}
+		fc.YLoci, fc.YMismatches = compareYLoci(up, ref)
+		if c.YFilter && fc.YMismatches > c.MaxYMismatches {
+			continue
+		}
+
+		var err error
+		if fc.PO, err = MotherlessPaternityIndex(up, ref, f, c.Kinship); err != nil {
+			return nil, fmt.Errorf("reference %v: %w", ref.ID, err)
+		}
+		if fc.FS, err = FullSiblingIndex(up, ref, f, c.Kinship); err != nil {
+			return nil, fmt.Errorf("reference %v: %w", ref.ID, err)
+		}
+
+		fc.KI, fc.Relationship = fc.PO.LR, "parent/child"
+		if fc.FS.LR > fc.PO.LR {
+			fc.KI, fc.Relationship = fc.FS.LR, "full siblings"
+		}
+		r = append(r, fc)
+	}
+
+	sort.SliceStable(r, func(i, j int) bool { return r[i].KI > r[j].KI })
+	if c.TopN > 0 && len(r) > c.TopN {
+		r = r[:c.TopN]
+	}
+
+	return r, nil
+}
+
+// compareYLoci returns the number of Y loci typed in both samples and the
+// number of these loci with different alleles.
+func compareYLoci(s1, s2 Sample) (int, int) {
+	var shared, mismatches int
+	for _, l := range s1.Loci {
+		if l.Linkage() != YLINKED || !s2.HasLocus(l.ID) {
+			continue
+		}
+		a1, a2 := typedAlleles(l), typedAlleles(s2.Locus(l.ID))
+		if len(a1) == 0 || len(a2) == 0 {
+			continue
+		}
+		shared++
+		if !slices.Equal(a1, a2) {
+			mismatches++
+		}
+	}
+	return shared, mismatches
+}
+
+// typedAlleles returns the sorted typed alleles of locus l without
+// duplicates.
+func typedAlleles(l Locus) []AlleleID {
+	var ids []AlleleID
+	for _, a := range l.Alleles {
+		if a.ID.IsTyped() && !slices.Contains(ids, a.ID) {
+			ids = append(ids, a.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Less(ids[j]) })
+	return ids
+}
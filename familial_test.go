@@ -0,0 +1,49 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"testing"
+)
+
+// =============================================================================
+func Test_FamilialSearch(t *testing.T) {
+
+	up := peaks("UP", "VWA:14", "VWA:17", "TH01:6", "TH01:9", "D8S1179:12", "D8S1179:13",
+		"DYS576:17", "DYS389I:13")
+	refs := []Sample{
+		peaks("unrelated", "VWA:15", "VWA:16", "TH01:7", "TH01:8", "D8S1179:10", "D8S1179:14"),
+		peaks("father", "VWA:14", "VWA:15", "TH01:6", "TH01:7", "D8S1179:10", "D8S1179:12",
+			"DYS576:18", "DYS389I:13"),
+		peaks("brother", "VWA:14", "VWA:17", "TH01:6", "TH01:9", "D8S1179:12", "D8S1179:13",
+			"DYS576:17", "DYS389I:13"),
+	}
+
+	c := FamilialConf{Kinship: KinshipConf{Model: NOMUTATION}, TopN: 2}
+	r, err := FamilialSearch(up, refs, contFreqs, c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(r) != 2 || r[0].Reference != "brother" || r[0].Relationship != "full siblings" ||
+		r[1].Reference != "father" || r[1].Relationship != "parent/child" {
+		t.Fatalf("expected: brother (full siblings), father (parent/child), got: %+v", r)
+	}
+	if r[1].YLoci != 2 || r[1].YMismatches != 1 || len(r[1].PO.Loci) != 5 {
+		t.Fatalf("expected 2 Y loci with 1 mismatch and 5 loci, got: %+v", r[1])
+	}
+
+	// the Y haplotype of the father differs at DYS576
+	c.YFilter = true
+	r, _ = FamilialSearch(up, refs, contFreqs, c)
+	if len(r) != 2 || r[1].Reference != "unrelated" {
+		t.Fatalf("expected: brother, unrelated, got: %+v", r)
+	}
+
+	c.MaxYMismatches = 1
+	r, _ = FamilialSearch(up, refs, contFreqs, c)
+	if len(r) != 2 || r[1].Reference != "father" {
+		t.Fatalf("expected: brother, father, got: %+v", r)
+	}
+}
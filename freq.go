@@ -3,6 +3,14 @@
 
 package forge
 
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
 // Freqs holds the frequency information for a specific population
 // (e.g. Europe).
 type Freqs struct {
@@ -12,6 +20,8 @@ type Freqs struct {
 	Pop string
 	// Minimum allele frequency to use.
 	Fmin float64
+	// MinRule defines the frequency of rare and unobserved alleles (see Freq).
+	MinRule MinFreqRule
 	// Slice of STR markers (e.g. VWA) with the respective frequency data.
 	Floci []Flocus
 }
@@ -20,12 +30,18 @@ type Freqs struct {
 type Flocus struct {
 	ID       string    // e.g. VWA
 	Falleles []Fallele // Slice of alleles with frequency information
+	N        int       // number of individuals typed at the locus; 0 if unknown
 }
 
-// Fallele holds the frequency information for an allele.
+// Fallele holds the frequency information for an allele. For sequence-level
+// frequencies (MPS data), a Flocus holds one Fallele per isoallele, each with
+// its sequence Seq.
 type Fallele struct {
-	ID   float64 // e.g. 9.3
-	Freq float64 // frequency
+	ID   AlleleID // e.g. 9.3
+	Freq float64  // frequency
+	Seq  Sequence // sequence of the isoallele; empty for length-level data
+	// Count is the number of observations of the allele; 0 if unknown.
+	Count int
 }
 
 // Flocus returns a Flocus object with locus name id from f.
@@ -45,12 +61,37 @@ func (f Freqs) HasFlocus(id string) bool {
 	return len(f.Flocus(id).Falleles) > 0
 }
 
-// Fallele returns a Fallele object with allele name id from l.
-func (l Flocus) Fallele(id float64) Fallele {
+// Fallele returns a Fallele object with allele name id from l. If l holds
+// only sequence-level frequencies for id, the frequencies of all isoalleles of
+// id are summed up.
+func (l Flocus) Fallele(id AlleleID) Fallele {
+	var iso Fallele
 	for _, a := range l.Falleles {
-		if a.ID == id {
+		if a.ID != id {
+			continue
+		}
+		if a.Seq.IsZero() {
 			return a
 		}
+		iso.ID = id
+		iso.Freq += a.Freq
+	}
+
+	return iso
+}
+
+// FalleleMode returns the Fallele of allele a from l under match mode m. In
+// LENGTH mode it is the same as Fallele(a.ID); in SEQUENCE mode only the
+// frequency of the isoallele with the sequence of a is returned.
+func (l Flocus) FalleleMode(a Allele, m MatchMode) Fallele {
+	if m == LENGTH {
+		return l.Fallele(a.ID)
+	}
+
+	for _, fa := range l.Falleles {
+		if fa.ID == a.ID && fa.Seq.key() == a.Seq.key() {
+			return fa
+		}
 	}
 
 	return Fallele{}
@@ -58,6 +99,249 @@ func (l Flocus) Fallele(id float64) Fallele {
 
 // HasFallele tests whether the locus frequency data l contains an
 // allele with name id.
-func (l Flocus) HasFallele(id float64) bool {
-	return l.Fallele(id).ID != 0
+func (l Flocus) HasFallele(id AlleleID) bool {
+	return !l.Fallele(id).ID.IsZero()
+}
+
+// MinFreqRule defines the frequency of rare and unobserved alleles.
+type MinFreqRule int
+
+const (
+	// FMINFIXED uses Freqs.Fmin for unobserved alleles.
+	FMINFIXED MinFreqRule = iota
+	// FMIN5N raises the frequency of every allele to at least 5/2N (NRC II),
+	// with N the number of individuals typed at the locus.
+	FMIN5N
+	// FMIN1N uses 1/2N for unobserved alleles, i.e. as if observed once.
+	FMIN1N
+)
+
+// String returns the minimum frequency rule as string.
+func (r MinFreqRule) String() string {
+	switch r {
+	case FMIN5N:
+		return "5/2N"
+	case FMIN1N:
+		return "1/2N"
+	default: // FMINFIXED
+		return "Fmin"
+	}
+}
+
+// Freq returns the frequency of allele id at locus lID under the minimum
+// frequency rule f.MinRule. If the sample size of the locus is unknown, the
+// rule falls back to FMINFIXED.
+func (f Freqs) Freq(lID string, id AlleleID) float64 {
+
+	fl := f.Flocus(lID)
+	fa := fl.Fallele(id)
+	observed := !fa.ID.IsZero()
+
+	switch {
+	case f.MinRule == FMIN5N && fl.N > 0:
+		return math.Max(fa.Freq, 5/float64(2*fl.N))
+	case f.MinRule == FMIN1N && fl.N > 0 && !observed:
+		return 1 / float64(2*fl.N)
+	case !observed:
+		return f.Fmin
+	default:
+		return fa.Freq
+	}
+}
+
+// BuildFreqs builds a Freqs object for population pop from the reference
+// profiles refs (e.g. as read by ReadGMRefs). An individual is counted at a
+// locus if it has at least one typed allele there; a single allele is counted
+// as homozygous. Isoalleles of MPS references are counted separately. The
+// minimum frequency fmin and rule r are stored in the returned Freqs. It
+// returns an error if a reference has more than two alleles at a locus.
+func BuildFreqs(refs []Sample, pop string, fmin float64, r MinFreqRule) (Freqs, error) {
+
+	var ids []string
+	counts := make(map[string]map[alleleKey]int)
+	seqs := make(map[alleleKey]Sequence) // first full sequence of an isoallele
+	n := make(map[string]int)
+	var sources []string
+	for _, s := range refs {
+
+		if !slices.Contains(sources, s.Source) {
+			sources = append(sources, s.Source)
+		}
+
+		for _, l := range s.Loci {
+
+			var alleles []Allele
+			for _, a := range l.Alleles {
+				if a.ID.IsTyped() {
+					alleles = append(alleles, a)
+				}
+			}
+
+			switch len(alleles) {
+			case 0:
+				continue
+			case 1:
+				alleles = append(alleles, alleles[0])
+			case 2:
+			default:
+				return Freqs{}, fmt.Errorf("reference %v, locus %v: %v alleles",
+					s.ID, l.ID, len(alleles))
+			}
+
+			if _, ok := counts[l.ID]; !ok {
+				counts[l.ID] = make(map[alleleKey]int)
+				ids = append(ids, l.ID)
+			}
+			for _, a := range alleles {
+				counts[l.ID][a.key(SEQUENCE)]++
+				if _, ok := seqs[a.key(SEQUENCE)]; !ok {
+					seqs[a.key(SEQUENCE)] = a.Seq
+				}
+			}
+			n[l.ID]++
+		}
+	}
+
+	if len(ids) == 0 {
+		return Freqs{}, fmt.Errorf("no typed references for population %v", pop)
+	}
+
+	var fLoci []Flocus
+	for _, id := range ids {
+		fl := Flocus{ID: id, N: n[id]}
+		for k, c := range counts[id] {
+			fl.Falleles = append(fl.Falleles, Fallele{
+				ID:    k.ID,
+				Freq:  float64(c) / float64(2*n[id]),
+				Seq:   seqs[k],
+				Count: c,
+			})
+		}
+		fl.sortFalleles()
+		fLoci = append(fLoci, fl)
+	}
+
+	return Freqs{
+		Source:  strings.Join(sources, ", "),
+		Pop:     pop,
+		Fmin:    fmin,
+		MinRule: r,
+		Floci:   fLoci,
+	}, nil
+}
+
+// sortFalleles sorts the alleles of l by ID and isoalleles by sequence.
+func (l *Flocus) sortFalleles() {
+	sort.SliceStable(l.Falleles, func(i, j int) bool {
+		a, b := l.Falleles[i], l.Falleles[j]
+		if a.ID != b.ID {
+			return a.ID.Less(b.ID)
+		}
+		return a.Seq.Repeat+a.Seq.Bracket < b.Seq.Repeat+b.Seq.Bracket
+	})
+}
+
+// count returns the number of observations of allele a, derived from its
+// frequency if the count is unknown.
+func (l Flocus) count(a Fallele) float64 {
+	if a.Count > 0 {
+		return float64(a.Count)
+	}
+	return a.Freq * float64(2*l.N)
+}
+
+// SizeBiased returns a copy of f with the Balding size-bias correction for
+// the queried profile p, i.e. p is added twice (as suspect and as offender)
+// to the database at each of its loci: an allele observed x times in N
+// individuals gets the frequency (x+2)/(2N+4) if p is heterozygous and
+// (x+4)/(2N+4) if p is homozygous. Alleles of p are matched by sequence, too.
+// An allele of a CE profile without a length-level entry in f is spread over
+// the isoalleles of its length in proportion to their counts. Loci without a
+// sample size are not corrected.
+func (f Freqs) SizeBiased(p Sample) Freqs {
+
+	r := f
+	r.Floci = make([]Flocus, len(f.Floci))
+	for i, fl := range f.Floci {
+
+		r.Floci[i] = Flocus{ID: fl.ID, N: fl.N}
+		r.Floci[i].Falleles = append([]Fallele(nil), fl.Falleles...)
+
+		if fl.N == 0 || !p.HasLocus(fl.ID) {
+			continue
+		}
+
+		var typed []Allele
+		for _, a := range p.Locus(fl.ID).Alleles {
+			if a.ID.IsTyped() {
+				typed = append(typed, a)
+			}
+		}
+		if len(typed) == 0 || len(typed) > 2 {
+			continue
+		}
+		if len(typed) == 1 { // homozygous
+			typed = append(typed, typed[0])
+		}
+
+		added := make(map[alleleKey]int)
+		var keys []alleleKey
+		for _, a := range typed {
+			if added[a.key(SEQUENCE)] == 0 {
+				keys = append(keys, a.key(SEQUENCE))
+			}
+			added[a.key(SEQUENCE)] += 2
+		}
+
+		// a CE allele without a length-level entry is spread over its
+		// isoalleles by their counts
+		extra := make([]float64, len(fl.Falleles))
+		var missing []alleleKey
+		for _, k := range keys {
+			if j := slices.IndexFunc(fl.Falleles, func(fa Fallele) bool {
+				return alleleKey{ID: fa.ID, Seq: fa.Seq.key()} == k
+			}); j >= 0 {
+				extra[j] += float64(added[k])
+				continue
+			}
+			var total float64
+			if k.Seq == (Sequence{}) {
+				for _, fa := range fl.Falleles {
+					if fa.ID == k.ID {
+						total += fl.count(fa)
+					}
+				}
+			}
+			if total == 0 { // not in the database
+				missing = append(missing, k)
+				continue
+			}
+			for j, fa := range fl.Falleles {
+				if fa.ID == k.ID {
+					extra[j] += float64(added[k]) * fl.count(fa) / total
+				}
+			}
+		}
+
+		rl := &r.Floci[i]
+		rl.N = fl.N + 2
+		for j, fa := range fl.Falleles {
+			c := fl.count(fa) + extra[j]
+			rl.Falleles[j].Freq = c / float64(2*rl.N)
+			if fa.Count > 0 {
+				rl.Falleles[j].Count = int(math.Round(c))
+			}
+		}
+		for _, k := range missing {
+			rl.Falleles = append(rl.Falleles, Fallele{
+				ID:    k.ID,
+				Freq:  float64(added[k]) / float64(2*rl.N),
+				Seq:   k.Seq,
+				Count: added[k],
+			})
+		}
+		rl.sortFalleles()
+	}
+
+	return r
 }
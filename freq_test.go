@@ -4,6 +4,7 @@
 package forge
 
 import (
+	"math"
 	"reflect"
 	"testing"
 )
@@ -21,13 +22,13 @@ func TestFreqs_Flocus(t *testing.T) {
 		{
 			Freqs{
 				Floci: []Flocus{
-					{ID: "SE33", Falleles: []Fallele{{ID: 9.3}}},
-					{ID: "VWA", Falleles: []Fallele{{ID: 17}}},
-					{ID: "FGA", Falleles: []Fallele{{ID: 22.3}}},
+					{ID: "SE33", Falleles: []Fallele{{ID: A2ID("9.3")}}},
+					{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17")}}},
+					{ID: "FGA", Falleles: []Fallele{{ID: A2ID("22.3")}}},
 				},
 			},
 			"SE33",
-			Flocus{ID: "SE33", Falleles: []Fallele{{ID: 9.3}}},
+			Flocus{ID: "SE33", Falleles: []Fallele{{ID: A2ID("9.3")}}},
 		},
 	}
 
@@ -53,9 +54,9 @@ func TestFreqs_HasFlocus(t *testing.T) {
 			Freqs{
 				Floci: []Flocus{
 					// HasFlocus returns false when no alleles present
-					{ID: "SE33", Falleles: []Fallele{{ID: 9.3}}},
-					{ID: "VWA", Falleles: []Fallele{{ID: 17}}},
-					{ID: "FGA", Falleles: []Fallele{{ID: 22.3}}},
+					{ID: "SE33", Falleles: []Fallele{{ID: A2ID("9.3")}}},
+					{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17")}}},
+					{ID: "FGA", Falleles: []Fallele{{ID: A2ID("22.3")}}},
 				},
 			},
 			"SE33",
@@ -65,9 +66,9 @@ func TestFreqs_HasFlocus(t *testing.T) {
 			Freqs{
 				Floci: []Flocus{
 					// HasFlocus returns false when no alleles present
-					{ID: "SE33", Falleles: []Fallele{{ID: 9.3}}},
-					{ID: "VWA", Falleles: []Fallele{{ID: 17}}},
-					{ID: "FGA", Falleles: []Fallele{{ID: 22.3}}},
+					{ID: "SE33", Falleles: []Fallele{{ID: A2ID("9.3")}}},
+					{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17")}}},
+					{ID: "FGA", Falleles: []Fallele{{ID: A2ID("22.3")}}},
 				},
 			},
 			"AMEL",
@@ -88,19 +89,19 @@ func TestFlocus_Fallele(t *testing.T) {
 
 	type test struct {
 		inFlocus Flocus
-		inID     float64
+		inID     AlleleID
 		want     Fallele
 	}
 
 	tests := []test{
 		{
-			Flocus{ID: "SE33", Falleles: []Fallele{{ID: 9}, {ID: 9.3}}},
-			9.3,
-			Fallele{ID: 9.3},
+			Flocus{ID: "SE33", Falleles: []Fallele{{ID: A2ID("9")}, {ID: A2ID("9.3")}}},
+			A2ID("9.3"),
+			Fallele{ID: A2ID("9.3")},
 		},
 		{
-			Flocus{ID: "SE33", Falleles: []Fallele{{ID: 9}, {ID: 9.3}}},
-			6,
+			Flocus{ID: "SE33", Falleles: []Fallele{{ID: A2ID("9")}, {ID: A2ID("9.3")}}},
+			A2ID("6"),
 			Fallele{},
 		}}
 
@@ -117,19 +118,19 @@ func TestFlocus_HasFallele(t *testing.T) {
 
 	type test struct {
 		inFlocus Flocus
-		inID     float64
+		inID     AlleleID
 		want     bool
 	}
 
 	tests := []test{
 		{
-			Flocus{ID: "SE33", Falleles: []Fallele{{ID: 9}, {ID: 9.3}}},
-			9.3,
+			Flocus{ID: "SE33", Falleles: []Fallele{{ID: A2ID("9")}, {ID: A2ID("9.3")}}},
+			A2ID("9.3"),
 			true,
 		},
 		{
-			Flocus{ID: "SE33", Falleles: []Fallele{{ID: 9}, {ID: 9.3}}},
-			6,
+			Flocus{ID: "SE33", Falleles: []Fallele{{ID: A2ID("9")}, {ID: A2ID("9.3")}}},
+			A2ID("6"),
 			false,
 		}}
 
@@ -140,3 +141,163 @@ func TestFlocus_HasFallele(t *testing.T) {
 		}
 	}
 }
+
+// =============================================================================
+func TestFlocus_FalleleMode(t *testing.T) {
+
+	fl := Flocus{ID: "D12S391", Falleles: []Fallele{
+		{ID: A2ID("18"), Freq: 0.15},
+		{ID: A2ID("19"), Freq: 0.08, Seq: d12iso1.Seq},
+		{ID: A2ID("19"), Freq: 0.03, Seq: d12iso2.Seq},
+	}}
+
+	type test struct {
+		inAllele Allele
+		inMode   MatchMode
+		want     float64
+	}
+
+	tests := []test{
+		{d12iso1, LENGTH, 0.11},
+		{d12iso1, SEQUENCE, 0.08},
+		{d12iso2, SEQUENCE, 0.03},
+		{Allele{ID: A2ID("18")}, SEQUENCE, 0.15},
+		{Allele{ID: A2ID("20")}, LENGTH, 0},
+	}
+
+	for i, tc := range tests {
+		res := fl.FalleleMode(tc.inAllele, tc.inMode).Freq
+		if math.Abs(tc.want-res) > 1e-12 {
+			t.Fatalf("test %d: expected: %v, got: %v", i+1, tc.want, res)
+		}
+	}
+}
+
+// =============================================================================
+func TestBuildFreqs(t *testing.T) {
+
+	refs := []Sample{
+		{ID: "R1", Source: "refs.csv", Loci: []Locus{
+			{ID: "VWA", Alleles: []Allele{{ID: A2ID("14")}, {ID: A2ID("17")}}},
+			{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}}},
+		}},
+		{ID: "R2", Source: "refs.csv", Loci: []Locus{
+			{ID: "VWA", Alleles: []Allele{{ID: A2ID("17")}}},
+			{ID: "TH01", Alleles: []Allele{{ID: A2ID("OL")}}}, // not typed
+		}},
+	}
+
+	want := Freqs{Source: "refs.csv", Pop: "in-house", Fmin: 0.01, MinRule: FMIN5N, Floci: []Flocus{
+		{ID: "VWA", N: 2, Falleles: []Fallele{
+			{ID: A2ID("14"), Freq: 0.25, Count: 1},
+			{ID: A2ID("17"), Freq: 0.75, Count: 3},
+		}},
+		{ID: "TH01", N: 1, Falleles: []Fallele{{ID: A2ID("6"), Freq: 1, Count: 2}}},
+	}}
+
+	res, err := BuildFreqs(refs, "in-house", 0.01, FMIN5N)
+	if err != nil || !reflect.DeepEqual(want, res) {
+		t.Fatalf("expected: %v, got: %v (%v)", want, res, err)
+	}
+
+	refs[0].Loci[0].Alleles = append(refs[0].Loci[0].Alleles, Allele{ID: A2ID("18")})
+	if _, err := BuildFreqs(refs, "in-house", 0.01, FMIN5N); err == nil {
+		t.Fatalf("expected error for tri-allelic reference")
+	}
+}
+
+// =============================================================================
+func TestFreqs_Freq(t *testing.T) {
+
+	f := Freqs{Fmin: 0.001, Floci: []Flocus{
+		{ID: "VWA", N: 100, Falleles: []Fallele{{ID: A2ID("14"), Freq: 0.01}, {ID: A2ID("17"), Freq: 0.3}}},
+		{ID: "TH01", Falleles: []Fallele{{ID: A2ID("6"), Freq: 0.2}}}, // unknown N
+	}}
+
+	type test struct {
+		rule MinFreqRule
+		lID  string
+		id   string
+		want float64
+	}
+
+	tests := []test{
+		{FMINFIXED, "VWA", "14", 0.01},
+		{FMINFIXED, "VWA", "21", 0.001},
+		{FMIN5N, "VWA", "14", 0.025},
+		{FMIN5N, "VWA", "17", 0.3},
+		{FMIN5N, "VWA", "21", 0.025},
+		{FMIN1N, "VWA", "14", 0.01},
+		{FMIN1N, "VWA", "21", 0.005},
+		{FMIN5N, "TH01", "9.3", 0.001}, // falls back to Fmin
+	}
+
+	for i, tc := range tests {
+		f.MinRule = tc.rule
+		if res := f.Freq(tc.lID, A2ID(tc.id)); math.Abs(res-tc.want) > 1e-12 {
+			t.Fatalf("test %d (%v): expected: %v, got: %v", i+1, tc.rule, tc.want, res)
+		}
+	}
+}
+
+// =============================================================================
+func TestFreqs_SizeBiased(t *testing.T) {
+
+	// 50 individuals, i.e. 100 alleles
+	f := Freqs{Floci: []Flocus{
+		{ID: "VWA", N: 50, Falleles: []Fallele{
+			{ID: A2ID("14"), Freq: 0.1, Count: 10},
+			{ID: A2ID("17"), Freq: 0.9, Count: 90},
+		}},
+		{ID: "TH01", N: 50, Falleles: []Fallele{{ID: A2ID("6"), Freq: 1}}}, // counts unknown
+	}}
+	p := Sample{ID: "suspect", Loci: []Locus{
+		{ID: "VWA", Alleles: []Allele{{ID: A2ID("14")}, {ID: A2ID("21")}}},
+		{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}}},
+	}}
+
+	res := f.SizeBiased(p)
+
+	want := []Flocus{
+		{ID: "VWA", N: 52, Falleles: []Fallele{
+			{ID: A2ID("14"), Freq: 12.0 / 104, Count: 12},
+			{ID: A2ID("17"), Freq: 90.0 / 104, Count: 90},
+			{ID: A2ID("21"), Freq: 2.0 / 104, Count: 2},
+		}},
+		{ID: "TH01", N: 52, Falleles: []Fallele{{ID: A2ID("6"), Freq: 1}}},
+	}
+	if !reflect.DeepEqual(want, res.Floci) {
+		t.Fatalf("expected: %v, got: %v", want, res.Floci)
+	}
+
+	// f is unchanged
+	if f.Floci[0].N != 50 || f.Floci[0].Falleles[0].Count != 10 || len(f.Floci[0].Falleles) != 2 {
+		t.Fatalf("expected unchanged frequencies, got: %v", f.Floci)
+	}
+
+	// a CE allele is spread over the isoalleles of its length
+	fs := Freqs{Floci: []Flocus{{ID: "D12S391", N: 50, Falleles: []Fallele{
+		{ID: A2ID("18"), Freq: 0.9, Count: 90},
+		{ID: A2ID("19"), Freq: 0.06, Count: 6, Seq: d12iso1.Seq},
+		{ID: A2ID("19"), Freq: 0.04, Count: 4, Seq: d12iso2.Seq},
+	}}}}
+	ce := Sample{ID: "suspect", Loci: []Locus{
+		{ID: "D12S391", Alleles: []Allele{{ID: A2ID("19")}, {ID: A2ID("20")}}},
+	}}
+	want = []Flocus{{ID: "D12S391", N: 52, Falleles: []Fallele{
+		{ID: A2ID("18"), Freq: 90.0 / 104, Count: 90},
+		{ID: A2ID("19"), Freq: 4.8 / 104, Count: 5, Seq: d12iso2.Seq},
+		{ID: A2ID("19"), Freq: 7.2 / 104, Count: 7, Seq: d12iso1.Seq},
+		{ID: A2ID("20"), Freq: 2.0 / 104, Count: 2},
+	}}}
+	res = fs.SizeBiased(ce)
+	if len(res.Floci[0].Falleles) != len(want[0].Falleles) {
+		t.Fatalf("expected: %v, got: %v", want, res.Floci)
+	}
+	for i, fa := range res.Floci[0].Falleles {
+		w := want[0].Falleles[i]
+		if fa.ID != w.ID || fa.Seq != w.Seq || fa.Count != w.Count || math.Abs(fa.Freq-w.Freq) > 1e-12 {
+			t.Fatalf("allele %d: expected: %v, got: %v", i+1, w, fa)
+		}
+	}
+}
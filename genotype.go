@@ -0,0 +1,286 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"sort"
+	"strconv"
+)
+
+// wildcard is the allele F that stands for any allele, i.e. a possible
+// drop-out, in weighted genotypes.
+var wildcard = AlleleID{Cat: WILDCARD}
+
+// WeightedLocus holds the candidate genotypes of a person at a locus ranked
+// by decreasing weight. The wildcard F stands for any allele, e.g. 15,F is
+// 15 with a possible drop-out of the second allele. F is always the second
+// allele of a genotype (see orderedGenotype).
+type WeightedLocus struct {
+	ID        string
+	Genotypes []GenotypeWeight
+}
+
+// WeightedProfile is a person profile with weighted candidate genotypes
+// instead of definite alleles, e.g. of an uncertain minor contributor.
+type WeightedProfile struct {
+	ID     string
+	Source string
+	Loci   []WeightedLocus
+}
+
+// WeightedMatch holds the comparison of a reference with a weighted profile.
+type WeightedMatch struct {
+	Reference  string
+	Loci       int     // number of loci of the profile typed in the reference
+	Matches    int     // loci with the genotype of the reference amongst the genotypes
+	TopMatches int     // loci with the genotype of the reference ranked first
+	Weight     float64 // product of the weights of the genotypes of the reference
+}
+
+// Locus returns the weighted locus id of wp, or an empty locus.
+func (wp WeightedProfile) Locus(id string) WeightedLocus {
+	for _, wl := range wp.Loci {
+		if wl.ID == id {
+			return wl
+		}
+	}
+	return WeightedLocus{}
+}
+
+// Weighted returns the profile of person s with all genotypes of weight 1.
+// A single allele at a locus is a homozygote.
+func (s Sample) Weighted() WeightedProfile {
+	wp := WeightedProfile{ID: s.ID, Source: s.Source}
+	for _, l := range s.Loci {
+		if gt, ok := refGenotype(l); ok {
+			wp.Loci = append(wp.Loci, WeightedLocus{ID: l.ID,
+				Genotypes: []GenotypeWeight{{Genotype: gt, Weight: 1}}})
+		}
+	}
+	return wp
+}
+
+// Sample returns the genotypes of wp of the largest weight as a sample. The
+// wildcard F is omitted, so 15,F yields the single allele 15.
+func (wp WeightedProfile) Sample() Sample {
+	s := NewSample(wp.ID, wp.Source)
+	for _, wl := range wp.Loci {
+		if len(wl.Genotypes) == 0 {
+			continue
+		}
+		l := NewLocus(wl.ID)
+		for _, id := range wl.Genotypes[0].Genotype {
+			if id.IsTyped() {
+				l.AddAllele(NewAllele(id))
+			}
+		}
+		if len(l.Alleles) > 0 {
+			s.AddLocus(l)
+		}
+	}
+	s.UnknownKit()
+	return s
+}
+
+// compatible reports whether the candidate genotype gt, possibly with the
+// wildcard F, agrees with the genotype ref.
+func compatible(gt, ref [2]AlleleID) bool {
+	gt = orderedGenotype(gt[0], gt[1])
+	switch {
+	case gt[1].IsWildcard():
+		return gt[0].IsWildcard() || gt[0] == ref[0] || gt[0] == ref[1]
+	default:
+		return gt == ref
+	}
+}
+
+// Weight returns the sum of the weights of the genotypes of wl compatible
+// with genotype gt and the rank of the first of them (0 if none is).
+func (wl WeightedLocus) Weight(gt [2]AlleleID) (float64, int) {
+	var w float64
+	var rank int
+	for i, x := range wl.Genotypes {
+		if compatible(x.Genotype, gt) {
+			w += x.Weight
+			if rank == 0 {
+				rank = i + 1
+			}
+		}
+	}
+	return w, rank
+}
+
+// Compare compares reference ref with wp at the loci typed in both.
+func (wp WeightedProfile) Compare(ref Sample) WeightedMatch {
+	wm := WeightedMatch{Reference: ref.ID, Weight: 1}
+	for _, wl := range wp.Loci {
+		gt, ok := refGenotype(ref.Locus(wl.ID))
+		if !ok || len(wl.Genotypes) == 0 {
+			continue
+		}
+		wm.Loci++
+		w, rank := wl.Weight(gt)
+		if rank > 0 {
+			wm.Matches++
+		}
+		if rank == 1 {
+			wm.TopMatches++
+		}
+		wm.Weight *= w
+	}
+	return wm
+}
+
+// SamePersonWeighted is SamePerson for a weighted profile wp and a person
+// profile p. A locus matches if the genotype of p is a definite genotype of
+// wp, it matches softly if it only agrees with a genotype with the wildcard
+// F, and it mismatches otherwise.
+func SamePersonWeighted(wp WeightedProfile, p Sample) bool {
+
+	if p.MaxAlleles() > 2 {
+		return false
+	}
+
+	count := make(map[mismatch]int)
+	for _, wl := range wp.Loci {
+		gt, ok := refGenotype(p.Locus(wl.ID))
+		if !ok || len(wl.Genotypes) == 0 {
+			continue
+		}
+
+		r := nomatch
+		for _, x := range wl.Genotypes {
+			if !compatible(x.Genotype, gt) {
+				continue
+			}
+			if !x.Genotype[0].IsWildcard() && !x.Genotype[1].IsWildcard() {
+				r = match
+				break
+			}
+			r = fuzzy
+		}
+		count[r]++
+	}
+
+	return withinTolerance(count, len(wp.Loci), len(p.Loci))
+}
+
+// WeightedMajorComponent is MajorComponent with weighted genotypes. Loci with
+// a major component have its genotype with weight 1. At the other loci the
+// genotypes compatible with the peak heights share the weight equally:
+// 15,15 and 15,F for a single allele 15 or a dominant allele 15 below
+// minHomozygous, 15,16 and 15,15 for 15 and 16 neither balanced nor distinct,
+// all pairs of the three largest alleles if the third allele is not distinct
+// from the second, and 15,16 alone for balanced alleles below weakSignal. Low
+// quality loci and IQCS/IQCL have no genotypes.
+func (s Sample) WeightedMajorComponent(heteroImbalance, majorCompRatio, minHomozygous, weakSignal float64) WeightedProfile {
+
+	wp := WeightedProfile{ID: "MC::", Source: s.Source}
+	for _, l := range s.Loci {
+		wl := l.WeightedMajorComponent(heteroImbalance, majorCompRatio, minHomozygous, weakSignal)
+		if len(wl.Genotypes) > 0 {
+			wp.Loci = append(wp.Loci, wl)
+		}
+	}
+	return wp
+}
+
+// WeightedMajorComponent returns the weighted genotypes of the major
+// component at locus l. See Sample.WeightedMajorComponent.
+func (l Locus) WeightedMajorComponent(heteroImbalance, majorCompRatio, minHomozygous, weakSignal float64) WeightedLocus {
+
+	wl := WeightedLocus{ID: l.ID}
+	if l.ID == "IQCS" || l.ID == "IQCL" || l.IsLowQuality() {
+		return wl
+	}
+
+	if mc := l.MajorComponent(heteroImbalance, majorCompRatio, minHomozygous, weakSignal); len(mc.Alleles) > 0 {
+		gt, _ := refGenotype(mc)
+		wl.Genotypes = []GenotypeWeight{{Genotype: gt, Weight: 1}}
+		return wl
+	}
+
+	sortLoc := l.WithoutOffLadder().SortByHeight()
+	var candidates [][2]AlleleID
+	switch a := sortLoc.Alleles; {
+	case len(a) == 0:
+		return wl
+	case len(a) == 1 || a[0].Height/a[1].Height > majorCompRatio:
+		candidates = [][2]AlleleID{{a[0].ID, a[0].ID}, orderedGenotype(a[0].ID, wildcard)}
+	case a[1].Height/a[0].Height <= heteroImbalance:
+		candidates = [][2]AlleleID{orderedGenotype(a[0].ID, a[1].ID), {a[0].ID, a[0].ID}}
+	case len(a) > 2 && a[1].Height/a[2].Height <= majorCompRatio:
+		candidates = [][2]AlleleID{orderedGenotype(a[0].ID, a[1].ID),
+			orderedGenotype(a[0].ID, a[2].ID), orderedGenotype(a[1].ID, a[2].ID)}
+	default: // balanced but weak
+		candidates = [][2]AlleleID{orderedGenotype(a[0].ID, a[1].ID)}
+	}
+
+	for _, gt := range candidates {
+		wl.Genotypes = append(wl.Genotypes, GenotypeWeight{Genotype: gt, Weight: 1 / float64(len(candidates))})
+	}
+	return wl
+}
+
+// InferWeightedUnknownPersons is InferUnknownPersons with weighted genotypes.
+// The unknown persons are peeled off as by InferUnknownPersons, but each of
+// them holds the weighted genotypes of its major component at all autosomal
+// loci (see WeightedMajorComponent).
+func (s Sample) InferWeightedUnknownPersons(heteroImbalance, majorCompRatio,
+	minHomozygous, weakSignal float64) []WeightedProfile {
+
+	_, UPs := s.inferUnknownPersons(heteroImbalance, majorCompRatio, minHomozygous, weakSignal, true)
+	return UPs
+}
+
+// weightedUP returns the unknown person id of the weighted major component of
+// s at the autosomal loci.
+func (s Sample) weightedUP(id, source string, heteroImbalance, majorCompRatio,
+	minHomozygous, weakSignal float64) WeightedProfile {
+
+	up := WeightedProfile{ID: id, Source: source}
+	for _, wl := range s.WeightedMajorComponent(heteroImbalance, majorCompRatio, minHomozygous, weakSignal).Loci {
+		if (Locus{ID: wl.ID}).Linkage() == AUTOSOMAL {
+			up.Loci = append(up.Loci, wl)
+		}
+	}
+	return up
+}
+
+// Profile returns the weighted profile of contributor k of d. See
+// Contributor.
+func (d Deconvolution) Profile(k int) WeightedProfile {
+	wp := WeightedProfile{ID: d.Sample + "::C" + strconv.Itoa(k+1), Source: d.Sample}
+	c := d.Contributor(k)
+	for _, ld := range d.Loci {
+		if gws := c[ld.Locus]; len(gws) > 0 {
+			wp.Loci = append(wp.Loci, WeightedLocus{ID: ld.Locus, Genotypes: gws})
+		}
+	}
+	return wp
+}
+
+// ExportWeighted writes the weighted profiles wps to file f, separated by
+// sep.
+func ExportWeighted(wps []WeightedProfile, f string, sep rune) error {
+	return write2CSV(buildWeighted(wps), f, sep)
+}
+
+// buildWeighted builds the rows of the weighted profiles. See
+// ExportWeighted.
+func buildWeighted(wps []WeightedProfile) [][]string {
+	d := [][]string{{"Sample Name", "Marker", "Rank", "Allele 1", "Allele 2", "Weight"}}
+	for _, wp := range wps {
+		for _, wl := range wp.Loci {
+			gws := append([]GenotypeWeight(nil), wl.Genotypes...)
+			sort.SliceStable(gws, func(i, j int) bool { return gws[i].Weight > gws[j].Weight })
+			for i, gw := range gws {
+				d = append(d, []string{wp.ID, wl.ID, strconv.Itoa(i + 1),
+					gw.Genotype[0].String(), gw.Genotype[1].String(),
+					strconv.FormatFloat(gw.Weight, 'g', 6, 64)})
+			}
+		}
+	}
+	return d
+}
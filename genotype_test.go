@@ -0,0 +1,165 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// =============================================================================
+func TestLocus_WeightedMajorComponent(t *testing.T) {
+
+	const (
+		MinHomozygous   = 1500
+		MajorCompRatio  = 2.9
+		HeteroImbalance = 0.67
+		WeakSignal      = 500
+	)
+
+	gt := func(a, b string) [2]AlleleID { return [2]AlleleID{A2ID(a), A2ID(b)} }
+
+	type test struct {
+		inLocus Locus
+		want    [][2]AlleleID
+	}
+
+	tests := []test{
+		{ // 1: major component
+			peaks("s", "L:15:3000", "L:16:2800", "L:17:400").Loci[0],
+			[][2]AlleleID{gt("15", "16")},
+		},
+		{ // 2: weak single allele, possible drop-out
+			peaks("s", "L:15:900").Loci[0],
+			[][2]AlleleID{gt("15", "15"), gt("15", "F")},
+		},
+		{ // 3: neither balanced nor distinct
+			peaks("s", "L:15:3000", "L:16:1500").Loci[0],
+			[][2]AlleleID{gt("15", "16"), gt("15", "15")},
+		},
+		{ // 4: third allele not distinct
+			peaks("s", "L:15:3000", "L:16:2800", "L:17:1500").Loci[0],
+			[][2]AlleleID{gt("15", "16"), gt("15", "17"), gt("16", "17")},
+		},
+		{ // 5: balanced but weak
+			peaks("s", "L:15:400", "L:16:380").Loci[0],
+			[][2]AlleleID{gt("15", "16")},
+		},
+		{ // 6: low quality
+			Locus{ID: "L", Alleles: []Allele{{ID: A2ID("15"), Height: 3000}},
+				PQVs: PQVs{"ADO": LOWQUALITY}},
+			nil,
+		},
+	}
+
+	for i, tt := range tests {
+		wl := tt.inLocus.WeightedMajorComponent(HeteroImbalance, MajorCompRatio, MinHomozygous, WeakSignal)
+		var got [][2]AlleleID
+		var sum float64
+		for _, gw := range wl.Genotypes {
+			got = append(got, gw.Genotype)
+			sum += gw.Weight
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("test %d: expected: %v, got: %v", i+1, tt.want, got)
+		}
+		if len(got) > 0 && math.Abs(sum-1) > 1e-9 {
+			t.Fatalf("test %d: expected: weights sum to 1, got: %v", i+1, sum)
+		}
+	}
+}
+
+// =============================================================================
+func TestWeightedProfile_Compare(t *testing.T) {
+
+	wp := WeightedProfile{ID: "UP", Loci: []WeightedLocus{
+		{ID: "L1", Genotypes: []GenotypeWeight{{[2]AlleleID{A2ID("15"), A2ID("15")}, 0.5}, {[2]AlleleID{A2ID("15"), wildcard}, 0.5}}},
+		{ID: "L2", Genotypes: []GenotypeWeight{{[2]AlleleID{A2ID("9"), A2ID("10")}, 0.7}, {[2]AlleleID{A2ID("9"), A2ID("9")}, 0.3}}},
+		{ID: "L3", Genotypes: []GenotypeWeight{{[2]AlleleID{A2ID("20"), A2ID("21")}, 1}}},
+		{ID: "L4", Genotypes: []GenotypeWeight{{[2]AlleleID{A2ID("12"), A2ID("13")}, 1}}},
+	}}
+
+	type test struct {
+		ref  Sample
+		want WeightedMatch
+		same bool
+	}
+
+	tests := []test{
+		{ // 15,15 is compatible with 15,15 and 15,F
+			peaks("A", "L1:15", "L2:9", "L2:10", "L3:20", "L3:21", "L4:12", "L4:13"),
+			WeightedMatch{Reference: "A", Loci: 4, Matches: 4, TopMatches: 4, Weight: 0.7},
+			true,
+		},
+		{ // 15,17 only with 15,F
+			peaks("B", "L1:15", "L1:17", "L2:9", "L3:20", "L3:21", "L4:12", "L4:13"),
+			WeightedMatch{Reference: "B", Loci: 4, Matches: 4, TopMatches: 2, Weight: 0.15},
+			true,
+		},
+		{
+			peaks("C", "L1:16", "L2:11", "L3:20", "L3:22", "L4:12", "L4:13"),
+			WeightedMatch{Reference: "C", Loci: 4, Matches: 1, TopMatches: 1, Weight: 0},
+			false,
+		},
+	}
+
+	for i, tt := range tests {
+		got := wp.Compare(tt.ref)
+		if got.Loci != tt.want.Loci || got.Matches != tt.want.Matches ||
+			got.TopMatches != tt.want.TopMatches || math.Abs(got.Weight-tt.want.Weight) > 1e-9 {
+			t.Fatalf("test %d: expected: %v, got: %v", i, tt.want, got)
+		}
+		if same := SamePersonWeighted(wp, tt.ref); same != tt.same {
+			t.Fatalf("test %d: expected: %v, got: %v", i, tt.same, same)
+		}
+	}
+
+	// the hard call omits the wildcard
+	s := wp.Sample()
+	if len(s.Loci) != 4 || len(s.Locus("L1").Alleles) != 1 || len(s.Locus("L2").Alleles) != 2 {
+		t.Fatalf("unexpected hard call %v", s)
+	}
+	if !reflect.DeepEqual(s.Weighted().Loci[2], wp.Loci[2]) {
+		t.Fatalf("expected: %v, got: %v", wp.Loci[2], s.Weighted().Loci[2])
+	}
+
+	// the wildcard is the second allele of a genotype
+	if gt := orderedGenotype(wildcard, A2ID("15")); gt != [2]AlleleID{A2ID("15"), wildcard} {
+		t.Fatalf("expected: 15,F, got: %v", gt)
+	}
+	if !compatible([2]AlleleID{wildcard, A2ID("15")}, [2]AlleleID{A2ID("15"), A2ID("17")}) {
+		t.Fatalf("expected: F,15 compatible with 15,17")
+	}
+
+	rows := buildWeighted([]WeightedProfile{wp})
+	if len(rows) != 7 || !reflect.DeepEqual(rows[2], []string{"UP", "L1", "2", "15", "F", "0.5"}) {
+		t.Fatalf("unexpected table %v", rows)
+	}
+}
+
+// =============================================================================
+func TestSample_InferWeightedUnknownPersons(t *testing.T) {
+
+	s := peaks("stain", "L1:19:15980", "L2:27:3043", "L2:28:15953", "L2:29:17927",
+		"L3:15:900", "L4:9:3000", "L4:10:1500")
+
+	ups := s.InferWeightedUnknownPersons(0.67, 2.9, 1500, 500)
+	if len(ups) == 0 || ups[0].ID != "stain::UP1" {
+		t.Fatalf("expected: unknown persons, got: %v", ups)
+	}
+	// L3 and L4 have no major component, but weighted genotypes
+	if len(ups[0].Locus("L3").Genotypes) != 2 || len(ups[0].Locus("L4").Genotypes) != 2 {
+		t.Fatalf("expected: weighted genotypes at L3 and L4, got: %v", ups[0])
+	}
+	hard := s.InferUnknownPersons(0.67, 2.9, 1500, 500)
+	if len(hard) != len(ups) {
+		t.Fatalf("expected: %v unknown persons, got: %v", len(hard), len(ups))
+	}
+	for i := range hard {
+		if hard[i].ID != ups[i].ID || hard[i].Source != ups[i].Source {
+			t.Fatalf("expected: %v from %v, got: %v from %v", hard[i].ID, hard[i].Source, ups[i].ID, ups[i].Source)
+		}
+	}
+}
@@ -0,0 +1,516 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// MutationModel is the model of the mutations between parent and child.
+type MutationModel int
+
+const (
+	NOMUTATION MutationModel = iota
+	// STEPWISE mutations to alleles d repeats away have probabilities
+	// proportional to r^(d-1) for the mutation range r; mutations to alleles
+	// with a different fractional repeat are not possible.
+	STEPWISE
+	// PROPORTIONAL mutations to an allele have probabilities proportional to
+	// its frequency.
+	PROPORTIONAL
+)
+
+// String returns the mutation model as string.
+func (m MutationModel) String() string {
+	switch m {
+	case STEPWISE:
+		return "stepwise"
+	case PROPORTIONAL:
+		return "proportional"
+	default: // NOMUTATION
+		return "no mutation"
+	}
+}
+
+// KinshipConf holds the parameters of the kinship calculations. Zero values
+// of Rate and Range are replaced by the values of DefaultKinshipConf; use
+// NOMUTATION to disable mutations.
+type KinshipConf struct {
+	Model MutationModel
+	Rate  float64            // mutation rate per meiosis of all loci without Rates
+	Rates map[string]float64 // mutation rates per meiosis by locus ID
+	Range float64            // mutation range r of the stepwise model
+	// NullProb is the frequency of silent (null) alleles: a homozygous
+	// person may carry a null allele.
+	NullProb float64
+	Theta    float64 // Balding–Nichols correction of the founders
+}
+
+// DefaultKinshipConf holds the default parameters of the kinship
+// calculations.
+var DefaultKinshipConf = KinshipConf{
+	Model: STEPWISE,
+	Rate:  0.002,
+	Range: 0.1,
+}
+
+// withDefaults returns c with zero values replaced by the defaults.
+func (c KinshipConf) withDefaults() KinshipConf {
+	if c.Rate == 0 {
+		c.Rate = DefaultKinshipConf.Rate
+	}
+	if c.Range == 0 {
+		c.Range = DefaultKinshipConf.Range
+	}
+	return c
+}
+
+// rate returns the mutation rate of locus lID.
+func (c KinshipConf) rate(lID string) float64 {
+	if c.Model == NOMUTATION {
+		return 0
+	}
+	if r, ok := c.Rates[lID]; ok {
+		return r
+	}
+	return c.Rate
+}
+
+// validate checks the parameters of c.
+func (c KinshipConf) validate() error {
+	switch {
+	case c.Rate < 0 || c.Rate > 1:
+		return fmt.Errorf("mutation rate %v not in [0, 1]", c.Rate)
+	case c.Range <= 0 || c.Range >= 1:
+		return fmt.Errorf("mutation range %v not in (0, 1)", c.Range)
+	case c.NullProb < 0 || c.NullProb >= 1 || c.Theta < 0 || c.Theta >= 1:
+		return fmt.Errorf("null allele probability %v or theta %v not in [0, 1)", c.NullProb, c.Theta)
+	}
+	for lID, r := range c.Rates {
+		if r < 0 || r > 1 {
+			return fmt.Errorf("locus %v: mutation rate %v not in [0, 1]", lID, r)
+		}
+	}
+	return nil
+}
+
+// PedPerson is a member of a pedigree. Founders have no parents; all other
+// persons have a father and a mother.
+type PedPerson struct {
+	ID     string
+	Father string
+	Mother string
+	Male   bool
+}
+
+// isFounder returns true if p has no parents.
+func (p PedPerson) isFounder() bool {
+	return p.Father == "" && p.Mother == ""
+}
+
+// Pedigree describes the relationships of a group of persons. Typed persons
+// are identified by the IDs of their profiles.
+type Pedigree struct {
+	Name    string
+	Persons []PedPerson
+}
+
+// Person returns the person with ID id and true, or false if p does not hold
+// it.
+func (p Pedigree) Person(id string) (PedPerson, bool) {
+	for _, x := range p.Persons {
+		if x.ID == id {
+			return x, true
+		}
+	}
+	return PedPerson{}, false
+}
+
+// Validate checks whether the persons of p have unique IDs, both or no
+// parents, male fathers and female mothers, and whether nobody is their own
+// ancestor.
+func (p Pedigree) Validate() error {
+
+	if _, err := p.sorted(); err != nil {
+		return fmt.Errorf("pedigree %v: %w", p.Name, err)
+	}
+	return nil
+}
+
+// sorted returns the persons of p with all parents before their children.
+func (p Pedigree) sorted() ([]PedPerson, error) {
+
+	ids := make(map[string]PedPerson)
+	for _, x := range p.Persons {
+		if x.ID == "" {
+			return nil, fmt.Errorf("person without ID")
+		}
+		if _, ok := ids[x.ID]; ok {
+			return nil, fmt.Errorf("duplicate person %v", x.ID)
+		}
+		ids[x.ID] = x
+	}
+
+	for _, x := range p.Persons {
+		if x.isFounder() {
+			continue
+		}
+		if x.Father == "" || x.Mother == "" {
+			return nil, fmt.Errorf("person %v: only one parent", x.ID)
+		}
+		f, okF := ids[x.Father]
+		m, okM := ids[x.Mother]
+		switch {
+		case !okF || !okM:
+			return nil, fmt.Errorf("person %v: parent not in pedigree", x.ID)
+		case !f.Male:
+			return nil, fmt.Errorf("person %v: father %v is not male", x.ID, f.ID)
+		case m.Male:
+			return nil, fmt.Errorf("person %v: mother %v is male", x.ID, m.ID)
+		}
+	}
+
+	var r []PedPerson
+	done := make(map[string]bool)
+	for len(r) < len(p.Persons) {
+		n := len(r)
+		for _, x := range p.Persons {
+			if !done[x.ID] && (x.isFounder() || done[x.Father] && done[x.Mother]) {
+				r = append(r, x)
+				done[x.ID] = true
+			}
+		}
+		if len(r) == n {
+			return nil, fmt.Errorf("cyclic pedigree")
+		}
+	}
+
+	return r, nil
+}
+
+// Trio returns the pedigree of child with the parents father and mother.
+func Trio(child, father, mother string) Pedigree {
+	return Pedigree{Name: "trio", Persons: []PedPerson{
+		{ID: father, Male: true}, {ID: mother}, {ID: child, Father: father, Mother: mother},
+	}}
+}
+
+// Unrelated returns the pedigree of unrelated founders with the IDs ids.
+func Unrelated(ids ...string) Pedigree {
+	p := Pedigree{Name: "unrelated"}
+	for _, id := range ids {
+		p.Persons = append(p.Persons, PedPerson{ID: id})
+	}
+	return p
+}
+
+// Males returns p with the persons ids male, e.g. Unrelated("F", "D").Males("F")
+// for an unrelated man and woman.
+func (p Pedigree) Males(ids ...string) Pedigree {
+	r := p
+	r.Persons = append([]PedPerson(nil), p.Persons...)
+	for i, x := range r.Persons {
+		if slices.Contains(ids, x.ID) {
+			r.Persons[i].Male = true
+		}
+	}
+	return r
+}
+
+// KinshipLR returns the likelihood ratio of the profiles of the typed persons
+// under the pedigrees ped1 (Hp) and ped2 (Hd) for all autosomal loci with
+// frequency data in f, except amelogenin. The persons of the pedigrees are
+// the profiles with the same ID and untyped persons; persons of ped1 and ped2
+// without a profile are summed over all genotypes. Loci where not all
+// persons are typed contribute with the profiles of the typed persons.
+//
+// Founder alleles are drawn with the Balding–Nichols correction c.Theta.
+// Parents transmit each of their alleles with probability 1/2 and mutate it
+// by c.Model. Homozygous persons may carry a null allele with frequency
+// c.NullProb. Alleles that are not in the profiles are pooled.
+func KinshipLR(profiles []Sample, ped1, ped2 Pedigree, f Freqs, c KinshipConf) (LRResult, error) {
+
+	c = c.withDefaults()
+	if err := c.validate(); err != nil {
+		return LRResult{}, err
+	}
+
+	var sorted [2][]PedPerson
+	for i, p := range []Pedigree{ped1, ped2} {
+		var err error
+		if sorted[i], err = p.sorted(); err != nil {
+			return LRResult{}, fmt.Errorf("pedigree %v: %w", p.Name, err)
+		}
+		for _, s := range profiles {
+			if _, ok := p.Person(s.ID); !ok {
+				return LRResult{}, fmt.Errorf("pedigree %v: no person %v", p.Name, s.ID)
+			}
+		}
+	}
+
+	r := LRResult{LR: 1}
+	var included int
+	for _, lID := range evidenceLoci(profiles) {
+
+		ll := LocusLR{Locus: lID, Excluded: mixExcluded(lID, f, nil)}
+		if ll.Excluded == "" {
+			k, err := newKinLocus(profiles, lID, f, c)
+			if err != nil {
+				return LRResult{}, err
+			}
+			ll.Hp = k.likelihood(sorted[0])
+			ll.Hd = k.likelihood(sorted[1])
+			if ll.Hd == 0 {
+				return LRResult{}, fmt.Errorf("locus %v: profiles impossible under pedigree %v",
+					lID, ped2.Name)
+			}
+			ll.LR = ll.Hp / ll.Hd
+			r.LR *= ll.LR
+			included++
+		}
+
+		r.Loci = append(r.Loci, ll)
+	}
+
+	if included == 0 {
+		return LRResult{}, fmt.Errorf("no locus with frequency data")
+	}
+
+	return r, nil
+}
+
+// PaternityIndex returns the paternity index of the trio of child, mother and
+// alleged father: the LR of 'the alleged father is the father of the child'
+// vs. 'a random man is the father of the child'.
+func PaternityIndex(child, mother, father Sample, f Freqs, c KinshipConf) (LRResult, error) {
+	hd := Trio(child.ID, "(random man)", mother.ID)
+	hd.Persons = append(hd.Persons, PedPerson{ID: father.ID, Male: true})
+	return KinshipLR([]Sample{child, mother, father}, Trio(child.ID, father.ID, mother.ID), hd, f, c)
+}
+
+// MotherlessPaternityIndex returns the paternity index of the child and the
+// alleged father without the mother.
+func MotherlessPaternityIndex(child, father Sample, f Freqs, c KinshipConf) (LRResult, error) {
+	hd := Trio(child.ID, "(random man)", "(mother)")
+	hd.Persons = append(hd.Persons, PedPerson{ID: father.ID, Male: true})
+	return KinshipLR([]Sample{child, father}, Trio(child.ID, father.ID, "(mother)"), hd, f, c)
+}
+
+// FullSiblingIndex returns the LR of 'a and b are full siblings' vs. 'a and b
+// are unrelated'.
+func FullSiblingIndex(a, b Sample, f Freqs, c KinshipConf) (LRResult, error) {
+	sibs := Pedigree{Name: "full siblings", Persons: []PedPerson{
+		{ID: "(father)", Male: true}, {ID: "(mother)"},
+		{ID: a.ID, Father: "(father)", Mother: "(mother)"},
+		{ID: b.ID, Father: "(father)", Mother: "(mother)"},
+	}}
+	return KinshipLR([]Sample{a, b}, sibs, Unrelated(a.ID, b.ID), f, c)
+}
+
+// HalfSiblingIndex returns the LR of 'a and b are paternal half siblings' vs.
+// 'a and b are unrelated'.
+func HalfSiblingIndex(a, b Sample, f Freqs, c KinshipConf) (LRResult, error) {
+	sibs := Pedigree{Name: "half siblings", Persons: []PedPerson{
+		{ID: "(father)", Male: true}, {ID: "(mother 1)"}, {ID: "(mother 2)"},
+		{ID: a.ID, Father: "(father)", Mother: "(mother 1)"},
+		{ID: b.ID, Father: "(father)", Mother: "(mother 2)"},
+	}}
+	return KinshipLR([]Sample{a, b}, sibs, Unrelated(a.ID, b.ID), f, c)
+}
+
+// kinLocus holds the data of the pedigree likelihood at a locus. Alleles are
+// numbered: the alleles of the profiles, the pooled allele Q of all other
+// alleles, and the null allele if its probability is not zero.
+type kinLocus struct {
+	ids   []AlleleID
+	p     []float64
+	null  int                 // index of the null allele; -1 if none
+	mut   [][]float64         // mutation probabilities from allele i to j
+	typed map[string][][2]int // possible genotypes of the typed persons
+	theta float64
+}
+
+// newKinLocus prepares the pedigree likelihood of locus lID.
+func newKinLocus(profiles []Sample, lID string, f Freqs, c KinshipConf) (kinLocus, error) {
+
+	k := kinLocus{null: -1, typed: make(map[string][][2]int), theta: c.Theta}
+	var sum float64
+	for _, s := range profiles {
+		for _, id := range typedGenotype(s.Locus(lID)) {
+			if k.index(id) < 0 {
+				k.ids = append(k.ids, id)
+				k.p = append(k.p, f.Freq(lID, id)*(1-c.NullProb))
+				sum += f.Freq(lID, id)
+			}
+		}
+	}
+	q := len(k.p)
+	k.p = append(k.p, math.Max(0, 1-sum)*(1-c.NullProb))
+	if c.NullProb > 0 {
+		k.null = len(k.p)
+		k.p = append(k.p, c.NullProb)
+	}
+
+	for _, s := range profiles {
+		g := typedGenotype(s.Locus(lID))
+		switch {
+		case len(g) == 0:
+			continue
+		case len(g) > 2:
+			return kinLocus{}, fmt.Errorf("sample %v, locus %v: %v alleles", s.ID, lID, len(g))
+		}
+		a, b := k.index(g[0]), k.index(g[1])
+		k.typed[s.ID] = [][2]int{{a, b}}
+		if a == b && k.null >= 0 {
+			k.typed[s.ID] = append(k.typed[s.ID], [2]int{a, k.null})
+		}
+	}
+
+	k.mut = mutationMatrix(k.ids, k.p, q, k.null, c.rate(lID), c)
+	return k, nil
+}
+
+// mutationMatrix returns the probabilities of the mutations of the alleles
+// ids, the pooled allele q and the null allele null with the frequencies p.
+// The pooled allele receives all stepwise mutations to alleles that are not
+// in ids and mutates proportionally; null alleles do not mutate.
+func mutationMatrix(ids []AlleleID, p []float64, q, null int, rate float64, c KinshipConf) [][]float64 {
+
+	m := make([][]float64, len(p))
+	for i := range m {
+		m[i] = make([]float64, len(p))
+		m[i][i] = 1
+		if i == null || rate == 0 {
+			continue
+		}
+
+		// the mutable alleles and their frequencies
+		var other float64
+		for j := range p {
+			if j != i && j != null {
+				other += p[j]
+			}
+		}
+
+		m[i][i] = 1 - rate
+		switch {
+		case c.Model == STEPWISE && i != q:
+			var sum float64
+			for j, id := range ids {
+				if j == i || !id.HasRepeats() || !ids[i].HasRepeats() || id.Partial != ids[i].Partial {
+					continue
+				}
+				d := math.Abs(float64(id.Repeats - ids[i].Repeats))
+				m[i][j] = rate * (1 - c.Range) * math.Pow(c.Range, d-1) / 2
+				sum += m[i][j]
+			}
+			m[i][q] = rate - sum
+		case other > 0:
+			for j := range p {
+				if j != i && j != null {
+					m[i][j] = rate * p[j] / other
+				}
+			}
+		default:
+			m[i][i] = 1
+		}
+	}
+
+	return m
+}
+
+// index returns the index of allele id or -1 if k does not hold it.
+func (k kinLocus) index(id AlleleID) int {
+	for i, x := range k.ids {
+		if x == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// genotypes returns the possible genotypes of person id.
+func (k kinLocus) genotypes(id string) [][2]int {
+	if g, ok := k.typed[id]; ok {
+		return g
+	}
+	var r [][2]int
+	for a := range k.p {
+		for b := a; b < len(k.p); b++ {
+			r = append(r, [2]int{a, b})
+		}
+	}
+	return r
+}
+
+// transmit returns the probability that a parent with genotype g transmits
+// allele a.
+func (k kinLocus) transmit(g [2]int, a int) float64 {
+	return (k.mut[g[0]][a] + k.mut[g[1]][a]) / 2
+}
+
+// likelihood returns the probability of the typed genotypes given the
+// persons sorted with parents before their children.
+func (k kinLocus) likelihood(persons []PedPerson) float64 {
+
+	genotypes := make(map[string][2]int)
+	counts := make([]int, len(k.p))
+
+	// walk sums over the genotypes of persons i, ... given the n founder
+	// alleles drawn so far
+	var walk func(i, n int) float64
+	walk = func(i, n int) float64 {
+		if i == len(persons) {
+			return 1
+		}
+
+		x := persons[i]
+		var r float64
+		for _, g := range k.genotypes(x.ID) {
+			var prob float64
+			if x.isFounder() {
+				prob = k.draw(g[0], counts[g[0]], n)
+				counts[g[0]]++
+				prob *= k.draw(g[1], counts[g[1]], n+1)
+				counts[g[0]]--
+				if g[0] != g[1] {
+					prob *= 2
+				}
+			} else {
+				f, m := genotypes[x.Father], genotypes[x.Mother]
+				prob = k.transmit(f, g[0]) * k.transmit(m, g[1])
+				if g[0] != g[1] {
+					prob += k.transmit(f, g[1]) * k.transmit(m, g[0])
+				}
+			}
+			if prob == 0 {
+				continue
+			}
+
+			genotypes[x.ID] = g
+			if x.isFounder() {
+				counts[g[0]]++
+				counts[g[1]]++
+				prob *= walk(i+1, n+2)
+				counts[g[0]]--
+				counts[g[1]]--
+			} else {
+				prob *= walk(i+1, n)
+			}
+			r += prob
+		}
+
+		return r
+	}
+
+	return walk(0, 0)
+}
+
+// draw returns the probability to draw allele a given that it has been seen na
+// times amongst n founder alleles.
+func (k kinLocus) draw(a, na, n int) float64 {
+	return (float64(na)*k.theta + (1-k.theta)*k.p[a]) / (1 + float64(n-1)*k.theta)
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"testing"
+)
+
+// =============================================================================
+func Test_PaternityIndex(t *testing.T) {
+
+	p := func(a string) float64 { return contFreqs.Freq("VWA", A2ID(a)) }
+	noMut := KinshipConf{Model: NOMUTATION}
+
+	type test struct {
+		child, mother, father Sample
+		conf                  KinshipConf
+		want                  float64
+	}
+
+	tests := []test{
+		{ // 1: obligate paternal allele 17 (NRC II): PI = 1/(2p)
+			peaks("child", "VWA:14", "VWA:17"), peaks("mother", "VWA:14", "VWA:16"),
+			peaks("AF", "VWA:15", "VWA:17"), noMut, 1 / (2 * p("17")),
+		},
+		{ // 2: homozygous alleged father: PI = 1/p
+			peaks("child", "VWA:14", "VWA:17"), peaks("mother", "VWA:14", "VWA:16"),
+			peaks("AF", "VWA:17"), noMut, 1 / p("17"),
+		},
+		{ // 3: exclusion without mutation
+			peaks("child", "VWA:14", "VWA:17"), peaks("mother", "VWA:14", "VWA:16"),
+			peaks("AF", "VWA:15", "VWA:18"), noMut, 0,
+		},
+		{ // 4: stepwise mutations 18 > 17 and 15 > 17: μ(1-r)/2 and μ(1-r)r/2
+			peaks("child", "VWA:14", "VWA:17"), peaks("mother", "VWA:14", "VWA:16"),
+			peaks("AF", "VWA:15", "VWA:18"), KinshipConf{Model: STEPWISE, Rate: 0.002, Range: 0.1},
+			(0.002*0.9/2 + 0.002*0.9*0.1/2) / 2 / p("17"),
+		},
+	}
+
+	for i, test := range tests {
+		r, err := PaternityIndex(test.child, test.mother, test.father, contFreqs, test.conf)
+		if err != nil || math.Abs(r.LR-test.want) > 0.01*test.want {
+			t.Fatalf("test %d: expected: %v, got: %v (%v)", i+1, test.want, r.LR, err)
+		}
+	}
+
+	// motherless: child 14/17, alleged father 15/17: PI = 1/(4p)
+	r, err := MotherlessPaternityIndex(peaks("child", "VWA:14", "VWA:17"),
+		peaks("AF", "VWA:15", "VWA:17"), contFreqs, noMut)
+	if want := 1 / (4 * p("17")); err != nil || math.Abs(r.LR-want) > 1e-9 {
+		t.Fatalf("motherless: expected: %v, got: %v (%v)", want, r.LR, err)
+	}
+}
+
+// =============================================================================
+func Test_SiblingIndex(t *testing.T) {
+
+	pa := contFreqs.Freq("VWA", A2ID("14"))
+	a := peaks("A", "VWA:14", "TH01:6", "TH01:9.3")
+	b := peaks("B", "VWA:14", "TH01:7", "TH01:8")
+	noMut := KinshipConf{Model: NOMUTATION}
+
+	r, err := FullSiblingIndex(a, b, contFreqs, noMut)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// both homozygous: (1+p)²/4p²; no shared alleles: k0 = 1/4
+	if want := (1 + pa) * (1 + pa) / (4 * pa * pa); math.Abs(r.Loci[0].LR-want) > 1e-9 {
+		t.Fatalf("test 1: expected: %v, got: %v", want, r.Loci[0].LR)
+	}
+	if math.Abs(r.Loci[1].LR-0.25) > 1e-9 || math.Abs(r.LR-r.Loci[0].LR*0.25) > 1e-9 {
+		t.Fatalf("test 2: expected: 0.25, got: %v", r.Loci[1].LR)
+	}
+
+	r, err = HalfSiblingIndex(a, b, contFreqs, noMut)
+	if want := (1 + pa) / (2 * pa); err != nil || math.Abs(r.Loci[0].LR-want) > 1e-9 {
+		t.Fatalf("test 3: expected: %v, got: %v (%v)", want, r.Loci[0].LR, err)
+	}
+
+	// a null allele lowers the LR of two homozygous siblings
+	r2, err := FullSiblingIndex(a, b, contFreqs, KinshipConf{Model: NOMUTATION, NullProb: 0.01})
+	if err != nil || r2.Loci[0].LR >= (1+pa)*(1+pa)/(4*pa*pa) {
+		t.Fatalf("test 4: expected LR < %v, got: %v (%v)", (1+pa)*(1+pa)/(4*pa*pa), r2.Loci[0].LR, err)
+	}
+}
+
+// =============================================================================
+func Test_Pedigree_Validate(t *testing.T) {
+
+	tests := []Pedigree{
+		{Persons: []PedPerson{{ID: "F", Male: true}, {ID: "C", Father: "F"}}},
+		{Persons: []PedPerson{{ID: "F"}, {ID: "M"}, {ID: "C", Father: "F", Mother: "M"}}},
+		{Persons: []PedPerson{{ID: "F", Male: true}, {ID: "F"}}},
+		{Persons: []PedPerson{{ID: "F", Male: true, Father: "C", Mother: "M"}, {ID: "M"},
+			{ID: "C", Male: true, Father: "F", Mother: "M"}}},
+	}
+
+	for i, p := range tests {
+		if err := p.Validate(); err == nil {
+			t.Fatalf("test %d: expected error", i+1)
+		}
+	}
+	if err := Trio("C", "F", "M").Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
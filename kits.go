@@ -20,6 +20,8 @@ type Kit struct {
 type STR struct {
 	ID  string `json:"ID"`  // e.g. VWA
 	Dye string `json:"Dye"` // color of the dye
+	// Stutter holds the stutter ratios of the STR in the kit (optional).
+	Stutter []StutterRatio `json:"Stutter,omitempty"`
 }
 
 // TODO: fix the tests for this function
@@ -110,7 +112,7 @@ func readKitFile(f string) (Kit, error) {
 	// avoid vWA/VWA issues like in parser.go
 	var strs []STR
 	for _, str := range kit.STRs {
-		strs = append(strs, STR{strings.ToUpper(str.ID), str.Dye})
+		strs = append(strs, STR{strings.ToUpper(str.ID), str.Dye, str.Stutter})
 	}
 
 	return Kit{
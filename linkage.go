@@ -133,7 +133,7 @@ func LocusCoordinates(id string) GenomicCoordinates {
 		return GenomicCoordinates{Chr: -1}
 	case "DYS437":
 		return GenomicCoordinates{Chr: -1}
-	case "DYS385 a/b": // TODO: a/b will be upper letter, right? see NewLocus()
+	case "DYS385 a/b", "DYS385 A/B", "DYS385":
 		return GenomicCoordinates{Chr: -1}
 	case "DYS449":
 		return GenomicCoordinates{Chr: -1}
@@ -147,11 +147,31 @@ func LocusCoordinates(id string) GenomicCoordinates {
 		return GenomicCoordinates{Chr: -1}
 	case "DYS533":
 		return GenomicCoordinates{Chr: -1}
+	case "DYF399S1", "DYF403S1A", "DYF403S1B", "DYF404S1", "DYS526A", "DYS526B",
+		"DYS547", "DYS612", "DYS626":
+		return GenomicCoordinates{Chr: -1}
 	case "YINDEL": //TODO: check whether correct name
 		return GenomicCoordinates{Chr: -1}
-	case "DXS10101":
+	case "DXS10148", "DXS10135", "DXS8378", "DXS7132", "DXS10079", "DXS10074",
+		"DXS10103", "HPRTB", "DXS10101", "DXS10146", "DXS10134", "DXS7423":
 		return GenomicCoordinates{Chr: -2}
 	default:
 		return GenomicCoordinates{}
 	}
 }
+
+// Syntenic returns true if the autosomal loci with ids id1 and id2 are located
+// on the same chromosome, i.e. they may be linked (e.g. VWA and D12S391). It
+// also returns the distance of the loci in base pairs.
+func Syntenic(id1, id2 string) (bool, int) {
+	c1, c2 := LocusCoordinates(id1), LocusCoordinates(id2)
+	if c1.Chr <= 0 || c1.Chr != c2.Chr {
+		return false, 0
+	}
+
+	d := c1.Start - c2.Start
+	if d < 0 {
+		d = -d
+	}
+	return true, d
+}
@@ -18,15 +18,15 @@ func TestLocus_LocusCoordinates(t *testing.T) {
 
 	tests := []test{
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 			GenomicCoordinates{Chr: 4, Start: 155866000},
 		},
 		{
-			Locus{ID: "Noname", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "Noname", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 			GenomicCoordinates{},
 		},
 		{
-			Locus{ID: "DYS390", Alleles: []Allele{{ID: 12}}},
+			Locus{ID: "DYS390", Alleles: []Allele{{ID: A2ID("12")}}},
 			GenomicCoordinates{Chr: -1},
 		},
 	}
@@ -12,6 +12,10 @@ import (
 type Locus struct {
 	ID      string   // name of the locus (e.g. SE33)
 	Alleles []Allele // slice of all alleles at this locus
+	Dye     string   // dye of the marker as read from the Genemapper file
+	// PQVs holds the allele quality flags GeneMapper ID-X reports for the
+	// alleles at this locus (e.g. AN, ADO, AE).
+	PQVs PQVs
 }
 
 // NewLocus generates a Locus object with ID id. All IDs will be converted to
@@ -26,27 +30,38 @@ func NewLocus(id string) Locus {
 }
 
 // AddAllele adds an Allele a to Locus l and sorts the alleles by ID. It will
-// only add the allele if it has a non-zero ID.
+// only add the allele if it has a non-empty ID and if it is not already
+// present. Isoalleles (see Sequence) are kept distinct. Off-ladder alleles are
+// only considered present if an off-ladder allele of the same size is.
 func (l *Locus) AddAllele(a Allele) {
-	// Do not test for a.ID > 0 because X and Y are decoded as -2 und -1.
-	// Alleles must also be unique, except if they are something weird (-999).
-	if a.ID != 0 && (!l.HasAllele(a.ID) || a.ID == -999) {
+	if !a.ID.IsZero() && !l.hasSameAllele(a) {
 		l.Alleles = append(l.Alleles, a)
 		l.SortByID()
 	}
 }
 
+// hasSameAllele returns true if locus l holds an allele designating the same
+// allele as a (see sameAllele).
+func (l Locus) hasSameAllele(a Allele) bool {
+	for _, la := range l.Alleles {
+		if sameAllele(la, a) {
+			return true
+		}
+	}
+	return false
+}
+
 // RemoveAllele removes allele a from locus l. This does not chnage the order
 // of alleles, hence no sorting is required
 func (l *Locus) RemoveAllele(a Allele) {
 
-	if !l.HasAllele(a.ID) {
+	if !l.hasSameAllele(a) {
 		return
 	}
 
 	var newAlleles []Allele
 	for _, la := range l.Alleles {
-		if la.ID != a.ID {
+		if !sameAllele(la, a) {
 			newAlleles = append(newAlleles, la)
 		}
 	}
@@ -54,9 +69,21 @@ func (l *Locus) RemoveAllele(a Allele) {
 	l.Alleles = newAlleles
 }
 
+// WithoutOffLadder returns a copy of locus l without its off-ladder alleles.
+func (l Locus) WithoutOffLadder() Locus {
+	nl := l
+	nl.Alleles = nil
+	for _, a := range l.Alleles {
+		if !a.IsOffLadder() {
+			nl.Alleles = append(nl.Alleles, a)
+		}
+	}
+	return nl
+}
+
 // Allele returns the allele of name id. If no such allele is found it returns
 // an empty struct.
-func (l Locus) Allele(id float64) Allele {
+func (l Locus) Allele(id AlleleID) Allele {
 	for _, a := range l.Alleles {
 		if id == a.ID {
 			return a
@@ -67,14 +94,22 @@ func (l Locus) Allele(id float64) Allele {
 
 // HasAllele returns true if Locus l contains an Allele id. Otherwise it
 // returns false.
-func (l Locus) HasAllele(id float64) bool {
-	return l.Allele(id).ID != 0
+func (l Locus) HasAllele(id AlleleID) bool {
+	return !l.Allele(id).ID.IsZero()
 }
 
-// SortByID sorts locus l by allele id.
+// SortByID sorts locus l by allele id (see AlleleID.Less). Off-ladder alleles
+// are sorted by size, isoalleles by their repeat sequence.
 func (l Locus) SortByID() {
-	sort.Slice(l.Alleles, func(i, j int) bool {
-		return l.Alleles[i].ID < l.Alleles[j].ID
+	sort.SliceStable(l.Alleles, func(i, j int) bool {
+		ai, aj := l.Alleles[i], l.Alleles[j]
+		if ai.ID != aj.ID {
+			return ai.ID.Less(aj.ID)
+		}
+		if ai.Size != aj.Size {
+			return ai.Size < aj.Size
+		}
+		return ai.Seq.Repeat+ai.Seq.Bracket < aj.Seq.Repeat+aj.Seq.Bracket
 	})
 }
 
@@ -40,24 +40,34 @@ func TestLocus_AddAllele(t *testing.T) {
 
 	tests := []test{
 		{ // 1
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
-			Allele{ID: 21},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}, {ID: 21}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
+			Allele{ID: A2ID("21")},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}, {ID: A2ID("21")}}},
 		},
 		{ // 2
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 			Allele{},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 		},
 		{ // 3
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
-			Allele{ID: 9.3},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
+			Allele{ID: A2ID("9.3")},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 		},
-		{ // 4
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3, Height: 100}, {ID: -999, Height: 100}}},
-			Allele{ID: -999, Height: 734},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: -999, Height: 100}, {ID: -999, Height: 734}, {ID: 9.3, Height: 100}}},
+		{ // 4: off-ladder alleles of the same size are the same allele
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3"), Height: 100}, {ID: A2ID("OL"), Height: 100}}},
+			Allele{ID: A2ID("OL"), Height: 734},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3"), Height: 100}, {ID: A2ID("OL"), Height: 100}}},
+		},
+		{ // 5: off-ladder alleles of different sizes are sorted by size
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3"), Height: 100}, {ID: A2ID("OL"), Height: 100, Size: 220.4}}},
+			Allele{ID: A2ID("OL"), Height: 734, Size: 201.2},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3"), Height: 100}, {ID: A2ID("OL"), Height: 734, Size: 201.2}, {ID: A2ID("OL"), Height: 100, Size: 220.4}}},
+		},
+		{ // 6: designations are ordered by repeat number
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID(">24")}}},
+			Allele{ID: A2ID("<8")},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("<8")}, {ID: A2ID("9.3")}, {ID: A2ID(">24")}}},
 		},
 	}
 
@@ -80,24 +90,24 @@ func TestLocus_RemoveAllele(t *testing.T) {
 
 	tests := []test{
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
-			Allele{ID: 12},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
+			Allele{ID: A2ID("12")},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}}},
 		},
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 			Allele{},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 		},
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
-			Allele{ID: 9.3},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 12}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
+			Allele{ID: A2ID("9.3")},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("12")}}},
 		},
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3, Height: 100}, {ID: -999, Height: 100}}},
-			Allele{ID: -999, Height: 734},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3, Height: 100}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3"), Height: 100}, {ID: A2ID("OL"), Height: 100}}},
+			Allele{ID: A2ID("OL"), Height: 734},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3"), Height: 100}}},
 		},
 	}
 
@@ -114,19 +124,19 @@ func TestLocus_Allele(t *testing.T) {
 
 	type test struct {
 		inLocus Locus
-		inID    float64
+		inID    AlleleID
 		want    Allele
 	}
 
 	tests := []test{
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9, Height: 928}, {ID: 12, Height: 212}}},
-			9,
-			Allele{ID: 9, Height: 928},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
+			A2ID("9"),
+			Allele{ID: A2ID("9"), Height: 928},
 		},
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9, Height: 928}, {ID: 12, Height: 212}}},
-			21,
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
+			A2ID("21"),
 			Allele{},
 		},
 	}
@@ -144,24 +154,24 @@ func TestLocus_HasAllele(t *testing.T) {
 
 	type test struct {
 		inLocus Locus
-		inID    float64
+		inID    AlleleID
 		want    bool
 	}
 
 	tests := []test{
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9, Height: 928}, {ID: 12, Height: 212}}},
-			9,
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
+			A2ID("9"),
 			true,
 		},
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9, Height: 928}, {ID: 12, Height: 212}}},
-			21,
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
+			A2ID("21"),
 			false,
 		},
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9, Height: 928}, {ID: 12, Height: 212}}},
-			0,
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
+			A2ID(""),
 			false,
 		},
 	}
@@ -184,19 +194,19 @@ func TestLocus_linkage(t *testing.T) {
 
 	tests := []test{
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 			AUTOSOMAL,
 		},
 		{
-			Locus{ID: "DYS635", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "DYS635", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 			YLINKED,
 		},
 		{
-			Locus{ID: "DXS10101", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "DXS10101", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 			XLINKED,
 		},
 		{
-			Locus{ID: "Noname", Alleles: []Allele{{ID: 9.3}, {ID: 12}}},
+			Locus{ID: "Noname", Alleles: []Allele{{ID: A2ID("9.3")}, {ID: A2ID("12")}}},
 			AUTOSOMAL,
 		},
 	}
@@ -219,12 +229,12 @@ func TestLocus_SortByID(t *testing.T) {
 
 	tests := []test{
 		{ // 1
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 19, Height: 928}, {ID: 12, Height: 212}}},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 12, Height: 212}, {ID: 19, Height: 928}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("19"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("12"), Height: 212}, {ID: A2ID("19"), Height: 928}}},
 		},
 		{ // 2
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 19, Height: 928}, {ID: 7, Height: 2742}, {ID: 12, Height: 212}}},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 7, Height: 2742}, {ID: 12, Height: 212}, {ID: 19, Height: 928}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("19"), Height: 928}, {ID: A2ID("7"), Height: 2742}, {ID: A2ID("12"), Height: 212}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("7"), Height: 2742}, {ID: A2ID("12"), Height: 212}, {ID: A2ID("19"), Height: 928}}},
 		},
 	}
 
@@ -246,12 +256,12 @@ func TestLocus_SortByHeight(t *testing.T) {
 
 	tests := []test{
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 19, Height: 928}, {ID: 12, Height: 212}}},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 19, Height: 928}, {ID: 12, Height: 212}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("19"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("19"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
 		},
 		{
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 19, Height: 928}, {ID: 7, Height: 2742}, {ID: 12, Height: 212}}},
-			Locus{ID: "FGA", Alleles: []Allele{{ID: 7, Height: 2742}, {ID: 19, Height: 928}, {ID: 12, Height: 212}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("19"), Height: 928}, {ID: A2ID("7"), Height: 2742}, {ID: A2ID("12"), Height: 212}}},
+			Locus{ID: "FGA", Alleles: []Allele{{ID: A2ID("7"), Height: 2742}, {ID: A2ID("19"), Height: 928}, {ID: A2ID("12"), Height: 212}}},
 		},
 	}
 
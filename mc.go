@@ -28,18 +28,27 @@ func (s Sample) HasMajorComponent(heteroImbalance, majorCompRatio, minHomozygous
 
 // MajorComponent attempts to infer a major component at locus l based on the
 // given parameters. If no major component is found, it returns a Locus object
-// with the same ID as l but without any alleles.
+// with the same ID as l but without any alleles. Off-ladder peaks are not
+// considered and loci flagged as low quality never yield a major component.
+// Loci flagged with possible drop-out (see ApplyThresholds) never yield a
+// homozygous major component.
 func (l Locus) MajorComponent(heteroImbalance, majorCompRatio, minHomozygous, weakSignal float64) Locus {
 
 	if l.ID == "IQCS" || l.ID == "IQCL" { // TODO: maybe get list of alleles from file kits.go?
 		return NewLocus(l.ID)
 	}
 
+	if l.IsLowQuality() {
+		return NewLocus(l.ID)
+	}
+	l = l.WithoutOffLadder()
+	dropOut := l.PossibleDropOut()
+
 	if len(l.Alleles) < 2 {
 		// The single allele is large enough for homozygous MC.
 		// Note: GO guarantees that the first condition is
 		// evaluated before the second one.
-		if len(l.Alleles) == 1 &&
+		if len(l.Alleles) == 1 && !dropOut &&
 			l.Alleles[0].Height >= minHomozygous {
 			return l
 		}
@@ -58,6 +67,9 @@ func (l Locus) MajorComponent(heteroImbalance, majorCompRatio, minHomozygous, we
 	// First allele is distinct and strong enough for homozygous MC.
 	if a1.Height/a2.Height > majorCompRatio &&
 		a1.Height > minHomozygous {
+		if dropOut {
+			return mcLoc
+		}
 		mcLoc.AddAllele(a1)
 		return mcLoc
 	}
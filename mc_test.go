@@ -19,37 +19,51 @@ func Test_mcLocus(t *testing.T) {
 	tests := []test{
 		{ // 1
 			Locus{ID: "SE33", Alleles: []Allele{
-				{ID: 9.2, Height: 820},
-				{ID: 18, Height: 998},
-				{ID: 23, Height: 7623}}},
+				{ID: A2ID("9.2"), Height: 820},
+				{ID: A2ID("18"), Height: 998},
+				{ID: A2ID("23"), Height: 7623}}},
 			Locus{ID: "SE33", Alleles: []Allele{
-				{ID: 23, Height: 7623}}},
+				{ID: A2ID("23"), Height: 7623}}},
 		},
 		{ // 2
 			Locus{ID: "SE33", Alleles: []Allele{
-				{ID: 23, Height: 7623}}},
+				{ID: A2ID("23"), Height: 7623}}},
 			Locus{ID: "SE33", Alleles: []Allele{
-				{ID: 23, Height: 7623}}},
+				{ID: A2ID("23"), Height: 7623}}},
 		},
 		{ // 3
 			Locus{ID: "IQCS", Alleles: []Allele{
-				{ID: 9.2, Height: 820}}},
+				{ID: A2ID("9.2"), Height: 820}}},
 			Locus{ID: "IQCS"},
 		},
 		{ // 4
 			Locus{ID: "SE33", Alleles: []Allele{
-				{ID: 9.2, Height: 8020},
-				{ID: 18, Height: 998},
-				{ID: 23, Height: 7623}}},
+				{ID: A2ID("9.2"), Height: 8020},
+				{ID: A2ID("18"), Height: 998},
+				{ID: A2ID("23"), Height: 7623}}},
 			Locus{ID: "SE33", Alleles: []Allele{
-				{ID: 9.2, Height: 8020},
-				{ID: 23, Height: 7623}}},
+				{ID: A2ID("9.2"), Height: 8020},
+				{ID: A2ID("23"), Height: 7623}}},
 		},
 		{ // 5
 			Locus{ID: "SE33", Alleles: []Allele{
-				{ID: 9.2, Height: 8020},
-				{ID: 18, Height: 9098},
-				{ID: 23, Height: 7623}}},
+				{ID: A2ID("9.2"), Height: 8020},
+				{ID: A2ID("18"), Height: 9098},
+				{ID: A2ID("23"), Height: 7623}}},
+			Locus{ID: "SE33"},
+		},
+		{ // 6: off-ladder peaks are ignored
+			Locus{ID: "SE33", Alleles: []Allele{
+				{ID: A2ID("OL"), Height: 9500, Size: 350.1},
+				{ID: A2ID("18"), Height: 998},
+				{ID: A2ID("23"), Height: 7623}}},
+			Locus{ID: "SE33", Alleles: []Allele{
+				{ID: A2ID("23"), Height: 7623}}},
+		},
+		{ // 7: low quality loci yield no major component
+			Locus{ID: "SE33", Alleles: []Allele{
+				{ID: A2ID("23"), Height: 7623}},
+				PQVs: PQVs{"ADO": LOWQUALITY}},
 			Locus{ID: "SE33"},
 		},
 	}
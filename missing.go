@@ -8,6 +8,13 @@ import "strings"
 // MissingFrom infers loci and alleles of person p that are missing from sample
 // s and returns them as sample. It only considers loci from sample s.
 func (p Sample) MissingFrom(s Sample) Sample {
+	return p.MissingFromMode(s, LENGTH)
+}
+
+// MissingFromMode is MissingFrom with alleles compared under match mode mode.
+// In SEQUENCE mode an isoallele of p is missing unless s has the same
+// sequence.
+func (p Sample) MissingFromMode(s Sample, mode MatchMode) Sample {
 
 	m := Sample{
 		ID: strings.Join([]string{"Missing", p.ID, "from", s.ID}, "_"),
@@ -31,7 +38,7 @@ func (p Sample) MissingFrom(s Sample) Sample {
 		// them.
 		missLoc := NewLocus(sl.ID)
 		for _, pa := range p.Locus(sl.ID).Alleles {
-			if !sl.HasAllele(pa.ID) {
+			if !sl.HasAlleleMode(pa, mode) {
 				missLoc.AddAllele(pa)
 			}
 		}
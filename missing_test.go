@@ -21,17 +21,17 @@ func TestSample_MissingFrom_MissingFromInt(t *testing.T) {
 	tests := []test{
 		{
 			Sample{ID: "person", Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "VWA", Alleles: []Allele{{ID: 9}, {ID: 11}}},
-				{ID: "TH01", Alleles: []Allele{{ID: 17}, {ID: 17.2}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "VWA", Alleles: []Allele{{ID: A2ID("9")}, {ID: A2ID("11")}}},
+				{ID: "TH01", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("17.2")}}},
 			}},
 			Sample{ID: "stain", Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}, {ID: 29.3}, {ID: 31}}},
-				{ID: "VWA", Alleles: []Allele{{ID: 7}, {ID: 11}, {ID: 17}}},
-				{ID: "Penta", Alleles: []Allele{{ID: 4}, {ID: 7.1}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}, {ID: A2ID("29.3")}, {ID: A2ID("31")}}},
+				{ID: "VWA", Alleles: []Allele{{ID: A2ID("7")}, {ID: A2ID("11")}, {ID: A2ID("17")}}},
+				{ID: "Penta", Alleles: []Allele{{ID: A2ID("4")}, {ID: A2ID("7.1")}}},
 			}},
 			Sample{ID: "Missing_person_from_stain", Loci: []Locus{
-				{ID: "VWA", Alleles: []Allele{{ID: 9}}},
+				{ID: "VWA", Alleles: []Allele{{ID: A2ID("9")}}},
 			}},
 			1,
 		},
@@ -49,3 +49,25 @@ func TestSample_MissingFrom_MissingFromInt(t *testing.T) {
 		}
 	}
 }
+
+// =============================================================================
+func TestSample_MissingFromMode(t *testing.T) {
+
+	person := Sample{ID: "person", Loci: []Locus{
+		{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")}, d12iso1}},
+	}}
+	stain := Sample{ID: "stain", Loci: []Locus{
+		{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")}, d12iso2, {ID: A2ID("20")}}},
+	}}
+
+	if res := person.MissingFromMode(stain, LENGTH); len(res.Loci) != 0 {
+		t.Fatalf("test 1 (length): expected no missing alleles, got: %v", res)
+	}
+
+	want := Sample{ID: "Missing_person_from_stain", Loci: []Locus{
+		{ID: "D12S391", Alleles: []Allele{d12iso1}},
+	}}
+	if res := person.MissingFromMode(stain, SEQUENCE); !reflect.DeepEqual(want, res) {
+		t.Fatalf("test 2 (sequence): expected: %v, got: %v", want, res)
+	}
+}
@@ -0,0 +1,509 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MixtureConf holds the parameters of the estimation of the mixture
+// proportions of a sample.
+type MixtureConf struct {
+	Contributors int     // number of contributors, 2 or 3
+	Step         float64 // resolution of the mixture proportions
+	Bootstrap    int     // number of bootstrap samples of the loci
+	Level        float64 // level of the confidence intervals
+	// Tolerance is the largest root mean square difference of the observed
+	// and expected peak height proportions of a locus consistent with the
+	// mixture proportions.
+	Tolerance float64
+	Seed      int64 // seed of the bootstrap
+	// Known are the profiles of assumed contributors. They are the first
+	// contributors, and their genotypes condition the fit.
+	Known []Sample
+}
+
+// DefaultMixtureConf holds the default parameters of the mixture estimation.
+var DefaultMixtureConf = MixtureConf{
+	Contributors: 2,
+	Step:         0.01,
+	Bootstrap:    1000,
+	Level:        0.95,
+	Tolerance:    0.1,
+}
+
+// withDefaults returns c with zero values replaced by the defaults.
+func (c MixtureConf) withDefaults() MixtureConf {
+	if c.Contributors == 0 {
+		c.Contributors = DefaultMixtureConf.Contributors
+	}
+	if c.Step == 0 {
+		c.Step = DefaultMixtureConf.Step
+	}
+	if c.Bootstrap == 0 {
+		c.Bootstrap = DefaultMixtureConf.Bootstrap
+	}
+	if c.Level == 0 {
+		c.Level = DefaultMixtureConf.Level
+	}
+	if c.Tolerance == 0 {
+		c.Tolerance = DefaultMixtureConf.Tolerance
+	}
+	return c
+}
+
+// LocusMixture holds the fit of the mixture proportions at a locus.
+type LocusMixture struct {
+	Locus string
+	// Mixture are the proportions fitting the locus best. They are not unique
+	// if the alleles of all contributors are shared.
+	Mixture []float64
+	// Genotypes are the genotypes of the contributors fitting the locus best
+	// for the mixture proportions of the sample.
+	Genotypes  [][2]AlleleID
+	Residual   float64 // root mean square difference of the peak height proportions
+	Consistent bool    // Residual is at most MixtureConf.Tolerance
+}
+
+// MixtureEstimate holds the estimated mixture proportions of a sample.
+type MixtureEstimate struct {
+	Contributors int
+	// Mixture are the proportions of the contributors: first the known
+	// contributors in the order of MixtureConf.Known, then the unknowns in
+	// decreasing order.
+	Mixture []float64
+	Lower   []float64 // lower limits of the confidence intervals
+	Upper   []float64 // upper limits of the confidence intervals
+	Loci    []LocusMixture
+}
+
+// EstimateMixture estimates the mixture proportions of the c.Contributors
+// contributors of sample s from the peak heights of its autosomal loci. At
+// each locus the observed proportions of the peak heights are compared to
+// the proportions expected for all combinations of genotypes of the
+// contributors explaining the alleles of the locus (no drop-out, no
+// drop-in). The mixture proportions minimize the sum of the squared
+// differences of the best genotype combinations of all loci; they are
+// refitted once to the loci consistent with the first fit. The confidence
+// intervals are bootstrap percentile intervals from c.Bootstrap resamples of
+// the consistent loci. Loci with off-ladder peaks only, low quality loci and
+// loci with more than two alleles per contributor are not used. With known
+// contributors c.Known only the genotype combinations containing their
+// genotypes are considered, and loci at which an allele of a known
+// contributor is missing are not used either.
+func (s Sample) EstimateMixture(c MixtureConf) (MixtureEstimate, error) {
+
+	c = c.withDefaults()
+	switch {
+	case c.Contributors < 2 || c.Contributors > 3:
+		return MixtureEstimate{}, fmt.Errorf("%v contributors not supported", c.Contributors)
+	case c.Step <= 0 || c.Step > 0.1:
+		return MixtureEstimate{}, fmt.Errorf("step %v not in (0, 0.1]", c.Step)
+	case c.Level <= 0 || c.Level >= 1:
+		return MixtureEstimate{}, fmt.Errorf("confidence level %v not in (0, 1)", c.Level)
+	case c.Bootstrap < 1 || c.Tolerance < 0:
+		return MixtureEstimate{}, fmt.Errorf("%v bootstrap samples or negative tolerance %v", c.Bootstrap, c.Tolerance)
+	case len(c.Known) >= c.Contributors:
+		return MixtureEstimate{}, fmt.Errorf("%v known of %v contributors", len(c.Known), c.Contributors)
+	}
+
+	grid := mixtureGrid(c.Contributors, len(c.Known), c.Step)
+
+	var loci []ratioLocus
+	for _, l := range s.Loci {
+		rl, ok := newRatioLocus(l, c.Contributors, c.Known)
+		if !ok {
+			continue
+		}
+		rl.fit(grid)
+		loci = append(loci, rl)
+	}
+	if len(loci) == 0 {
+		return MixtureEstimate{}, fmt.Errorf("sample %v: no loci with peak heights", s.ID)
+	}
+
+	// argmin returns the grid point with the smallest sum of residuals of
+	// loci idx
+	argmin := func(idx []int) int {
+		best, bestSum := 0, math.Inf(1)
+		for g := range grid {
+			var sum float64
+			for _, i := range idx {
+				sum += loci[i].residuals[g]
+			}
+			if sum < bestSum-1e-12 {
+				best, bestSum = g, sum
+			}
+		}
+		return best
+	}
+
+	// rms returns the root mean square residual of locus i at grid point g
+	rms := func(i, g int) float64 {
+		return math.Sqrt(loci[i].residuals[g] / float64(len(loci[i].alleles)))
+	}
+
+	all := make([]int, len(loci))
+	for i := range all {
+		all[i] = i
+	}
+	g := argmin(all)
+
+	// refit without the inconsistent loci
+	var consistent []int
+	for i := range loci {
+		if rms(i, g) <= c.Tolerance {
+			consistent = append(consistent, i)
+		}
+	}
+	if len(consistent) > 0 && len(consistent) < len(loci) {
+		g = argmin(consistent)
+	} else {
+		consistent = all
+	}
+	r := MixtureEstimate{Contributors: c.Contributors, Mixture: grid[g]}
+
+	// bootstrap percentile intervals
+	rng := rand.New(rand.NewSource(c.Seed))
+	boot := make([][]float64, c.Contributors)
+	idx := make([]int, len(consistent))
+	for b := 0; b < c.Bootstrap; b++ {
+		for i := range idx {
+			idx[i] = consistent[rng.Intn(len(consistent))]
+		}
+		for k, x := range grid[argmin(idx)] {
+			boot[k] = append(boot[k], x)
+		}
+	}
+	alpha := (1 - c.Level) / 2
+	for k := range boot {
+		sort.Float64s(boot[k])
+		lo := int(math.Floor(alpha * float64(len(boot[k])-1)))
+		hi := int(math.Ceil((1 - alpha) * float64(len(boot[k])-1)))
+		r.Lower = append(r.Lower, boot[k][lo])
+		r.Upper = append(r.Upper, boot[k][hi])
+	}
+
+	for i, rl := range loci {
+		lm := LocusMixture{
+			Locus:    rl.id,
+			Mixture:  grid[argminFloat(rl.residuals)],
+			Residual: rms(i, g),
+		}
+		lm.Consistent = lm.Residual <= c.Tolerance
+		for _, gt := range rl.combos[rl.best[g]] {
+			lm.Genotypes = append(lm.Genotypes, orderedGenotype(rl.alleles[gt[0]], rl.alleles[gt[1]]))
+		}
+		r.Loci = append(r.Loci, lm)
+	}
+
+	return r, nil
+}
+
+// MixtureMajorComponent returns the genotypes of the major contributor of
+// sample s at the loci consistent with the mixture proportions estimated by
+// EstimateMixture. The sample has no major component if the confidence
+// intervals of the proportions of the two largest contributors overlap. Known
+// contributors c.Known are ignored.
+func (s Sample) MixtureMajorComponent(c MixtureConf) (Sample, error) {
+
+	c.Known = nil
+	m, err := s.EstimateMixture(c)
+	if err != nil {
+		return Sample{}, err
+	}
+
+	mcSamp := NewSample("MC::", s.Source)
+	if m.Lower[0] <= m.Upper[1] {
+		mcSamp.UnknownKit()
+		return mcSamp, nil
+	}
+	for _, lm := range m.Loci {
+		if !lm.Consistent {
+			continue
+		}
+		l := s.Locus(lm.Locus)
+		mcLoc := NewLocus(lm.Locus)
+		for i, id := range lm.Genotypes[0] {
+			if i == 0 || id != lm.Genotypes[0][0] {
+				mcLoc.AddAllele(l.Allele(id))
+			}
+		}
+		mcSamp.AddLocus(mcLoc)
+	}
+
+	mcSamp.UnknownKit()
+	return mcSamp, nil
+}
+
+// SubtractKnown returns sample s with the expected peak heights of the known
+// contributors known subtracted, for the mixture proportions estimated by
+// EstimateMixture with these known contributors. Unlike
+// RemovePersonFromSample, alleles shared with the known contributors are
+// kept with their remaining peak heights; only alleles without remaining
+// peak height are removed. Loci not used for the estimation or inconsistent
+// with the mixture proportions are kept unchanged.
+func (s Sample) SubtractKnown(known []Sample, c MixtureConf) (Sample, MixtureEstimate, error) {
+
+	c.Known = known
+	m, err := s.EstimateMixture(c)
+	if err != nil {
+		return Sample{}, MixtureEstimate{}, err
+	}
+
+	var ids []string
+	for _, k := range known {
+		ids = append(ids, k.ID)
+	}
+	ns := NewSample(s.ID, s.Source+"::SUB:"+strings.Join(ids, ","))
+
+	for _, l := range s.Loci {
+		rl, ok := newRatioLocus(l, m.Contributors, known)
+		if !ok || !slices.ContainsFunc(m.Loci, func(lm LocusMixture) bool { return lm.Locus == l.ID && lm.Consistent }) {
+			ns.AddLocus(l)
+			continue
+		}
+
+		// expected peak heights of the known contributors
+		sub := make(map[AlleleID]float64)
+		for k := range known {
+			gt, ok := refGenotype(known[k].Locus(l.ID))
+			if !ok {
+				continue
+			}
+			sub[gt[0]] += rl.total * m.Mixture[k] / 2
+			sub[gt[1]] += rl.total * m.Mixture[k] / 2
+		}
+
+		nl := NewLocus(l.ID)
+		nl.Dye, nl.PQVs = l.Dye, l.PQVs
+		for _, a := range l.Alleles {
+			if x, ok := sub[a.ID]; ok && a.ID.IsTyped() {
+				a.Height -= x
+				sub[a.ID] = math.Max(0, -a.Height)
+				if a.Height <= 0 {
+					continue
+				}
+			}
+			nl.AddAllele(a)
+		}
+		if len(nl.Alleles) > 0 {
+			ns.AddLocus(nl)
+		}
+	}
+
+	return ns, m, nil
+}
+
+// ratioLocus holds the peak height proportions of a locus and the genotype
+// combinations explaining them.
+type ratioLocus struct {
+	id      string
+	alleles []AlleleID
+	obs     []float64 // observed peak height proportions of the alleles
+	total   float64   // sum of the peak heights
+	combos  [][][2]int
+	// residuals and indices of the best genotype combinations at the points
+	// of the mixture grid
+	residuals []float64
+	best      []int
+}
+
+// newRatioLocus returns the peak height proportions of locus l for n
+// contributors, the first of which are the known contributors known. It
+// returns false if l cannot be used.
+func newRatioLocus(l Locus, n int, known []Sample) (ratioLocus, bool) {
+
+	if l.Linkage() != AUTOSOMAL || l.IsLowQuality() {
+		return ratioLocus{}, false
+	}
+
+	rl := ratioLocus{id: l.ID}
+	var sum float64
+	for _, a := range l.WithoutOffLadder().Alleles {
+		if !a.ID.IsTyped() || a.Height <= 0 {
+			continue
+		}
+		i := 0
+		for i < len(rl.alleles) && rl.alleles[i] != a.ID {
+			i++
+		}
+		if i == len(rl.alleles) {
+			rl.alleles = append(rl.alleles, a.ID)
+			rl.obs = append(rl.obs, 0)
+		}
+		rl.obs[i] += a.Height
+		sum += a.Height
+	}
+	if len(rl.alleles) == 0 || len(rl.alleles) > 2*n {
+		return ratioLocus{}, false
+	}
+	for i := range rl.obs {
+		rl.obs[i] /= sum
+	}
+	rl.total = sum
+
+	// the genotypes of the known contributors as allele indices
+	var fixed [][2]int
+	for _, k := range known {
+		gt, ok := refGenotype(k.Locus(l.ID))
+		if !ok {
+			fixed = append(fixed, [2]int{-1, -1})
+			continue
+		}
+		i, j := slices.Index(rl.alleles, gt[0]), slices.Index(rl.alleles, gt[1])
+		if i < 0 || j < 0 {
+			return ratioLocus{}, false
+		}
+		fixed = append(fixed, [2]int{min(i, j), max(i, j)})
+	}
+
+	for _, combo := range genotypeCombinations(len(rl.alleles), n) {
+		ok := true
+		for k, gt := range fixed {
+			if gt[0] >= 0 && combo[k] != gt {
+				ok = false
+			}
+		}
+		if ok {
+			rl.combos = append(rl.combos, combo)
+		}
+	}
+	if len(rl.combos) == 0 {
+		return ratioLocus{}, false
+	}
+	return rl, true
+}
+
+// fit computes the residuals of the best genotype combinations at all points
+// of grid.
+func (rl *ratioLocus) fit(grid [][]float64) {
+	rl.residuals = make([]float64, len(grid))
+	rl.best = make([]int, len(grid))
+	for g, phi := range grid {
+		rl.residuals[g] = math.Inf(1)
+		for i, combo := range rl.combos {
+			if r := rl.residual(combo, phi); r < rl.residuals[g]-1e-12 {
+				rl.residuals[g], rl.best[g] = r, i
+			}
+		}
+	}
+}
+
+// residual returns the sum of the squared differences of the observed and
+// expected peak height proportions of the genotype combination combo for
+// mixture proportions phi.
+func (rl ratioLocus) residual(combo [][2]int, phi []float64) float64 {
+	exp := rl.expected(combo, phi)
+	var r float64
+	for i := range exp {
+		r += (rl.obs[i] - exp[i]) * (rl.obs[i] - exp[i])
+	}
+	return r
+}
+
+// expected returns the expected peak height proportions of the alleles for
+// the genotype combination combo and mixture proportions phi.
+func (rl ratioLocus) expected(combo [][2]int, phi []float64) []float64 {
+	exp := make([]float64, len(rl.alleles))
+	for k, gt := range combo {
+		exp[gt[0]] += phi[k] / 2
+		exp[gt[1]] += phi[k] / 2
+	}
+	return exp
+}
+
+// genotypeCombinations returns all combinations of the genotypes of n
+// contributors made of alleles 0..na-1 that contain every allele. The
+// genotypes are pairs of allele indices with the smaller index first.
+func genotypeCombinations(na, n int) [][][2]int {
+
+	var genotypes [][2]int
+	for i := 0; i < na; i++ {
+		for j := i; j < na; j++ {
+			genotypes = append(genotypes, [2]int{i, j})
+		}
+	}
+
+	var r [][][2]int
+	combo := make([][2]int, n)
+	var rec func(k int)
+	rec = func(k int) {
+		if k == n {
+			seen := make([]bool, na)
+			for _, gt := range combo {
+				seen[gt[0]], seen[gt[1]] = true, true
+			}
+			for _, ok := range seen {
+				if !ok {
+					return
+				}
+			}
+			r = append(r, append([][2]int(nil), combo...))
+			return
+		}
+		for _, gt := range genotypes {
+			combo[k] = gt
+			rec(k + 1)
+		}
+	}
+	rec(0)
+
+	return r
+}
+
+// mixtureGrid returns all mixture proportions of n contributors that are
+// positive multiples of step. The proportions of the nk known contributors
+// come first in any order, followed by those of the unknowns in decreasing
+// order.
+func mixtureGrid(n, nk int, step float64) [][]float64 {
+
+	m := int(math.Round(1 / step))
+	var r [][]float64
+	parts := make([]int, n)
+	var rec func(k, rest, maxPart int)
+	rec = func(k, rest, maxPart int) {
+		if k < nk {
+			for p := rest - (n - 1 - k); p >= 1; p-- {
+				parts[k] = p
+				rec(k+1, rest-p, m)
+			}
+			return
+		}
+		if k == n-1 {
+			if rest >= 1 && rest <= maxPart {
+				parts[k] = rest
+				phi := make([]float64, n)
+				for i, p := range parts {
+					phi[i] = float64(p) / float64(m)
+				}
+				r = append(r, phi)
+			}
+			return
+		}
+		for p := min(maxPart, rest-(n-1-k)); p >= 1; p-- {
+			parts[k] = p
+			rec(k+1, rest-p, p)
+		}
+	}
+	rec(0, m, m)
+
+	return r
+}
+
+// argminFloat returns the index of the smallest value of x.
+func argminFloat(x []float64) int {
+	best := 0
+	for i := range x {
+		if x[i] < x[best]-1e-12 {
+			best = i
+		}
+	}
+	return best
+}
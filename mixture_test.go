@@ -0,0 +1,190 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// mixStain is a 3:1 mixture of the persons 12,14;15,16;9,9.3;11,13;20,21 and
+// 13,13;17,18;6,9.3;11,11;22,24 with a discordant locus FGA.
+var mixStain = peaks("stain",
+	"VWA:12:760", "VWA:13:490", "VWA:14:740",
+	"D3S1358:15:740", "D3S1358:16:760", "D3S1358:17:240", "D3S1358:18:260",
+	"TH01:6:260", "TH01:9:750", "TH01:9.3:1000",
+	"D8S1179:11:1240", "D8S1179:13:760",
+	"D21S11:20:750", "D21S11:21:740", "D21S11:22:245", "D21S11:24:255",
+	"FGA:20:500", "FGA:21:500", "FGA:22:500", "FGA:23:500",
+)
+
+// =============================================================================
+func TestSample_EstimateMixture(t *testing.T) {
+
+	m, err := mixStain.EstimateMixture(MixtureConf{Seed: 1, Bootstrap: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(m.Mixture[0]-0.75) > 0.02 || math.Abs(m.Mixture[1]-0.25) > 0.02 {
+		t.Fatalf("expected: [0.75 0.25], got: %v", m.Mixture)
+	}
+	for k := range m.Mixture {
+		if m.Lower[k] > m.Mixture[k] || m.Upper[k] < m.Mixture[k] {
+			t.Fatalf("expected: %v in [%v, %v]", m.Mixture[k], m.Lower[k], m.Upper[k])
+		}
+	}
+
+	want := map[string][2][2]AlleleID{
+		"VWA":     {{A2ID("12"), A2ID("14")}, {A2ID("13"), A2ID("13")}},
+		"D3S1358": {{A2ID("15"), A2ID("16")}, {A2ID("17"), A2ID("18")}},
+		"TH01":    {{A2ID("9"), A2ID("9.3")}, {A2ID("6"), A2ID("9.3")}},
+		"D8S1179": {{A2ID("11"), A2ID("13")}, {A2ID("11"), A2ID("11")}},
+		"D21S11":  {{A2ID("20"), A2ID("21")}, {A2ID("22"), A2ID("24")}},
+	}
+	for _, lm := range m.Loci {
+		if lm.Locus == "FGA" {
+			if lm.Consistent {
+				t.Fatalf("expected: FGA inconsistent, got: residual %v", lm.Residual)
+			}
+			continue
+		}
+		if !lm.Consistent {
+			t.Fatalf("%v: expected: consistent, got: residual %v", lm.Locus, lm.Residual)
+		}
+		if got := [2][2]AlleleID{lm.Genotypes[0], lm.Genotypes[1]}; got != want[lm.Locus] {
+			t.Fatalf("%v: expected: %v, got: %v", lm.Locus, want[lm.Locus], got)
+		}
+	}
+
+	// the bootstrap is reproducible
+	m2, _ := mixStain.EstimateMixture(MixtureConf{Seed: 1, Bootstrap: 200})
+	if !reflect.DeepEqual(m, m2) {
+		t.Fatalf("expected: equal estimates for the same seed")
+	}
+
+	for _, c := range []MixtureConf{{Contributors: 4}, {Step: 0.5}, {Level: 1}, {Bootstrap: -1}, {Tolerance: -0.1}} {
+		if _, err := mixStain.EstimateMixture(c); err == nil {
+			t.Fatalf("expected: error for %v", c)
+		}
+	}
+}
+
+// =============================================================================
+func TestSample_MixtureMajorComponent(t *testing.T) {
+
+	mc, err := mixStain.MixtureMajorComponent(MixtureConf{Seed: 1, Bootstrap: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mc.Loci) != 5 || mc.HasLocus("FGA") {
+		t.Fatalf("expected: 5 loci without FGA, got: %v", mc.Loci)
+	}
+	if l := mc.Locus("D8S1179"); len(l.Alleles) != 2 || l.Alleles[0].Height != 1240 {
+		t.Fatalf("expected: D8S1179 11,13 with heights, got: %v", l)
+	}
+
+	// a balanced mixture has no major component
+	even := peaks("even", "VWA:12:500", "VWA:13:510", "VWA:14:490", "VWA:15:500",
+		"TH01:6:505", "TH01:7:495", "TH01:8:500", "TH01:9:500")
+	mc, err = even.MixtureMajorComponent(MixtureConf{Bootstrap: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mc.Loci) != 0 {
+		t.Fatalf("expected: no major component, got: %v", mc.Loci)
+	}
+}
+
+// =============================================================================
+func Test_genotypeCombinations(t *testing.T) {
+
+	type test struct {
+		na, n, want int
+	}
+
+	tests := []test{
+		{1, 2, 1},
+		{2, 2, 7},  // 3² combinations minus 11/11 and 22/22
+		{4, 2, 6},  // disjoint heterozygotes, ordered
+		{6, 3, 90}, // 6!/2³
+		{5, 2, 0},
+	}
+
+	for i, tt := range tests {
+		if got := len(genotypeCombinations(tt.na, tt.n)); got != tt.want {
+			t.Fatalf("test %d: expected: %v, got: %v", i, tt.want, got)
+		}
+	}
+}
+
+// =============================================================================
+func Test_mixtureGrid(t *testing.T) {
+
+	type test struct {
+		n, nk int
+		want  int
+	}
+
+	tests := []test{
+		{2, 0, 5}, // 0.9/0.1 .. 0.5/0.5
+		{3, 0, 8},
+		{2, 1, 9}, // the known contributor may be the minor one
+		{3, 1, 20},
+	}
+
+	for i, tt := range tests {
+		grid := mixtureGrid(tt.n, tt.nk, 0.1)
+		if len(grid) != tt.want {
+			t.Fatalf("test %d: expected: %v, got: %v", i, tt.want, len(grid))
+		}
+		for _, phi := range grid {
+			var sum float64
+			for k, x := range phi {
+				sum += x
+				if k > tt.nk && x > phi[k-1] {
+					t.Fatalf("test %d: expected: decreasing unknowns, got: %v", i, phi)
+				}
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Fatalf("test %d: expected: sum 1, got: %v", i, phi)
+			}
+		}
+	}
+}
+
+// mixMajor is the major contributor of mixStain.
+var mixMajor = peaks("victim", "VWA:12", "VWA:14", "D3S1358:15", "D3S1358:16",
+	"TH01:9", "TH01:9.3", "D8S1179:11", "D8S1179:13", "D21S11:20", "D21S11:21", "FGA:20", "FGA:21")
+
+// =============================================================================
+func TestSample_SubtractKnown(t *testing.T) {
+
+	c := MixtureConf{Seed: 1, Bootstrap: 100}
+	s, m, err := mixStain.SubtractKnown([]Sample{mixMajor}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.Mixture[0]-0.75) > 0.02 {
+		t.Fatalf("expected: known proportion 0.75, got: %v", m.Mixture)
+	}
+
+	// the shared allele 9.3 of the minor contributor is kept
+	th01 := s.Locus("TH01")
+	if th01.HasAllele(A2ID("9")) || !th01.HasAllele(A2ID("9.3")) || !th01.HasAllele(A2ID("6")) {
+		t.Fatalf("expected: TH01 6,9.3, got: %v", th01)
+	}
+	if h := th01.Allele(A2ID("9.3")).Height; math.Abs(h-250) > 30 {
+		t.Fatalf("expected: remaining height 250, got: %v", h)
+	}
+	// FGA is inconsistent with the known contributor and kept unchanged
+	if len(s.Locus("FGA").Alleles) != 4 {
+		t.Fatalf("expected: FGA unchanged, got: %v", s.Locus("FGA"))
+	}
+
+	if _, _, err := mixStain.SubtractKnown([]Sample{mixMajor, mixMajor}, c); err == nil {
+		t.Fatalf("expected: error for 2 known of 2 contributors")
+	}
+}
@@ -0,0 +1,526 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The readers in this file import samples typed by massively parallel
+// sequencing (MPS). The read count of an allele fills the role peak height
+// plays for CE data, i.e. it is stored in Allele.Height. Thereby, functions
+// such as MajorComponent, InferUnknownPersons, and ExportCSV work unchanged on
+// MPS data.
+
+// newMPSReader returns a csv.Reader for MPS tables from r separated by sep.
+// The number of fields may vary between rows.
+func newMPSReader(r io.Reader, sep rune) *csv.Reader {
+	csvR := csv.NewReader(r)
+	csvR.Comma = sep
+	csvR.FieldsPerRecord = -1
+	csvR.LazyQuotes = true
+	csvR.TrimLeadingSpace = true
+	return csvR
+}
+
+// parseReads parses the read count s of column col.
+func parseReads(s, col string) (float64, error) {
+	reads, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, &ParseError{Column: col, Err: err}
+	}
+	return reads, nil
+}
+
+// mpsSamples collects alleles per sample and locus while preserving the order
+// in which samples and loci appear in the data.
+type mpsSamples struct {
+	source  string
+	ids     []string
+	samples map[string]*Sample
+}
+
+// newMPSSamples returns an empty mpsSamples collection of source.
+func newMPSSamples(source string) *mpsSamples {
+	return &mpsSamples{source: source, samples: make(map[string]*Sample)}
+}
+
+// add adds allele a of locus lID to sample id. Alleles of the same sequence
+// (e.g. forward and reverse strand rows) are summed up.
+func (m *mpsSamples) add(id, lID string, a Allele) {
+
+	s, ok := m.samples[id]
+	if !ok {
+		ns := NewSample(id, m.source)
+		s = &ns
+		m.samples[id] = s
+		m.ids = append(m.ids, id)
+	}
+
+	lID = strings.ToUpper(lID)
+	for i := range s.Loci {
+		if s.Loci[i].ID != lID {
+			continue
+		}
+		for j := range s.Loci[i].Alleles {
+			if sameAllele(s.Loci[i].Alleles[j], a) {
+				s.Loci[i].Alleles[j].Height += a.Height
+				return
+			}
+		}
+		s.Loci[i].AddAllele(a)
+		return
+	}
+
+	l := NewLocus(lID)
+	l.AddAllele(a)
+	s.Loci = append(s.Loci, l)
+}
+
+// list returns the collected samples with an unknown kit.
+func (m *mpsSamples) list() []Sample {
+	var r []Sample
+	for _, id := range m.ids {
+		s := *m.samples[id]
+		s.UnknownKit()
+		r = append(r, s)
+	}
+	return r
+}
+
+// =============================================================================
+// STRait Razor
+
+// ReadSTRaitRazor reads the allele table of file f as written by STRait
+// Razor for the sample sampleID. See ReadSTRaitRazorFrom.
+func ReadSTRaitRazor(f, sampleID string) ([]Sample, error) {
+	return readFile(f, func(r io.Reader) ([]Sample, error) {
+		return ReadSTRaitRazorFrom(r, f, sampleID)
+	})
+}
+
+// ReadSTRaitRazorFrom reads a STRait Razor allele table from r. STRait Razor
+// writes one tab separated table per sample with the columns marker, allele
+// (in repeat units), repeat sequence, and the forward and reverse read counts
+// (or a single column with the total read count). An optional header line is
+// skipped. The total read count is stored as height of the allele.
+func ReadSTRaitRazorFrom(r io.Reader, source, sampleID string) ([]Sample, error) {
+
+	csvR := newMPSReader(r, '\t')
+	m := newMPSSamples(source)
+
+	for first := true; ; first = false {
+		line, err := csvR.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, newCSVParseError(source, err)
+		}
+
+		lineNo, _ := csvR.FieldPos(0)
+		if len(line) < 4 {
+			return nil, &ParseError{Source: source, Line: lineNo, Sample: sampleID,
+				Err: fmt.Errorf("expect at least 4 columns, got %v", len(line))}
+		}
+
+		// skip the header, i.e. a first line without read counts
+		if _, err := strconv.ParseFloat(line[3], 64); first && err != nil {
+			continue
+		}
+
+		a, err := parseSTRaitRazorRow(line)
+		if err != nil {
+			var pErr *ParseError
+			if !errors.As(err, &pErr) {
+				pErr = &ParseError{Err: err}
+			}
+			pErr.Source, pErr.Line, pErr.Sample, pErr.Marker = source, lineNo, sampleID, line[0]
+			return nil, pErr
+		}
+
+		m.add(sampleID, line[0], a)
+	}
+
+	return m.list(), nil
+}
+
+// parseSTRaitRazorRow returns the allele of a row of a STRait Razor table.
+func parseSTRaitRazorRow(line []string) (Allele, error) {
+
+	id, err := ParseAlleleID(line[1])
+	if err != nil {
+		return Allele{}, &ParseError{Column: "Allele", Err: err}
+	}
+
+	reads, err := parseReads(line[3], "Forward")
+	if err != nil {
+		return Allele{}, err
+	}
+	if len(line) > 4 {
+		rev, err := parseReads(line[4], "Reverse")
+		if err != nil {
+			return Allele{}, err
+		}
+		reads += rev
+	}
+
+	return Allele{
+		ID:     id,
+		Height: reads,
+		Seq:    Sequence{Repeat: strings.ToUpper(line[2])},
+	}, nil
+}
+
+// =============================================================================
+// FDSTools
+
+// tssvBlock matches a block of the TSSV sequence format, e.g. AGAT(12).
+var tssvBlock = regexp.MustCompile(`([ACGTN]+)\((\d+)\)`)
+
+// fdsBlock matches a block of the FDSTools allele name format, e.g. AGAT[12].
+var fdsBlock = regexp.MustCompile(`([ACGTN]+)\[(\d+)\]`)
+
+// expandBlocks expands the repeat blocks of seq found by re into the repeat
+// sequence and the bracketed notation. It returns false if seq does not
+// consist of blocks only or has no repeat, e.g. AATG(0).
+func expandBlocks(seq string, re *regexp.Regexp) (Sequence, int, bool) {
+
+	matches := re.FindAllStringSubmatch(seq, -1)
+	if len(matches) == 0 || strings.Join(re.FindAllString(seq, -1), "") != seq {
+		return Sequence{}, 0, false
+	}
+
+	var repeat strings.Builder
+	var bracket []string
+	var unit, maxN int
+	for _, m := range matches {
+		n, _ := strconv.Atoi(m[2])
+		repeat.WriteString(strings.Repeat(m[1], n))
+		bracket = append(bracket, "["+m[1]+"]"+m[2])
+		if n > maxN { // the motif of the longest run defines the repeat unit
+			unit, maxN = len(m[1]), n
+		}
+	}
+
+	if unit == 0 {
+		return Sequence{}, 0, false
+	}
+
+	return Sequence{Repeat: repeat.String(), Bracket: strings.Join(bracket, " ")}, unit, true
+}
+
+// lengthID returns the CE designation of a repeat region of n bases and a
+// repeat unit of unit bases, e.g. 11.2 for 46 bases of a tetranucleotide.
+func lengthID(n, unit int) AlleleID {
+	return A2ID(strconv.Itoa(n/unit) + "." + strconv.Itoa(n%unit))
+}
+
+// parseFDSToolsAllele parses an allele as written by FDSTools, either in the
+// TSSV format (e.g. AGAT(12)AGAC(1)) or as allele name (e.g.
+// CE13_TCTA[11]TCTG[1]_+43A>G). For the TSSV format, the CE designation is
+// derived from the length of the sequence.
+func parseFDSToolsAllele(s string) (AlleleID, Sequence, error) {
+
+	s = strings.TrimSpace(s)
+	if seq, unit, ok := expandBlocks(s, tssvBlock); ok {
+		return lengthID(len(seq.Repeat), unit), seq, nil
+	}
+
+	parts := strings.SplitN(s, "_", 3)
+	if len(parts) < 2 {
+		return AlleleID{}, Sequence{}, fmt.Errorf("invalid allele %q", s)
+	}
+
+	id, err := ParseAlleleID(strings.TrimPrefix(parts[0], "CE"))
+	if err != nil {
+		return AlleleID{}, Sequence{}, err
+	}
+
+	seq, _, ok := expandBlocks(parts[1], fdsBlock)
+	if !ok {
+		return AlleleID{}, Sequence{}, fmt.Errorf("invalid repeat structure %q", parts[1])
+	}
+	if len(parts) == 3 {
+		seq.Flanks = strings.Join(strings.Fields(parts[2]), ",")
+	}
+
+	return id, seq, nil
+}
+
+// ReadTSSV reads the output file f of the FDSTools tool tssv for the sample
+// sampleID. See ReadTSSVFrom.
+func ReadTSSV(f, sampleID string) ([]Sample, error) {
+	return readFile(f, func(r io.Reader) ([]Sample, error) {
+		return ReadTSSVFrom(r, f, sampleID)
+	})
+}
+
+// ReadTSSVFrom reads the output of the FDSTools tool tssv from r. The tab
+// separated table must have the columns marker, sequence (in TSSV format, e.g.
+// AGAT(12)AGAC(1)), and total. Rows of sequences that are not in TSSV format
+// (e.g. 'Other sequences') are skipped.
+func ReadTSSVFrom(r io.Reader, source, sampleID string) ([]Sample, error) {
+
+	rows, err := readFDSTable(r, source, []string{"marker", "sequence", "total"})
+	if err != nil {
+		return nil, err
+	}
+
+	m := newMPSSamples(source)
+	for _, row := range rows {
+		seq, unit, ok := expandBlocks(row.cells["sequence"], tssvBlock)
+		if !ok {
+			continue
+		}
+
+		reads, err := parseReads(row.cells["total"], "total")
+		if err != nil {
+			return nil, row.fail(err, sampleID)
+		}
+
+		m.add(sampleID, row.cells["marker"], Allele{
+			ID:     lengthID(len(seq.Repeat), unit),
+			Height: reads,
+			Seq:    seq,
+		})
+	}
+
+	return m.list(), nil
+}
+
+// ReadAlleleFinder reads the output file f of the FDSTools tool allelefinder.
+// See ReadAlleleFinderFrom.
+func ReadAlleleFinder(f string) ([]Sample, error) {
+	return readFile(f, func(r io.Reader) ([]Sample, error) {
+		return ReadAlleleFinderFrom(r, f)
+	})
+}
+
+// ReadAlleleFinderFrom reads the output of the FDSTools tool allelefinder from
+// r. The tab separated table must have the columns sample, marker, total and
+// allele; alleles can be in TSSV format or FDSTools allele names.
+func ReadAlleleFinderFrom(r io.Reader, source string) ([]Sample, error) {
+
+	rows, err := readFDSTable(r, source, []string{"sample", "marker", "total", "allele"})
+	if err != nil {
+		return nil, err
+	}
+
+	m := newMPSSamples(source)
+	for _, row := range rows {
+		id, seq, err := parseFDSToolsAllele(row.cells["allele"])
+		if err != nil {
+			return nil, row.fail(&ParseError{Column: "allele", Err: err}, row.cells["sample"])
+		}
+
+		reads, err := parseReads(row.cells["total"], "total")
+		if err != nil {
+			return nil, row.fail(err, row.cells["sample"])
+		}
+
+		m.add(row.cells["sample"], row.cells["marker"], Allele{ID: id, Height: reads, Seq: seq})
+	}
+
+	return m.list(), nil
+}
+
+// fdsRow is a row of an FDSTools table by column name.
+type fdsRow struct {
+	source string
+	line   int
+	cells  map[string]string
+}
+
+// fail returns err as a ParseError at the position of row r.
+func (r fdsRow) fail(err error, sampleID string) error {
+	var pErr *ParseError
+	if !errors.As(err, &pErr) {
+		pErr = &ParseError{Err: err}
+	}
+	pErr.Source, pErr.Line, pErr.Sample, pErr.Marker = r.source, r.line, sampleID, r.cells["marker"]
+	return pErr
+}
+
+// readFDSTable reads a tab separated FDSTools table from r and returns its
+// rows. The header must contain all columns in cols.
+func readFDSTable(r io.Reader, source string, cols []string) ([]fdsRow, error) {
+
+	csvR := newMPSReader(r, '\t')
+	header, err := csvR.Read()
+	if err == io.EOF {
+		return nil, &ParseError{Source: source, Line: 1, Err: errEmptyInput}
+	}
+	if err != nil {
+		return nil, newCSVParseError(source, err)
+	}
+
+	idx := make(map[string]int)
+	for i, h := range header {
+		idx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, c := range cols {
+		if _, ok := idx[c]; !ok {
+			return nil, &ParseError{Source: source, Line: 1, Column: c,
+				Err: fmt.Errorf("cannot find column %v", c)}
+		}
+	}
+
+	var rows []fdsRow
+	for {
+		line, err := csvR.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, newCSVParseError(source, err)
+		}
+
+		lineNo, _ := csvR.FieldPos(0)
+		row := fdsRow{source: source, line: lineNo, cells: make(map[string]string)}
+		for _, c := range cols {
+			if idx[c] >= len(line) {
+				return nil, &ParseError{Source: source, Line: lineNo, Column: c,
+					Err: errors.New("missing field")}
+			}
+			row.cells[c] = line[idx[c]]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// =============================================================================
+// ForenSeq UAS
+
+// ReadUASReport reads file f of a ForenSeq Universal Analysis Software (UAS)
+// Sample Details Report. See ReadUASReportFrom.
+func ReadUASReport(f string) ([]Sample, error) {
+	return readFile(f, func(r io.Reader) ([]Sample, error) {
+		return ReadUASReportFrom(r, f)
+	})
+}
+
+// ReadUASReportFrom reads a ForenSeq UAS Sample Details Report from r. The
+// report (an Excel workbook) must be saved as comma separated CSV file. The
+// sample ID is read from the 'Sample' line of the report header. The STR
+// tables of the report (i.e. tables of a section titled '... STRs' whose
+// header starts with 'Locus' and contains 'Allele Name' and 'Reads') are read
+// until the next empty line; all other tables such as the iSNPs are skipped.
+// Only alleles with 'Typed Allele' = 'Yes' are imported, i.e. the analytical
+// and interpretation thresholds of the UAS apply.
+func ReadUASReportFrom(r io.Reader, source string) ([]Sample, error) {
+
+	csvR := newMPSReader(r, ',')
+	m := newMPSSamples(source)
+
+	var sampleID, section string
+	var idx map[string]int // column index of the current STR table
+	for {
+		line, err := csvR.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, newCSVParseError(source, err)
+		}
+		lineNo, _ := csvR.FieldPos(0)
+
+		switch {
+		case isEmptyRow(line):
+			idx = nil // end of table
+		case idx == nil && (line[0] == "Sample" || line[0] == "Sample ID") && len(line) > 1:
+			sampleID = strings.TrimSpace(line[1])
+		case idx == nil && line[0] == "Locus":
+			if section == "" || strings.Contains(section, "STR") {
+				idx = uasIndex(line)
+			}
+		case idx == nil && isEmptyRow(line[1:]):
+			section = strings.TrimSpace(line[0])
+		case idx != nil:
+			if sampleID == "" {
+				return nil, &ParseError{Source: source, Line: lineNo,
+					Err: errors.New("sample ID missing in report header")}
+			}
+			if typed, ok := idx["Typed Allele"]; ok && !strings.EqualFold(cellAt(line, typed), "Yes") {
+				continue
+			}
+
+			a, err := parseUASRow(line, idx)
+			if err != nil {
+				var pErr *ParseError
+				if !errors.As(err, &pErr) {
+					pErr = &ParseError{Err: err}
+				}
+				pErr.Source, pErr.Line, pErr.Sample, pErr.Marker = source, lineNo, sampleID, line[0]
+				return nil, pErr
+			}
+			m.add(sampleID, line[0], a)
+		}
+	}
+
+	return m.list(), nil
+}
+
+// uasIndex returns the column index of a UAS STR table header. It returns nil
+// if the header is not one of an STR table (e.g. the iSNP table).
+func uasIndex(header []string) map[string]int {
+	idx := make(map[string]int)
+	for i, h := range header {
+		idx[strings.TrimSpace(h)] = i
+	}
+	if _, ok := idx["Allele Name"]; !ok {
+		return nil
+	}
+	if _, ok := idx["Reads"]; !ok {
+		return nil
+	}
+	return idx
+}
+
+// parseUASRow returns the allele of a row of a UAS STR table.
+func parseUASRow(line []string, idx map[string]int) (Allele, error) {
+
+	id, err := ParseAlleleID(cellAt(line, idx["Allele Name"]))
+	if err != nil {
+		return Allele{}, &ParseError{Column: "Allele Name", Err: err}
+	}
+
+	reads, err := parseReads(cellAt(line, idx["Reads"]), "Reads")
+	if err != nil {
+		return Allele{}, err
+	}
+
+	var seq Sequence
+	if i, ok := idx["Repeat Sequence"]; ok {
+		seq.Repeat = strings.ToUpper(strings.TrimSpace(cellAt(line, i)))
+	}
+
+	return Allele{ID: id, Height: reads, Seq: seq}, nil
+}
+
+// cellAt returns field i of line or an empty string if line is too short.
+func cellAt(line []string, i int) string {
+	if i < len(line) {
+		return line[i]
+	}
+	return ""
+}
+
+// isEmptyRow returns true if all fields of line are empty.
+func isEmptyRow(line []string) bool {
+	for _, f := range line {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// the isoalleles of D8S1179 and the alleles of TH01 as used in the MPS fixtures
+var (
+	d8iso1 = Allele{ID: A2ID("13"), Height: 780,
+		Seq: Sequence{Repeat: strings.Repeat("TCTA", 13)}}
+	d8iso2 = Allele{ID: A2ID("13"), Height: 690,
+		Seq: Sequence{Repeat: "TCTATCTG" + strings.Repeat("TCTA", 11)}}
+	th6 = Allele{ID: A2ID("6"), Height: 990,
+		Seq: Sequence{Repeat: strings.Repeat("AATG", 6)}}
+	th93 = Allele{ID: A2ID("9.3"), Height: 920,
+		Seq: Sequence{Repeat: strings.Repeat("AATG", 6) + "ATG" + strings.Repeat("AATG", 3)}}
+)
+
+// withBracket returns allele a with the bracketed notation b.
+func withBracket(a Allele, b string) Allele {
+	a.Seq.Bracket = b
+	return a
+}
+
+// =============================================================================
+func Test_ReadSTRaitRazor(t *testing.T) {
+
+	res, err := ReadSTRaitRazor("testdata/straitrazor.txt", "SR1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := []Locus{
+		{ID: "D8S1179", Alleles: []Allele{
+			{ID: A2ID("12"), Height: 70, Seq: Sequence{Repeat: strings.Repeat("TCTA", 12)}},
+			d8iso1, d8iso2,
+		}},
+		{ID: "TH01", Alleles: []Allele{th6, th93}},
+	}
+
+	if len(res) != 1 || res[0].ID != "SR1" || !reflect.DeepEqual(res[0].Loci, want) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+	if !res[0].IsOfUnknownKit() {
+		t.Fatalf("expected unknown kit, got: %v", res[0].Kit)
+	}
+
+	// the major component works on reads as on peak heights
+	mc := res[0].Loci[1].MajorComponent(0.67, 2.9, 1500, 500)
+	if !reflect.DeepEqual(mc.Alleles, []Allele{th6, th93}) {
+		t.Fatalf("expected major component: %v, got: %v", []Allele{th6, th93}, mc.Alleles)
+	}
+}
+
+// =============================================================================
+func Test_ReadSTRaitRazorFrom_ParseError(t *testing.T) {
+
+	in := "D8S1179\t13\tTCTA\t400\t380\n" +
+		"TH01\t6\tAATG\t500\tx\n"
+
+	_, err := ReadSTRaitRazorFrom(strings.NewReader(in), "upload", "SR1")
+	var pErr *ParseError
+	if !errors.As(err, &pErr) {
+		t.Fatalf("expected ParseError, got: %v", err)
+	}
+
+	want := ParseError{Source: "upload", Line: 2, Column: "Reverse", Sample: "SR1", Marker: "TH01"}
+	pErr.Err = nil
+	if *pErr != want {
+		t.Fatalf("expected: %v, got: %v", want, *pErr)
+	}
+}
+
+// =============================================================================
+func Test_ReadTSSV(t *testing.T) {
+
+	res, err := ReadTSSV("testdata/tssv.txt", "S1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := []Locus{
+		{ID: "D8S1179", Alleles: []Allele{
+			{ID: A2ID("12"), Height: 70, Seq: Sequence{
+				Repeat: strings.Repeat("TCTA", 12), Bracket: "[TCTA]12"}},
+			withBracket(d8iso1, "[TCTA]13"),
+			withBracket(d8iso2, "[TCTA]1 [TCTG]1 [TCTA]11"),
+		}},
+		{ID: "TH01", Alleles: []Allele{
+			withBracket(th6, "[AATG]6"),
+			withBracket(th93, "[AATG]6 [ATG]1 [AATG]3"),
+		}},
+	}
+
+	if len(res) != 1 || !reflect.DeepEqual(res[0].Loci, want) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+}
+
+// =============================================================================
+func Test_ReadAlleleFinder(t *testing.T) {
+
+	res, err := ReadAlleleFinder("testdata/allelefinder.txt")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := []Sample{
+		{ID: "S1", Loci: []Locus{
+			{ID: "D8S1179", Alleles: []Allele{
+				withBracket(d8iso1, "[TCTA]13"),
+				withBracket(d8iso2, "[TCTA]1 [TCTG]1 [TCTA]11"),
+			}},
+			{ID: "TH01", Alleles: []Allele{withBracket(th6, "[AATG]6")}},
+		}},
+		{ID: "S2", Loci: []Locus{
+			{ID: "D8S1179", Alleles: []Allele{{ID: A2ID("14"), Height: 820, Seq: Sequence{
+				Repeat: strings.Repeat("TCTA", 14), Bracket: "[TCTA]14", Flanks: "+43A>G"}}}},
+			{ID: "TH01", Alleles: []Allele{
+				{ID: A2ID("9.3"), Height: 1010, Seq: withBracket(th93, "[AATG]6 [ATG]1 [AATG]3").Seq},
+			}},
+		}},
+	}
+
+	if len(res) != len(want) {
+		t.Fatalf("expected %v samples, got: %v", len(want), len(res))
+	}
+	for i := range want {
+		if res[i].ID != want[i].ID || !reflect.DeepEqual(res[i].Loci, want[i].Loci) {
+			t.Fatalf("sample %d: expected: %v, got: %v", i+1, want[i], res[i])
+		}
+	}
+}
+
+// =============================================================================
+func Test_parseFDSToolsAllele(t *testing.T) {
+
+	type test struct {
+		in      string
+		wantID  AlleleID
+		wantSeq Sequence
+		wantErr bool
+	}
+
+	tests := []test{
+		{"AGAT(12)", A2ID("12"), Sequence{Repeat: strings.Repeat("AGAT", 12), Bracket: "[AGAT]12"}, false},
+		{"AGAT(11)AG(1)", A2ID("11.2"), Sequence{Repeat: strings.Repeat("AGAT", 11) + "AG", Bracket: "[AGAT]11 [AG]1"}, false},
+		{"CE12_AGAT[12]", A2ID("12"), Sequence{Repeat: strings.Repeat("AGAT", 12), Bracket: "[AGAT]12"}, false},
+		{"12_AGAT[12]_-20G>A +43A>G", A2ID("12"), Sequence{Repeat: strings.Repeat("AGAT", 12),
+			Bracket: "[AGAT]12", Flanks: "-20G>A,+43A>G"}, false},
+		{"Other sequences", AlleleID{}, Sequence{}, true},
+		{"CE12_AGATAGAT", AlleleID{}, Sequence{}, true},
+		{"AATG(0)", AlleleID{}, Sequence{}, true},
+		{"CE0_AATG[0]", AlleleID{}, Sequence{}, true},
+	}
+
+	for i, tc := range tests {
+		id, seq, err := parseFDSToolsAllele(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("test %d: expected error: %v, got: %v", i+1, tc.wantErr, err)
+		}
+		if id != tc.wantID || seq != tc.wantSeq {
+			t.Fatalf("test %d: expected: %v %v, got: %v %v", i+1, tc.wantID, tc.wantSeq, id, seq)
+		}
+	}
+}
+
+// =============================================================================
+func Test_ReadUASReport(t *testing.T) {
+
+	res, err := ReadUASReport("testdata/uas_sample_details.csv")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := []Locus{
+		{ID: "D8S1179", Alleles: []Allele{d8iso1, d8iso2}},
+		{ID: "TH01", Alleles: []Allele{th6, th93}},
+		{ID: "DXS10135", Alleles: []Allele{{ID: A2ID("21"), Height: 310,
+			Seq: Sequence{Repeat: strings.Repeat("AAGA", 21)}}}},
+	}
+
+	if len(res) != 1 || res[0].ID != "UAS1" || !reflect.DeepEqual(res[0].Loci, want) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+
+	// MPS samples are exported with reads as heights
+	rows, err := buildCSV(res[0], true)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if out := fmt.Sprint(rows); !strings.Contains(out, "780") || !strings.Contains(out, "DXS10135") {
+		t.Fatalf("expected reads in export, got: %v", out)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// NOCConf holds the parameters of the simulation of allele counts and of the
+// estimation of the number of contributors (NOC).
+type NOCConf struct {
+	MaxNOC int   // largest number of contributors considered
+	Sims   int   // number of simulated samples per number of contributors
+	Seed   int64 // seed of the random number generator
+	// Heights simulates peak heights: alleles below the analytical threshold
+	// AT drop out and alleles below the stochastic threshold ST count as low
+	// peaks. The peak heights are gamma distributed with a coefficient of
+	// variation Omega around the expectation given by the mixture proportions
+	// (uniformly distributed) and Mu, the sum of the peak heights of a
+	// heterozygous locus of all contributors. Mu defaults to the mean locus
+	// height of the evidence for EstimateNOC.
+	Heights bool
+	AT      float64
+	ST      float64
+	Omega   float64
+	Mu      float64
+}
+
+// DefaultNOCConf holds the default parameters of the NOC estimation.
+var DefaultNOCConf = NOCConf{
+	MaxNOC: 5,
+	Sims:   10000,
+	AT:     50,
+	ST:     200,
+	Omega:  0.3,
+}
+
+// withDefaults returns c with zero values replaced by the defaults.
+func (c NOCConf) withDefaults() NOCConf {
+	if c.MaxNOC == 0 {
+		c.MaxNOC = DefaultNOCConf.MaxNOC
+	}
+	if c.Sims == 0 {
+		c.Sims = DefaultNOCConf.Sims
+	}
+	if c.Omega == 0 {
+		c.Omega = DefaultNOCConf.Omega
+	}
+	return c
+}
+
+// NOCDistribution holds the simulated distributions of the allele counts of
+// samples with 1..MaxContributors contributors. The distributions are indexed
+// by the number of contributors minus one and by the count, e.g.
+// MaxAlleles[2][5] is the probability of a maximum allele count of 5 in a
+// mixture of 3 persons.
+type NOCDistribution struct {
+	Loci            []string
+	MaxContributors int
+	Sims            int
+	MaxAlleles      [][]float64 // maximum number of alleles of a locus
+	TotalAlleles    [][]float64 // total number of alleles of all loci
+	LowPeaks        [][]float64 // total number of peaks below ST; with Heights only
+}
+
+// NOCEstimate holds the maximum likelihood estimate of the number of
+// contributors of a sample.
+type NOCEstimate struct {
+	NOC       int
+	LogLik    []float64 // log likelihood of 1..MaxContributors contributors
+	Posterior []float64 // posterior probabilities for a uniform prior
+	Dist      NOCDistribution
+}
+
+// nocCounts are the allele counts of a sample.
+type nocCounts struct {
+	max, total, low int
+}
+
+// SimulateAlleleCounts simulates c.Sims samples of 1..c.MaxNOC contributors
+// each with the allele frequencies freqs at loci (all loci of freqs if nil)
+// and returns the distributions of their allele counts.
+func (freqs Freqs) SimulateAlleleCounts(loci []string, c NOCConf) (NOCDistribution, error) {
+
+	c = c.withDefaults()
+	switch {
+	case c.MaxNOC < 0 || c.Sims < 0:
+		return NOCDistribution{}, fmt.Errorf("negative number of contributors %v or simulations %v", c.MaxNOC, c.Sims)
+	case c.Heights && (c.Mu <= 0 || c.Omega < 0):
+		return NOCDistribution{}, fmt.Errorf("peak height %v not positive or negative variation %v", c.Mu, c.Omega)
+	}
+
+	if loci == nil {
+		for _, fl := range freqs.Floci {
+			loci = append(loci, fl.ID)
+		}
+	}
+	sim := Freqs{Pop: freqs.Pop}
+	for _, id := range loci {
+		if !freqs.HasFlocus(id) {
+			return NOCDistribution{}, fmt.Errorf("no allele frequencies of locus %v", id)
+		}
+		sim.Floci = append(sim.Floci, freqs.Flocus(id))
+	}
+	if len(sim.Floci) == 0 {
+		return NOCDistribution{}, fmt.Errorf("no loci")
+	}
+
+	maxCount := 2 * c.MaxNOC
+	d := NOCDistribution{Loci: loci, MaxContributors: c.MaxNOC, Sims: c.Sims}
+	rng := rand.New(rand.NewSource(c.Seed))
+	for n := 1; n <= c.MaxNOC; n++ {
+		maxA := make([]float64, maxCount+1)
+		total := make([]float64, maxCount*len(sim.Floci)+1)
+		low := make([]float64, maxCount*len(sim.Floci)+1)
+		for i := 0; i < c.Sims; i++ {
+			nc := sim.simulateCounts(n, c, rng)
+			maxA[nc.max]++
+			total[nc.total]++
+			low[nc.low]++
+		}
+		for _, x := range [][]float64{maxA, total, low} {
+			for j := range x {
+				x[j] /= float64(c.Sims)
+			}
+		}
+		d.MaxAlleles = append(d.MaxAlleles, maxA)
+		d.TotalAlleles = append(d.TotalAlleles, total)
+		if c.Heights {
+			d.LowPeaks = append(d.LowPeaks, low)
+		}
+	}
+
+	return d, nil
+}
+
+// simulateCounts draws a sample of n persons with the allele frequencies
+// freqs (see DrawSamples) and returns its allele counts.
+func (freqs Freqs) simulateCounts(n int, c NOCConf, rng *rand.Rand) nocCounts {
+
+	// mixture proportions, uniformly distributed on the simplex
+	phi := make([]float64, n)
+	var sum float64
+	for k := range phi {
+		phi[k] = -math.Log(1 - rng.Float64())
+		sum += phi[k]
+	}
+	for k := range phi {
+		phi[k] /= sum
+	}
+
+	persons := make([]Sample, n)
+	for k := range persons {
+		persons[k] = freqs.drawPerson(rng.Float64)
+	}
+
+	var r nocCounts
+	for i := range freqs.Floci {
+		// expected peak heights of the alleles; each allele copy of a
+		// contributor adds half of its heterozygous locus height
+		expected := make(map[AlleleID]float64)
+		for k, p := range persons {
+			for _, a := range p.Loci[i].Alleles {
+				expected[a.ID] += phi[k] * c.Mu / 2
+			}
+		}
+
+		var count int
+		for _, e := range expected {
+			if c.Heights {
+				h := e
+				if c.Omega > 0 {
+					h = gammaRand(rng, 1/(c.Omega*c.Omega), e*c.Omega*c.Omega)
+				}
+				if h < c.AT {
+					continue
+				}
+				if h < c.ST {
+					r.low++
+				}
+			}
+			count++
+		}
+		r.max = max(r.max, count)
+		r.total += count
+	}
+	return r
+}
+
+// EstimateNOC returns the maximum likelihood estimate of the number of
+// contributors of evidence. The allele counts of evidence are compared to
+// the distributions simulated with the allele frequencies f of the loci
+// typed in evidence and in f (see SimulateAlleleCounts). The counts are
+// treated as independent. The simulated distributions are Laplace smoothed
+// over the counts possible for n contributors, so that counts never simulated
+// do not exclude n, but more than 2n alleles at a locus do. With c.Heights, only peaks at or above c.AT are counted and
+// the number of peaks below c.ST is used as well.
+func EstimateNOC(evidence Sample, f Freqs, c NOCConf) (NOCEstimate, error) {
+
+	c = c.withDefaults()
+
+	var loci []string
+	var ev nocCounts
+	var heights float64
+	for _, l := range evidence.Loci {
+		if !f.HasFlocus(l.ID) {
+			continue
+		}
+		seen := make(map[AlleleID]bool)
+		var sum float64
+		for _, a := range l.Alleles {
+			if !a.ID.IsTyped() || seen[a.ID] || (c.Heights && a.Height < c.AT) {
+				continue
+			}
+			seen[a.ID] = true
+			sum += a.Height
+			if c.Heights && a.Height < c.ST {
+				ev.low++
+			}
+		}
+		loci = append(loci, l.ID)
+		heights += sum
+		ev.max = max(ev.max, len(seen))
+		ev.total += len(seen)
+	}
+	if len(loci) == 0 {
+		return NOCEstimate{}, fmt.Errorf("sample %v: no loci with allele frequencies", evidence.ID)
+	}
+	if c.Heights && c.Mu == 0 {
+		c.Mu = heights / float64(len(loci))
+	}
+
+	d, err := f.SimulateAlleleCounts(loci, c)
+	if err != nil {
+		return NOCEstimate{}, fmt.Errorf("sample %v: %w", evidence.ID, err)
+	}
+
+	r := NOCEstimate{Dist: d}
+	for n := 0; n < d.MaxContributors; n++ {
+		maxCount := 2 * (n + 1)
+		ll := smoothedLogProb(d.MaxAlleles[n], ev.max, maxCount, d.Sims) +
+			smoothedLogProb(d.TotalAlleles[n], ev.total, maxCount*len(loci), d.Sims)
+		if c.Heights {
+			ll += smoothedLogProb(d.LowPeaks[n], ev.low, maxCount*len(loci), d.Sims)
+		}
+		r.LogLik = append(r.LogLik, ll)
+		if r.NOC == 0 || ll > r.LogLik[r.NOC-1] {
+			r.NOC = n + 1
+		}
+	}
+
+	norm := logSumExp(r.LogLik)
+	if math.IsInf(norm, -1) {
+		return NOCEstimate{}, fmt.Errorf("sample %v: %v alleles at a locus exceed %v contributors",
+			evidence.ID, ev.max, d.MaxContributors)
+	}
+	for _, ll := range r.LogLik {
+		r.Posterior = append(r.Posterior, math.Exp(ll-norm))
+	}
+
+	return r, nil
+}
+
+// smoothedLogProb returns the log of the probability of count x of the
+// distribution dist simulated from sims samples, Laplace smoothed over the
+// possible counts 0..possible. Impossible counts have probability 0.
+func smoothedLogProb(dist []float64, x, possible, sims int) float64 {
+	if x > possible {
+		return math.Inf(-1)
+	}
+	var p float64
+	if x < len(dist) {
+		p = dist[x]
+	}
+	return math.Log((p*float64(sims) + 1) / float64(sims+possible+1))
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// nocFreqs are 8 loci with 10 equally frequent alleles each.
+var nocFreqs = func() Freqs {
+	f := Freqs{Pop: "noc"}
+	for i := 1; i <= 8; i++ {
+		fl := Flocus{ID: fmt.Sprintf("L%d", i)}
+		for j := 10; j < 20; j++ {
+			fl.Falleles = append(fl.Falleles, Fallele{ID: A2ID(fmt.Sprint(j)), Freq: 0.1})
+		}
+		f.Floci = append(f.Floci, fl)
+	}
+	return f
+}()
+
+// nocSample returns a sample with the alleles 10..10+n[i]-1 at locus i of
+// nocFreqs, all of the peak height h.
+func nocSample(h float64, n ...int) Sample {
+	var alleles []string
+	for i := 1; i <= 8; i++ {
+		for j := 0; j < n[i-1]; j++ {
+			alleles = append(alleles, fmt.Sprintf("L%d:%d:%v", i, 10+j, h))
+		}
+	}
+	return peaks("stain", alleles...)
+}
+
+// =============================================================================
+func Test_SimulateAlleleCounts(t *testing.T) {
+
+	c := NOCConf{MaxNOC: 3, Sims: 2000, Seed: 1}
+	d, err := nocFreqs.SimulateAlleleCounts(nil, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Loci) != 8 || len(d.MaxAlleles) != 3 || len(d.TotalAlleles) != 3 || d.LowPeaks != nil {
+		t.Fatalf("unexpected distribution %v", d)
+	}
+	for n := range d.MaxAlleles {
+		for _, dist := range [][]float64{d.MaxAlleles[n], d.TotalAlleles[n]} {
+			var sum float64
+			for _, p := range dist {
+				sum += p
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Fatalf("%d contributors: expected: probabilities sum to 1, got: %v", n+1, sum)
+			}
+		}
+		// more than 2 alleles per person are impossible
+		for x := 2*(n+1) + 1; x < len(d.MaxAlleles[n]); x++ {
+			if d.MaxAlleles[n][x] != 0 {
+				t.Fatalf("%d contributors: expected: no max allele count %d, got: %v", n+1, x, d.MaxAlleles[n][x])
+			}
+		}
+	}
+
+	// a single person is heterozygous with probability 0.9 at each locus
+	if p := d.MaxAlleles[0][1]; math.Abs(p-math.Pow(0.1, 8)) > 1e-3 {
+		t.Fatalf("expected: %v, got: %v", math.Pow(0.1, 8), p)
+	}
+
+	// seeded simulations are reproducible
+	d2, _ := nocFreqs.SimulateAlleleCounts(nil, c)
+	if !reflect.DeepEqual(d, d2) {
+		t.Fatalf("expected: equal distributions for the same seed")
+	}
+
+	if _, err := nocFreqs.SimulateAlleleCounts([]string{"FGA"}, c); err == nil {
+		t.Fatalf("expected: error for locus without frequencies")
+	}
+	if _, err := nocFreqs.SimulateAlleleCounts(nil, NOCConf{Heights: true}); err == nil {
+		t.Fatalf("expected: error for peak heights without Mu")
+	}
+}
+
+// =============================================================================
+func Test_EstimateNOC(t *testing.T) {
+
+	type test struct {
+		evidence Sample
+		conf     NOCConf
+		want     int
+	}
+
+	c := NOCConf{MaxNOC: 4, Sims: 2000, Seed: 1}
+	h := c
+	h.Heights, h.AT, h.ST = true, 50, 200
+
+	tests := []test{
+		{nocSample(1000, 2, 2, 1, 2, 2, 2, 2, 2), c, 1},
+		{nocSample(1000, 4, 3, 4, 3, 3, 4, 3, 4), c, 2},
+		{nocSample(1000, 5, 4, 5, 6, 4, 5, 4, 5), c, 3},
+		// 5 alleles need 3 persons, although the total count fits 2
+		{nocSample(1000, 5, 3, 4, 3, 3, 3, 3, 4), c, 3},
+		{nocSample(1000, 2, 2, 1, 2, 2, 2, 2, 2), h, 1},
+		{nocSample(1000, 4, 3, 4, 3, 3, 4, 3, 4), h, 2},
+		// no loci with allele frequencies
+		{peaks("stain", "FGA:20:1000"), c, 0},
+		// 9 alleles need more than 4 persons
+		{nocSample(1000, 9, 3, 4, 3, 3, 3, 3, 4), c, 0},
+	}
+
+	for i, tt := range tests {
+		got, err := EstimateNOC(tt.evidence, nocFreqs, tt.conf)
+		if tt.want == 0 {
+			if err == nil {
+				t.Fatalf("test %d: expected: error, got: %v", i, got.NOC)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if got.NOC != tt.want {
+			t.Fatalf("test %d: expected: %v, got: %v (%v)", i, tt.want, got.NOC, got.Posterior)
+		}
+		var sum float64
+		for _, p := range got.Posterior {
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Fatalf("test %d: expected: posterior sums to 1, got: %v", i, sum)
+		}
+	}
+
+	// 5 alleles at a locus exclude 1 and 2 persons
+	got, err := EstimateNOC(nocSample(1000, 5, 3, 4, 3, 3, 3, 3, 4), nocFreqs, c)
+	if err != nil || !math.IsInf(got.LogLik[1], -1) || got.Posterior[0] != 0 || got.Posterior[1] != 0 {
+		t.Fatalf("expected: 1 and 2 persons excluded, got: %v %v (%v)", got.LogLik, got.Posterior, err)
+	}
+}
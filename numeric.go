@@ -0,0 +1,252 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// logGammaPDF returns the log density of the gamma distribution with shape k
+// and scale s at x > 0.
+func logGammaPDF(x, k, s float64) float64 {
+	lg, _ := math.Lgamma(k)
+	return (k-1)*math.Log(x) - x/s - lg - k*math.Log(s)
+}
+
+// gammaCDF returns the cumulative distribution function of the gamma
+// distribution with shape k and scale s at x.
+func gammaCDF(x, k, s float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return gammaP(k, x/s)
+}
+
+// gammaP returns the regularized lower incomplete gamma function P(a, x),
+// computed by its series for x < a+1 and by the continued fraction of
+// Q(a, x) = 1 - P(a, x) otherwise (Numerical Recipes 6.2).
+func gammaP(a, x float64) float64 {
+
+	const (
+		maxIter = 500
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+
+	if x <= 0 {
+		return 0
+	}
+	lg, _ := math.Lgamma(a)
+	norm := math.Exp(-x + a*math.Log(x) - lg)
+
+	if x < a+1 {
+		ap, del := a, 1/a
+		sum := del
+		for i := 0; i < maxIter; i++ {
+			ap++
+			del *= x / ap
+			sum += del
+			if math.Abs(del) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return math.Min(1, sum*norm)
+	}
+
+	// modified Lentz's method
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i <= maxIter; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return math.Max(0, 1-norm*h)
+}
+
+// gammaRand returns a random number of the gamma distribution with shape k
+// and scale s by the method of Marsaglia and Tsang (2000).
+func gammaRand(rng *rand.Rand, k, s float64) float64 {
+
+	if k < 1 { // boost: Gamma(k) = Gamma(k+1) U^(1/k)
+		return gammaRand(rng, k+1, s) * math.Pow(rng.Float64(), 1/k)
+	}
+
+	d := k - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if math.Log(u) < x*x/2+d-d*v+d*math.Log(v) {
+			return d * v * s
+		}
+	}
+}
+
+// logSumExp returns log(Σ exp(x_i)) without overflow; it returns -Inf for no
+// values.
+func logSumExp(x []float64) float64 {
+	max := math.Inf(-1)
+	for _, v := range x {
+		max = math.Max(max, v)
+	}
+	if math.IsInf(max, -1) {
+		return max
+	}
+	var sum float64
+	for _, v := range x {
+		sum += math.Exp(v - max)
+	}
+	return max + math.Log(sum)
+}
+
+// nelderMead minimizes f by the downhill simplex method of Nelder and Mead,
+// starting at x0 with the initial simplex spanned by step. It stops after
+// maxIter iterations or when the function values of the simplex differ by
+// less than tol and returns the minimum found and its function value.
+func nelderMead(f func([]float64) float64, x0, step []float64, maxIter int, tol float64) ([]float64, float64) {
+
+	n := len(x0)
+	if n == 0 {
+		return nil, f(x0)
+	}
+
+	// the simplex and the function values of its vertices
+	simplex := make([][]float64, n+1)
+	values := make([]float64, n+1)
+	for i := range simplex {
+		simplex[i] = append([]float64(nil), x0...)
+		if i > 0 {
+			simplex[i][i-1] += step[i-1]
+		}
+		values[i] = f(simplex[i])
+	}
+
+	// point returns c + t (x - c)
+	point := func(c, x []float64, t float64) []float64 {
+		p := make([]float64, n)
+		for i := range p {
+			p[i] = c[i] + t*(x[i]-c[i])
+		}
+		return p
+	}
+
+	order := make([]int, n+1)
+	for iter := 0; iter < maxIter; iter++ {
+
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+		best, worst, second := order[0], order[n], order[n-1]
+
+		if math.Abs(values[worst]-values[best]) <= tol*(math.Abs(values[best])+tol) {
+			break
+		}
+
+		// centroid of all vertices but the worst
+		c := make([]float64, n)
+		for _, i := range order[:n] {
+			for j := range c {
+				c[j] += simplex[i][j] / float64(n)
+			}
+		}
+
+		r := point(c, simplex[worst], -1)
+		fr := f(r)
+		switch {
+		case fr < values[best]:
+			e := point(c, simplex[worst], -2)
+			if fe := f(e); fe < fr {
+				simplex[worst], values[worst] = e, fe
+			} else {
+				simplex[worst], values[worst] = r, fr
+			}
+		case fr < values[second]:
+			simplex[worst], values[worst] = r, fr
+		default:
+			k := point(c, simplex[worst], 0.5)
+			if fr < values[worst] {
+				k = point(c, r, 0.5)
+			}
+			if fk := f(k); fk < math.Min(fr, values[worst]) {
+				simplex[worst], values[worst] = k, fk
+				continue
+			}
+			// shrink towards the best vertex
+			for _, i := range order[1:] {
+				simplex[i] = point(simplex[best], simplex[i], 0.5)
+				values[i] = f(simplex[i])
+			}
+		}
+	}
+
+	best := 0
+	for i := range values {
+		if values[i] < values[best] {
+			best = i
+		}
+	}
+	return simplex[best], values[best]
+}
+
+// solveLinear solves the linear system a x = b by Gaussian elimination with
+// partial pivoting. It returns false if a is singular. a and b are modified.
+func solveLinear(a [][]float64, b []float64) ([]float64, bool) {
+
+	n := len(b)
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(a[i][k]) > math.Abs(a[p][k]) {
+				p = i
+			}
+		}
+		if math.Abs(a[p][k]) < 1e-300 {
+			return nil, false
+		}
+		a[k], a[p] = a[p], a[k]
+		b[k], b[p] = b[p], b[k]
+
+		for i := k + 1; i < n; i++ {
+			f := a[i][k] / a[k][k]
+			for j := k; j < n; j++ {
+				a[i][j] -= f * a[k][j]
+			}
+			b[i] -= f * b[k]
+		}
+	}
+
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := b[i]
+		for j := i + 1; j < n; j++ {
+			sum -= a[i][j] * x[j]
+		}
+		x[i] = sum / a[i][i]
+	}
+	return x, true
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"testing"
+)
+
+// =============================================================================
+func Test_gammaP(t *testing.T) {
+
+	type test struct {
+		a, x float64
+		want float64
+	}
+
+	tests := []test{
+		{1, 0.5, 1 - math.Exp(-0.5)},   // 1: exponential, series
+		{1, 4, 1 - math.Exp(-4)},       // 2: exponential, continued fraction
+		{0.5, 2, math.Erf(math.Sqrt2)}, // 3: P(1/2, x) = erf(√x)
+		{3, 2.5, 1 - math.Exp(-2.5)*(1+2.5+2.5*2.5/2)},
+		{10, 0, 0},
+	}
+
+	for i, test := range tests {
+		if res := gammaP(test.a, test.x); math.Abs(res-test.want) > 1e-10 {
+			t.Fatalf("test %d: expected: %v, got: %v", i+1, test.want, res)
+		}
+	}
+}
+
+// =============================================================================
+func Test_nelderMead(t *testing.T) {
+
+	rosenbrock := func(x []float64) float64 {
+		return 100*math.Pow(x[1]-x[0]*x[0], 2) + math.Pow(1-x[0], 2)
+	}
+
+	x, v := nelderMead(rosenbrock, []float64{-1.2, 1}, []float64{0.5, 0.5}, 5000, 1e-14)
+	if math.Abs(x[0]-1) > 1e-3 || math.Abs(x[1]-1) > 1e-3 || v > 1e-6 {
+		t.Fatalf("expected: [1 1], got: %v (%v)", x, v)
+	}
+}
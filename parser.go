@@ -5,7 +5,9 @@ package forge
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -17,51 +19,103 @@ import (
 // "Marker", and "Allele 1" as well as every column provided in info.
 func ReadGM(f, sID, kitDir string, info []string) ([]Sample, error) {
 
-	csvHeader, csvBody, err := readFile(f)
+	csvF, err := os.Open(f)
 	if err != nil {
-		return nil, fmt.Errorf(`reading %v fails: %v`, f, err)
+		return nil, fmt.Errorf(`reading %v fails: %w`, f, err)
 	}
+	defer func(csvF *os.File) {
+		err := csvF.Close()
+		if err != nil {
+			// TODO: handle error
+		}
+	}(csvF)
 
-	idx, err := indexHeader(sID, csvHeader, info)
+	return ReadGMFrom(csvF, f, sID, kitDir, info)
+}
+
+// ReadGMFrom parses Genemapper CSV data from r into a slice of its individual
+// sample objects, just like ReadGM. The rows are streamed from r, i.e. the
+// data is never held in memory as a whole. The source will be stored as
+// Source of each sample (e.g. the name of the uploaded file). If a cell cannot
+// be parsed, the returned error is a *ParseError.
+func ReadGMFrom(r io.Reader, source, sID, kitDir string, info []string) ([]Sample, error) {
+
+	csvR := newGMReader(r)
+
+	csvHeader, err := csvR.Read()
+	if err == io.EOF {
+		return nil, &ParseError{Source: source, Line: 1, Err: errEmptyInput}
+	}
 	if err != nil {
-		return nil, fmt.Errorf(`indexing %v fails: %v`, f, err)
+		return nil, newCSVParseError(source, err)
 	}
 
-	samples, err := parseSamples(sID, f, kitDir, csvBody, idx)
+	idx, err := indexHeader(sID, csvHeader, info)
 	if err != nil {
-		return nil, fmt.Errorf(`processing %v fails: %v`, f, err)
+		return nil, &ParseError{Source: source, Line: 1, Err: err}
 	}
 
-	return samples, nil
+	return parseSamples(sID, source, kitDir, info, csvR, idx)
 }
 
-// readFile reads the csv file f with field separator sep. It returns the first
-// line (header) separated from the data part of the csv file (body).
-func readFile(f string) (csvHeader []string, csvBody [][]string, err error) {
-	csvF, err := os.Open(f)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer func(csvF *os.File) {
-		err = csvF.Close()
-		if err != nil {
-			// TODO: handle error
-		}
-	}(csvF)
-
-	fReader := csv.NewReader(csvF)
-	fReader.Comma = '\t' // convert string to rune set
+// newGMReader returns a csv.Reader for tab separated Genemapper data from r.
+func newGMReader(r io.Reader) *csv.Reader {
+	csvR := csv.NewReader(r)
+	csvR.Comma = '\t' // convert string to rune set
 	// field set to the number of fields in the first record (i. e. line)
-	fReader.FieldsPerRecord = 0
+	csvR.FieldsPerRecord = 0
 	// quote may NOT appear in an unquoted field
-	fReader.LazyQuotes = false
+	csvR.LazyQuotes = false
+	// the fields of a record are copied into samples and loci, hence we can
+	// spare the allocation of a new slice per row.
+	csvR.ReuseRecord = true
 
-	csvData, err := fReader.ReadAll()
-	if err != nil {
-		return nil, nil, err
+	return csvR
+}
+
+// errEmptyInput is returned if the CSV data does not even contain a header.
+var errEmptyInput = errors.New("empty input")
+
+// ParseError describes a failure to import a cell of Genemapper CSV data. It
+// provides the position of the cell and the sample and marker it belongs to.
+type ParseError struct {
+	Source string // source of the data, e.g. the file name
+	Line   int    // line number (1-based) of the cell
+	Column string // column header of the cell, e.g. "Height 3"
+	Sample string // sample ID of the row
+	Marker string // marker of the row
+	Err    error  // the underlying error
+}
+
+// Error returns the error message of e including the cell position.
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%v: line %v", e.Source, e.Line)
+	if e.Column != "" {
+		msg += fmt.Sprintf(", column %q", e.Column)
+	}
+	if e.Sample != "" {
+		msg += fmt.Sprintf(", sample %v", e.Sample)
+	}
+	if e.Marker != "" {
+		msg += fmt.Sprintf(", marker %v", e.Marker)
+	}
+
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error of e.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newCSVParseError converts an error of the csv package into a ParseError.
+func newCSVParseError(source string, err error) *ParseError {
+	var csvErr *csv.ParseError
+	if errors.As(err, &csvErr) {
+		return &ParseError{Source: source, Line: csvErr.Line, Err: csvErr.Err}
 	}
 
-	return csvData[0], csvData[1:], nil
+	return &ParseError{Source: source, Err: err}
 }
 
 // index holds the column number (value) of columns of interest (key).
@@ -69,7 +123,8 @@ type index map[string]int
 
 // newIndex returns an object index which stores the column number of the sample
 // ID, the marker and the first allele. Additionally, it stores the offsets of
-// Height, Size, and Area information as well as the number of alleles.
+// Height, Size, Area, and Comment information as well as the number of
+// alleles.
 func newIndex(sID string) index {
 	return index{
 		sID:           -1,
@@ -79,15 +134,35 @@ func newIndex(sID string) index {
 
 		// Difference between an allele column and its respective area, height,
 		// and size columns.
-		"AreaOffset":   -1,
-		"HeightOffset": -1,
-		"SizeOffset":   -1,
+		"AreaOffset":    -1,
+		"HeightOffset":  -1,
+		"SizeOffset":    -1,
+		"CommentOffset": -1,
+	}
+}
+
+// gmColumns are the optional sample and marker columns of a GeneMapper ID-X
+// export. They are indexed whenever they are present in the header.
+var gmColumns = []string{"Sample File", "Panel", "Dye", "Run Name"}
+
+// isOptionalColumn returns true if the header h is one of the optional columns
+// of a GeneMapper ID-X export, i.e. one of gmColumns or a PQV column, or a
+// quality flag of forge.
+func isOptionalColumn(h string) bool {
+	for _, cols := range [][]string{gmColumns, locusPQVs, samplePQVs, forgePQVs} {
+		for _, c := range cols {
+			if h == c {
+				return true
+			}
+		}
 	}
+	return false
 }
 
 // indexHeader returns the column numbers of all columns of interest (e.g. the
 // sample's ID sID and further information in info such as run number) from
-// the file's first row header.
+// the file's first row header. The optional columns of GeneMapper ID-X exports
+// (see isOptionalColumn) are indexed only if they are present.
 func indexHeader(sID string, header, info []string) (index, error) {
 	idx := newIndex(sID)
 	for _, i := range info { // add all info columns to index
@@ -96,7 +171,7 @@ func indexHeader(sID string, header, info []string) (index, error) {
 
 	var noAlleles int
 	for i, h := range header {
-		if _, ok := idx[h]; ok {
+		if _, ok := idx[h]; ok || isOptionalColumn(h) {
 			idx[h] = i
 		}
 
@@ -107,6 +182,8 @@ func indexHeader(sID string, header, info []string) (index, error) {
 			idx["HeightOffset"] = i - idx["Allele 1"]
 		case "Size 1":
 			idx["SizeOffset"] = i - idx["Allele 1"]
+		case "Comment 1":
+			idx["CommentOffset"] = i - idx["Allele 1"]
 		}
 
 		if strings.HasPrefix(h, "Allele ") {
@@ -130,32 +207,55 @@ func indexHeader(sID string, header, info []string) (index, error) {
 	return idx, nil
 }
 
-// parseSamples parses the body of the csv data (csvBody) given the sample ID
-// column name (sID) and index idx. It returns a slice of Sample objects.
-func parseSamples(sID, f, kitDir string, csvBody [][]string, idx index) ([]Sample, error) {
+// parseSamples parses the rows of the csv data read by csvR given the sample
+// ID column name (sID) and index idx. It returns a slice of Sample objects in
+// the order in which they first appear in the data.
+func parseSamples(sID, source, kitDir string, info []string, csvR *csv.Reader, idx index) ([]Sample, error) {
 
+	var ids []string
 	sampleMap := make(map[string]Sample)
-	for _, line := range csvBody {
+	for {
+		line, err := csvR.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, newCSVParseError(source, err)
+		}
+
+		lineNo, _ := csvR.FieldPos(0)
 		id := line[idx[sID]]
 
 		// If the current line l contains a new sample.
 		if _, ok := sampleMap[id]; !ok {
-			s := NewSample(id, f)
-			for k, v := range idx {
-				// adding only additional data requested by caller to the sample
-				// (e.g. 'Dye', which is not in the newIndex()). The loci fields
-				// (which are present in newIndex()) will be filled later.
-				if _, ok = newIndex(sID)[k]; !ok {
-					s.Info[k] = line[v]
-				}
+			s := NewSample(id, source)
+			// adding only additional data requested by caller to the sample
+			// (e.g. 'Dye'). The loci fields will be filled later.
+			for _, k := range info {
+				s.Info[k] = line[idx[k]]
 			}
+			s.File = cell(line, idx, "Sample File")
+			s.Panel = cell(line, idx, "Panel")
+			s.Run = cell(line, idx, "Run Name")
 			sampleMap[id] = s
+			ids = append(ids, id)
 		}
 
 		locus, err := parseLocus(line, idx)
+		if err == nil {
+			err = parseSamplePQVs(sampleMap, id, line, idx)
+		}
 		if err != nil {
-			return nil, fmt.Errorf(`processing sample %v fails at locus %v: %v`,
-				id, line[idx["Marker"]], err)
+			var pErr *ParseError
+			if !errors.As(err, &pErr) {
+				pErr = &ParseError{Err: err}
+			}
+			pErr.Source = source
+			pErr.Line = lineNo
+			pErr.Sample = id
+			pErr.Marker = line[idx["Marker"]]
+
+			return nil, pErr
 		}
 
 		s := sampleMap[id]
@@ -165,7 +265,8 @@ func parseSamples(sID, f, kitDir string, csvBody [][]string, idx index) ([]Sampl
 
 	// convert Sample map to slice
 	var samples []Sample
-	for _, s := range sampleMap {
+	for _, id := range ids {
+		s := sampleMap[id]
 		if err := s.InferKit(kitDir); err != nil {
 			return nil, fmt.Errorf(`inferring kit for sample %v fails: %v`, s.ID, err)
 		}
@@ -175,16 +276,76 @@ func parseSamples(sID, f, kitDir string, csvBody [][]string, idx index) ([]Sampl
 	return samples, nil
 }
 
+// cell returns the field of column col in csv line l given index idx. It
+// returns an empty string if the column is not present.
+func cell(l []string, idx index, col string) string {
+	if i, ok := idx[col]; ok && i != -1 {
+		return l[i]
+	}
+	return ""
+}
+
+// parsePQVs reads the values of the PQV columns names from csv line l given
+// index idx. It returns nil if none of the columns is present.
+func parsePQVs(l []string, idx index, names []string) (PQVs, error) {
+	var p PQVs
+	for _, n := range names {
+		if _, ok := idx[n]; !ok {
+			continue
+		}
+
+		q, err := ParseQuality(l[idx[n]])
+		if err != nil {
+			return nil, &ParseError{Column: n, Err: err}
+		}
+		if p == nil {
+			p = make(PQVs)
+		}
+		p[n] = q
+	}
+	return p, nil
+}
+
+// parseSamplePQVs reads the sample-level PQVs from csv line l given index idx
+// and stores them in sample id of sampleMap. Since they are repeated on every
+// row of a sample, the worst quality found on any row is kept.
+func parseSamplePQVs(sampleMap map[string]Sample, id string, l []string, idx index) error {
+	p, err := parsePQVs(l, idx, samplePQVs)
+	if err != nil || p == nil {
+		return err
+	}
+
+	s := sampleMap[id]
+	if s.PQVs == nil {
+		s.PQVs = make(PQVs)
+	}
+	for n, q := range p {
+		if q > s.PQVs[n] {
+			s.PQVs[n] = q
+		}
+	}
+	sampleMap[id] = s
+
+	return nil
+}
+
 // processLocus reads csv line l given index idx and returns its data as Locus
 // object. All marker names will be converted to upper to avoid "vWA" vs "VWA"
-// confusions.
+// confusions. The dye and the allele PQVs are read if present.
 func parseLocus(l []string, idx index) (Locus, error) {
 
 	if l[idx["Marker"]] == "" {
-		return Locus{}, fmt.Errorf("marker name missing")
+		return Locus{}, &ParseError{Column: "Marker", Err: errors.New("marker name missing")}
 	}
 
 	loc := NewLocus(strings.ToUpper(l[idx["Marker"]]))
+	loc.Dye = cell(l, idx, "Dye")
+
+	pqvs, err := parsePQVs(l, idx, append(append([]string(nil), locusPQVs...), forgePQVs...))
+	if err != nil {
+		return Locus{}, err
+	}
+	loc.PQVs = pqvs
 
 	for n := 1; n <= idx["NoOfAlleles"]; n++ {
 		// No allele information at the position of the n_th allele at this
@@ -205,11 +366,16 @@ func parseLocus(l []string, idx index) (Locus, error) {
 }
 
 // offsets is declared only to improve readability in the following functions.
+// It holds the offsets of the numeric allele fields.
 var offsets = []string{"AreaOffset", "HeightOffset", "SizeOffset"}
 
+// fieldOffsets holds the offsets of all allele fields including comments.
+var fieldOffsets = append(offsets, "CommentOffset")
+
 // processAllele returns the Allele object of the n_th allele in csv line l
 // given index idx. Any information field that is not present in the file (i.e.
-// idx[field] = -1) will return 0.
+// idx[field] = -1) will return 0. If a field cannot be parsed, the returned
+// error is a *ParseError naming the column of the field.
 func parseAllele(n int, l []string, idx index) (Allele, error) {
 
 	// aPos is the column number of the n_th allele in line l.
@@ -223,7 +389,10 @@ func parseAllele(n int, l []string, idx index) (Allele, error) {
 
 			info, err := strconv.ParseFloat(l[aPos+idx[offset]], 64)
 			if err != nil {
-				return Allele{}, err
+				return Allele{}, &ParseError{
+					Column: strings.TrimSuffix(offset, "Offset") + " " + strconv.Itoa(n),
+					Err:    err,
+				}
 			}
 
 			switch offset {
@@ -237,11 +406,17 @@ func parseAllele(n int, l []string, idx index) (Allele, error) {
 		}
 	}
 
+	var comment string
+	if idx["CommentOffset"] != -1 {
+		comment = l[aPos+idx["CommentOffset"]]
+	}
+
 	return Allele{
-		ID:     A2Float(l[aPos]),
-		Area:   area,
-		Height: height,
-		Size:   size,
+		ID:      A2ID(l[aPos]),
+		Area:    area,
+		Height:  height,
+		Size:    size,
+		Comment: comment,
 	}, nil
 }
 
@@ -250,7 +425,7 @@ func parseAllele(n int, l []string, idx index) (Allele, error) {
 // (e.g. A1, A2, A3, H1, H2, H3).
 func isCollated(idx index) bool {
 
-	for _, offset := range offsets {
+	for _, offset := range fieldOffsets {
 		if idx[offset] == 1 {
 			return true
 		}
@@ -274,7 +449,7 @@ func allelePos(n int, idx index) int {
 
 	// IS collated A1, S1, H1, A2, S2, H2
 	var fields int
-	for _, offset := range offsets {
+	for _, offset := range fieldOffsets {
 		if idx[offset] != -1 {
 			fields++
 		}
@@ -286,19 +461,62 @@ func allelePos(n int, idx index) int {
 // and returns the profiles as samples.
 func ReadGMRefs(f string) ([]Sample, error) {
 
-	// We don't need the header for it is known, Khaleesi.
-	_, csvBody, err := readFile(f)
+	return readFile(f, func(r io.Reader) ([]Sample, error) {
+		return ReadGMRefsFrom(r, f)
+	})
+}
+
+// readFile opens file f and passes it to read. An error closing f is
+// returned if read succeeds.
+func readFile(f string, read func(r io.Reader) ([]Sample, error)) ([]Sample, error) {
+
+	file, err := os.Open(f)
 	if err != nil {
-		return nil, fmt.Errorf(`reading %v fails: %v`, f, err)
+		return nil, fmt.Errorf(`reading %v fails: %w`, f, err)
+	}
+
+	samples, err := read(file)
+	if cErr := file.Close(); err == nil && cErr != nil {
+		return nil, fmt.Errorf(`closing %v fails: %w`, f, cErr)
+	}
+	return samples, err
+}
+
+// ReadGMRefsFrom reads reference profiles as exported from Genemapper from r
+// and returns the profiles as samples with the given source. The rows are
+// streamed from r. If a row cannot be parsed, the returned error is a
+// *ParseError.
+func ReadGMRefsFrom(r io.Reader, source string) ([]Sample, error) {
+
+	csvR := newGMReader(r)
+
+	// We don't need the header for it is known, Khaleesi.
+	if _, err := csvR.Read(); err != nil {
+		if err == io.EOF {
+			return nil, &ParseError{Source: source, Line: 1, Err: errEmptyInput}
+		}
+		return nil, newCSVParseError(source, err)
 	}
 
+	var ids []string
 	sMap := make(map[string]Sample)
 	// walk through individual lines
-	for _, l := range csvBody {
+	for {
+		l, err := csvR.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, newCSVParseError(source, err)
+		}
 
+		lineNo, _ := csvR.FieldPos(0)
 		if len(l) != 4 {
-			return nil, fmt.Errorf(`reading %v fails: expect 4 columns, got %v`,
-				f, len(l))
+			return nil, &ParseError{
+				Source: source,
+				Line:   lineNo,
+				Err:    fmt.Errorf(`expect 4 columns, got %v`, len(l)),
+			}
 		}
 
 		id := l[0]
@@ -306,16 +524,18 @@ func ReadGMRefs(f string) ([]Sample, error) {
 
 		s := Sample{
 			ID:     id,
-			Source: f,
+			Source: source,
 		}
 		// sample ID already in map, retrieve the sample
 		if _, ok := sMap[id]; ok {
 			s = sMap[id]
+		} else {
+			ids = append(ids, id)
 		}
 
 		loc := NewLocus(l[2])
 		for _, a := range alleles {
-			loc.AddAllele(Allele{ID: A2Float(a)})
+			loc.AddAllele(Allele{ID: A2ID(a)})
 		}
 
 		// add locus info and data to map
@@ -326,8 +546,8 @@ func ReadGMRefs(f string) ([]Sample, error) {
 
 	// convert Sample map to list
 	var samples []Sample
-	for _, s := range sMap {
-		samples = append(samples, s)
+	for _, id := range ids {
+		samples = append(samples, sMap[id])
 	}
 
 	return samples, nil
@@ -1,7 +1,12 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
 package forge
 
 import (
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -78,14 +83,15 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{"Dye"},
 			87, // n_the allele
 			index{
-				"Sample File":  0,
-				"Marker":       1,
-				"Allele 1":     3,
-				"Dye":          2,
-				"AreaOffset":   -1,
-				"HeightOffset": 2,
-				"SizeOffset":   1,
-				"NoOfAlleles":  100,
+				"Sample File":   0,
+				"Marker":        1,
+				"Allele 1":      3,
+				"Dye":           2,
+				"AreaOffset":    -1,
+				"HeightOffset":  2,
+				"SizeOffset":    1,
+				"CommentOffset": -1,
+				"NoOfAlleles":   100,
 			},
 			true,
 			261,
@@ -97,13 +103,16 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{},
 			2, // n_the allele
 			index{
-				"Sample Name":  1,
-				"Marker":       3,
-				"Allele 1":     4,
-				"AreaOffset":   -1,
-				"HeightOffset": 2,
-				"SizeOffset":   -1,
-				"NoOfAlleles":  2,
+				"Sample Name":   1,
+				"Marker":        3,
+				"Allele 1":      4,
+				"AreaOffset":    -1,
+				"HeightOffset":  2,
+				"SizeOffset":    -1,
+				"CommentOffset": -1,
+				"NoOfAlleles":   2,
+				"Sample File":   0,
+				"Run Name":      2,
 			},
 			false,
 			5,
@@ -115,14 +124,16 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{"Run Name"},
 			1, // n_the allele
 			index{
-				"Sample Name":  1,
-				"Marker":       3,
-				"Allele 1":     4,
-				"Run Name":     2,
-				"AreaOffset":   -1,
-				"HeightOffset": -1,
-				"SizeOffset":   -1,
-				"NoOfAlleles":  2,
+				"Sample Name":   1,
+				"Marker":        3,
+				"Allele 1":      4,
+				"Run Name":      2,
+				"AreaOffset":    -1,
+				"HeightOffset":  -1,
+				"SizeOffset":    -1,
+				"CommentOffset": -1,
+				"NoOfAlleles":   2,
+				"Sample File":   0,
 			},
 			false,
 			4,
@@ -133,13 +144,16 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{},
 			2, // n_the allele
 			index{
-				"Sample Name":  1,
-				"Marker":       3,
-				"Allele 1":     4,
-				"AreaOffset":   -1,
-				"HeightOffset": 1,
-				"SizeOffset":   -1,
-				"NoOfAlleles":  2,
+				"Sample Name":   1,
+				"Marker":        3,
+				"Allele 1":      4,
+				"AreaOffset":    -1,
+				"HeightOffset":  1,
+				"SizeOffset":    -1,
+				"CommentOffset": -1,
+				"NoOfAlleles":   2,
+				"Sample File":   0,
+				"Run Name":      2,
 			},
 			true,
 			6,
@@ -151,14 +165,15 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{"Run Name"},
 			1, // n_the allele
 			index{
-				"Sample File":  0,
-				"Marker":       3,
-				"Allele 1":     4,
-				"AreaOffset":   -1,
-				"HeightOffset": 1,
-				"SizeOffset":   -1,
-				"NoOfAlleles":  1,
-				"Run Name":     2,
+				"Sample File":   0,
+				"Marker":        3,
+				"Allele 1":      4,
+				"AreaOffset":    -1,
+				"HeightOffset":  1,
+				"SizeOffset":    -1,
+				"CommentOffset": -1,
+				"NoOfAlleles":   1,
+				"Run Name":      2,
 			},
 			true,
 			4,
@@ -171,13 +186,16 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{},
 			2, // n_the allele
 			index{
-				"Sample Name":  1,
-				"Marker":       3,
-				"Allele 1":     4,
-				"AreaOffset":   -1,
-				"HeightOffset": 4,
-				"SizeOffset":   2,
-				"NoOfAlleles":  2,
+				"Sample Name":   1,
+				"Marker":        3,
+				"Allele 1":      4,
+				"AreaOffset":    -1,
+				"HeightOffset":  4,
+				"SizeOffset":    2,
+				"CommentOffset": -1,
+				"NoOfAlleles":   2,
+				"Sample File":   0,
+				"Run Name":      2,
 			},
 			false,
 			5,
@@ -190,13 +208,16 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{},
 			2, // n_the allele
 			index{
-				"Sample Name":  1,
-				"Marker":       3,
-				"Allele 1":     4,
-				"AreaOffset":   1,
-				"HeightOffset": 2,
-				"SizeOffset":   -1,
-				"NoOfAlleles":  2,
+				"Sample Name":   1,
+				"Marker":        3,
+				"Allele 1":      4,
+				"AreaOffset":    1,
+				"HeightOffset":  2,
+				"SizeOffset":    -1,
+				"CommentOffset": -1,
+				"NoOfAlleles":   2,
+				"Sample File":   0,
+				"Run Name":      2,
 			},
 			true,
 			7,
@@ -213,15 +234,16 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{"Dye", "Run Name"},
 			10, // n_the allele
 			index{
-				"Sample File":  0,
-				"Marker":       3,
-				"Allele 1":     5,
-				"AreaOffset":   -1,
-				"HeightOffset": 20,
-				"SizeOffset":   10,
-				"NoOfAlleles":  10,
-				"Dye":          4,
-				"Run Name":     2,
+				"Sample File":   0,
+				"Marker":        3,
+				"Allele 1":      5,
+				"AreaOffset":    -1,
+				"HeightOffset":  20,
+				"SizeOffset":    10,
+				"CommentOffset": -1,
+				"NoOfAlleles":   10,
+				"Dye":           4,
+				"Run Name":      2,
 			},
 			false,
 			14,
@@ -237,14 +259,17 @@ func Test_indexHeader_isCollated_allelePos(t *testing.T) {
 			[]string{"Dye"},
 			8, // n_the allele
 			index{
-				"Sample Name":  1,
-				"Marker":       3,
-				"Allele 1":     5,
-				"AreaOffset":   1,
-				"HeightOffset": 2,
-				"SizeOffset":   -1,
-				"NoOfAlleles":  8,
-				"Dye":          4,
+				"Sample Name":   1,
+				"Marker":        3,
+				"Allele 1":      5,
+				"AreaOffset":    1,
+				"HeightOffset":  2,
+				"SizeOffset":    -1,
+				"CommentOffset": -1,
+				"NoOfAlleles":   8,
+				"Dye":           4,
+				"Sample File":   0,
+				"Run Name":      2,
 			},
 			true,
 			26,
@@ -327,19 +352,20 @@ func Test_parseLocus_parseAllele(t *testing.T) {
 				"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "",
 				"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "",
 				"", "", "", "", "", "", "", "", "", "", "", "", "", ""},
-			Allele{ID: -999, Area: 0, Height: 4, Size: 123.54},
+			Allele{ID: A2ID("OL"), Area: 0, Height: 4, Size: 123.54},
 			// Note: alleles are sorted by ID by default!
 			Locus{ID: "VWA", Alleles: []Allele{
-				{ID: -999, Area: 0, Height: 3, Size: 114},
-				{ID: -999, Area: 0, Height: 4, Size: 123.54},
-				{ID: -999, Area: 0, Height: 1, Size: 159.16},
-				{ID: -999, Area: 0, Height: 3, Size: 168.78},
-				{ID: -999, Area: 0, Height: 3, Size: 176.84},
-				{ID: 7, Area: 0, Height: 2, Size: 117.98},
-				{ID: 12, Area: 0, Height: 5, Size: 137.64},
-				{ID: 13, Area: 0, Height: 40, Size: 141.91},
-				{ID: 14, Area: 0, Height: 40, Size: 146.24},
-				{ID: 15, Area: 0, Height: 21, Size: 150.33}}},
+				{ID: A2ID("7"), Area: 0, Height: 2, Size: 117.98},
+				{ID: A2ID("12"), Area: 0, Height: 5, Size: 137.64},
+				{ID: A2ID("13"), Area: 0, Height: 40, Size: 141.91},
+				{ID: A2ID("14"), Area: 0, Height: 40, Size: 146.24},
+				{ID: A2ID("15"), Area: 0, Height: 21, Size: 150.33},
+				{ID: A2ID("OL"), Area: 0, Height: 3, Size: 114},
+				{ID: A2ID("OL"), Area: 0, Height: 4, Size: 123.54},
+				{ID: A2ID("OL"), Area: 0, Height: 1, Size: 159.16},
+				{ID: A2ID("OL"), Area: 0, Height: 3, Size: 168.78},
+				{ID: A2ID("OL"), Area: 0, Height: 3, Size: 176.84}},
+				Dye: "B"},
 		},
 	}
 
@@ -365,3 +391,217 @@ func Test_parseLocus_parseAllele(t *testing.T) {
 
 	}
 }
+
+// =============================================================================
+func Test_ReadGMFrom(t *testing.T) {
+
+	type test struct {
+		in   string
+		want []Sample
+	}
+
+	tests := []test{
+		{
+			"Sample Name\tMarker\tDye\tAllele 1\tHeight 1\tAllele 2\tHeight 2\n" +
+				"S2\tVWA\tB\t15\t1200\t17\t1100\n" +
+				"S2\tAMEL\tY\tX\t800\t\t\n" +
+				"S1\tvWA\tB\t16\t300\t\t\n",
+			[]Sample{
+				{
+					ID:     "S2",
+					Info:   Info{"Dye": "B"},
+					Kit:    Kit{ID: "unknown Kit", STRs: []STR{{ID: "VWA"}, {ID: "AMEL"}}},
+					Source: "upload",
+					Loci: []Locus{
+						{ID: "VWA", Alleles: []Allele{{ID: A2ID("15"), Height: 1200}, {ID: A2ID("17"), Height: 1100}}, Dye: "B"},
+						{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X"), Height: 800}}, Dye: "Y"},
+					},
+				},
+				{
+					ID:     "S1",
+					Info:   Info{"Dye": "B"},
+					Kit:    Kit{ID: "unknown Kit", STRs: []STR{{ID: "VWA"}}},
+					Source: "upload",
+					Loci: []Locus{
+						{ID: "VWA", Alleles: []Allele{{ID: A2ID("16"), Height: 300}}, Dye: "B"},
+					},
+				},
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		res, err := ReadGMFrom(strings.NewReader(tc.in), "upload",
+			"Sample Name", "", []string{"Dye"})
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i+1, err)
+		}
+		if !reflect.DeepEqual(tc.want, res) {
+			t.Fatalf("test %d: expected: %v, got: %v", i+1, tc.want, res)
+		}
+	}
+}
+
+// =============================================================================
+func Test_ReadGMFrom_ParseError(t *testing.T) {
+
+	type test struct {
+		in   string
+		want ParseError
+	}
+
+	tests := []test{
+		{ // 1: broken height
+			"Sample Name\tMarker\tAllele 1\tHeight 1\tAllele 2\tHeight 2\n" +
+				"S1\tVWA\t15\t1200\t17\t1100\n" +
+				"S1\tTH01\t6\t900\t9.3\t12x3\n",
+			ParseError{Source: "upload", Line: 3, Column: "Height 2",
+				Sample: "S1", Marker: "TH01"},
+		},
+		{ // 2: missing marker
+			"Sample Name\tMarker\tAllele 1\tHeight 1\n" +
+				"S1\tVWA\t15\t1200\n" +
+				"S1\tTH01\t6\t900\n" +
+				"S2\t\t6\t900\n",
+			ParseError{Source: "upload", Line: 4, Column: "Marker",
+				Sample: "S2"},
+		},
+		{ // 3: missing header column
+			"Sample File\tMarker\tAllele 1\tHeight 1\n",
+			ParseError{Source: "upload", Line: 1},
+		},
+		{ // 4: wrong number of fields
+			"Sample Name\tMarker\tAllele 1\tHeight 1\n" +
+				"S1\tVWA\t15\n",
+			ParseError{Source: "upload", Line: 2},
+		},
+		{ // 5: empty input
+			"",
+			ParseError{Source: "upload", Line: 1},
+		},
+	}
+
+	for i, tc := range tests {
+		_, err := ReadGMFrom(strings.NewReader(tc.in), "upload",
+			"Sample Name", "", nil)
+
+		var res *ParseError
+		if !errors.As(err, &res) {
+			t.Fatalf("test %d: expected *ParseError, got: %v", i+1, err)
+		}
+		if res.Err == nil {
+			t.Fatalf("test %d: expected underlying error, got nil", i+1)
+		}
+
+		res.Err = nil
+		if !reflect.DeepEqual(tc.want, *res) {
+			t.Fatalf("test %d: expected: %+v, got: %+v", i+1, tc.want, *res)
+		}
+	}
+}
+
+// =============================================================================
+func Test_ReadGMRefsFrom(t *testing.T) {
+
+	in := "Sample Name\tPanel\tMarker\tAlleles\n" +
+		"R1\tNGM\tVWA\t15, 17\n" +
+		"R2\tNGM\tVWA\t16\n" +
+		"R1\tNGM\tAMEL\tX, Y\n"
+
+	want := []Sample{
+		{
+			ID:     "R1",
+			Source: "refs",
+			Loci: []Locus{
+				{ID: "VWA", Alleles: []Allele{{ID: A2ID("15")}, {ID: A2ID("17")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+			},
+		},
+		{
+			ID:     "R2",
+			Source: "refs",
+			Loci:   []Locus{{ID: "VWA", Alleles: []Allele{{ID: A2ID("16")}}}},
+		},
+	}
+
+	res, err := ReadGMRefsFrom(strings.NewReader(in), "refs")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(want, res) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+
+	_, err = ReadGMRefsFrom(strings.NewReader(in+"R3\tNGM\tTH01\n"), "refs")
+	var pErr *ParseError
+	if !errors.As(err, &pErr) || pErr.Line != 5 {
+		t.Fatalf("expected *ParseError at line 5, got: %v", err)
+	}
+}
+
+// =============================================================================
+func Test_ReadGMFrom_IDX(t *testing.T) {
+
+	in := "Sample File\tSample Name\tPanel\tMarker\tDye\t" +
+		"Allele 1\tSize 1\tHeight 1\tComment 1\t" +
+		"Allele 2\tSize 2\tHeight 2\tComment 2\t" +
+		"AN\tADO\tAE\tSQ\tRun Name\n" +
+		"A01.hid\tS1\tGF_v1X\tVWA\tB\t" +
+		"15\t151.2\t1200\t\tOL\t153.7\t80\tspike?\t" +
+		"Check\tPass\tPass\tPass\tRun_7\n" +
+		"A01.hid\tS1\tGF_v1X\tTH01\tG\t" +
+		"6\t180.1\t900\t\t\t\t\t\t" +
+		"Pass\tLow Quality\tPass\tCheck\tRun_7\n"
+
+	want := []Sample{
+		{
+			ID:     "S1",
+			Info:   Info{},
+			Kit:    Kit{ID: "unknown Kit", STRs: []STR{{ID: "VWA"}, {ID: "TH01"}}},
+			Source: "upload",
+			File:   "A01.hid",
+			Panel:  "GF_v1X",
+			Run:    "Run_7",
+			PQVs:   PQVs{"SQ": CHECK},
+			Loci: []Locus{
+				{
+					ID: "VWA",
+					Alleles: []Allele{
+						{ID: A2ID("15"), Size: 151.2, Height: 1200},
+						{ID: A2ID("OL"), Size: 153.7, Height: 80, Comment: "spike?"}},
+					Dye:  "B",
+					PQVs: PQVs{"AN": CHECK, "ADO": PASS, "AE": PASS},
+				},
+				{
+					ID:      "TH01",
+					Alleles: []Allele{{ID: A2ID("6"), Size: 180.1, Height: 900}},
+					Dye:     "G",
+					PQVs:    PQVs{"AN": PASS, "ADO": LOWQUALITY, "AE": PASS},
+				},
+			},
+		},
+	}
+
+	res, err := ReadGMFrom(strings.NewReader(in), "upload", "Sample Name", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(want, res) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+	if !res[0].Locus("VWA").Alleles[1].IsOffLadder() {
+		t.Fatalf("expected OL allele at VWA")
+	}
+	if !res[0].Locus("TH01").IsLowQuality() || res[0].Locus("VWA").IsLowQuality() {
+		t.Fatalf("expected only TH01 to be of low quality")
+	}
+
+	// unknown quality values are reported with their column
+	_, err = ReadGMFrom(strings.NewReader(strings.Replace(in, "Low Quality", "bad", 1)),
+		"upload", "Sample Name", "", nil)
+	var pErr *ParseError
+	if !errors.As(err, &pErr) || pErr.Column != "ADO" || pErr.Line != 3 ||
+		pErr.Marker != "TH01" {
+		t.Fatalf("expected *ParseError in column ADO at line 3, got: %v", err)
+	}
+}
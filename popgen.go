@@ -0,0 +1,455 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Correction is a method to correct p-values for multiple testing.
+type Correction int
+
+const (
+	NOCORRECTION Correction = iota
+	BONFERRONI              // family-wise error rate, p * m
+	HOLM                    // family-wise error rate, step-down Bonferroni
+	BH                      // false discovery rate, Benjamini–Hochberg
+)
+
+// String returns the correction method as string.
+func (c Correction) String() string {
+	switch c {
+	case BONFERRONI:
+		return "Bonferroni"
+	case HOLM:
+		return "Holm"
+	case BH:
+		return "Benjamini-Hochberg"
+	default: // NOCORRECTION
+		return "none"
+	}
+}
+
+// Adjust returns the p-values p adjusted by correction c. The order of p is
+// kept.
+func (c Correction) Adjust(p []float64) []float64 {
+
+	m := len(p)
+	adj := make([]float64, m)
+	copy(adj, p)
+	if c == NOCORRECTION || m == 0 {
+		return adj
+	}
+
+	idx := make([]int, m) // indices of p in ascending order
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return p[idx[i]] < p[idx[j]] })
+
+	switch c {
+	case BONFERRONI:
+		for i := range adj {
+			adj[i] = p[i] * float64(m)
+		}
+	case HOLM:
+		var max float64
+		for r, i := range idx {
+			max = math.Max(max, p[i]*float64(m-r))
+			adj[i] = max
+		}
+	case BH:
+		min := 1.0
+		for r := m - 1; r >= 0; r-- {
+			i := idx[r]
+			min = math.Min(min, p[i]*float64(m)/float64(r+1))
+			adj[i] = min
+		}
+	}
+
+	for i := range adj {
+		adj[i] = math.Min(adj[i], 1)
+	}
+	return adj
+}
+
+// ExactTestConf holds the parameters of the exact HWE and LD tests. Zero
+// values are replaced by the values of DefaultExactTestConf.
+type ExactTestConf struct {
+	Dememorization int        // burn-in steps of the Markov chain
+	Steps          int        // steps of the Markov chain after the burn-in
+	Permutations   int        // permutations of the LD test
+	Correction     Correction // correction for multiple testing
+	Seed           int64      // seed of the random number generator
+}
+
+// DefaultExactTestConf holds the default parameters of the exact tests.
+var DefaultExactTestConf = ExactTestConf{
+	Dememorization: 10000,
+	Steps:          100000,
+	Permutations:   10000,
+	Correction:     HOLM,
+}
+
+// withDefaults returns c with zero values replaced by the defaults.
+func (c ExactTestConf) withDefaults() ExactTestConf {
+	if c.Dememorization == 0 {
+		c.Dememorization = DefaultExactTestConf.Dememorization
+	}
+	if c.Steps == 0 {
+		c.Steps = DefaultExactTestConf.Steps
+	}
+	if c.Permutations == 0 {
+		c.Permutations = DefaultExactTestConf.Permutations
+	}
+	return c
+}
+
+// HWETest holds the result of the exact Hardy–Weinberg test of a locus.
+type HWETest struct {
+	Locus   string
+	N       int     // number of individuals typed at the locus
+	Alleles int     // number of distinct alleles
+	Hobs    float64 // observed heterozygosity
+	Hexp    float64 // expected heterozygosity (unbiased)
+	P       float64 // p-value of the exact test
+	PAdj    float64 // p-value corrected for multiple testing
+}
+
+// LDTest holds the result of the linkage-equilibrium test of a locus pair.
+type LDTest struct {
+	Locus1, Locus2 string
+	N              int     // number of individuals typed at both loci
+	P              float64 // p-value of the permutation test
+	PAdj           float64 // p-value corrected for multiple testing
+	// Syntenic is true if both loci are on the same chromosome (see
+	// Syntenic); Distance is their distance in base pairs.
+	Syntenic bool
+	Distance int
+}
+
+// genotype is the genotype of an individual at a locus, with A <= B.
+type genotype struct {
+	A, B int
+}
+
+// popGenotypes holds the genotypes of the individuals of a population dataset
+// by locus. Alleles are numbered per locus.
+type popGenotypes struct {
+	loci      []string
+	genotypes map[string]map[int]genotype // locus -> individual -> genotype
+	alleles   map[string]int              // locus -> number of alleles
+}
+
+// newPopGenotypes collects the genotypes of the reference profiles refs at
+// their autosomal loci. Individuals with more than two typed alleles at a
+// locus are not counted there; a single allele is counted as homozygous.
+func newPopGenotypes(refs []Sample) popGenotypes {
+
+	pg := popGenotypes{
+		genotypes: make(map[string]map[int]genotype),
+		alleles:   make(map[string]int),
+	}
+	numbers := make(map[string]map[AlleleID]int)
+
+	for i, s := range refs {
+		for _, l := range s.Loci {
+
+			if l.Linkage() != AUTOSOMAL || l.ID == "AMEL" {
+				continue
+			}
+
+			var ids []AlleleID
+			for _, a := range l.Alleles {
+				if a.ID.IsTyped() {
+					ids = append(ids, a.ID)
+				}
+			}
+			if len(ids) == 1 {
+				ids = append(ids, ids[0])
+			}
+			if len(ids) != 2 {
+				continue
+			}
+
+			if _, ok := numbers[l.ID]; !ok {
+				numbers[l.ID] = make(map[AlleleID]int)
+				pg.genotypes[l.ID] = make(map[int]genotype)
+				pg.loci = append(pg.loci, l.ID)
+			}
+
+			var g [2]int
+			for k, id := range ids {
+				n, ok := numbers[l.ID][id]
+				if !ok {
+					n = len(numbers[l.ID])
+					numbers[l.ID][id] = n
+				}
+				g[k] = n
+			}
+			if g[0] > g[1] {
+				g[0], g[1] = g[1], g[0]
+			}
+			pg.genotypes[l.ID][i] = genotype{g[0], g[1]}
+		}
+	}
+
+	for l, n := range numbers {
+		pg.alleles[l] = len(n)
+	}
+
+	return pg
+}
+
+// HWETests performs the exact test for Hardy–Weinberg equilibrium of Guo and
+// Thompson (1992) at each autosomal locus of the reference profiles refs. The
+// p-value is estimated by a Markov chain that walks through the genotype
+// tables with the observed allele counts; it is the proportion of tables no
+// more probable than the observed one.
+func HWETests(refs []Sample, c ExactTestConf) []HWETest {
+
+	c = c.withDefaults()
+	rng := rand.New(rand.NewSource(c.Seed))
+	pg := newPopGenotypes(refs)
+
+	var r []HWETest
+	for _, l := range pg.loci {
+		k := pg.alleles[l]
+		table := make([][]int, k) // lower triangle, table[i][j] with j <= i
+		for i := range table {
+			table[i] = make([]int, i+1)
+		}
+
+		t := HWETest{Locus: l, N: len(pg.genotypes[l]), Alleles: k}
+		counts := make([]int, k)
+		var het int
+		for _, g := range pg.genotypes[l] {
+			table[g.B][g.A]++
+			counts[g.A]++
+			counts[g.B]++
+			if g.A != g.B {
+				het++
+			}
+		}
+
+		t.Hobs = float64(het) / float64(t.N)
+		var sumP2 float64
+		for _, n := range counts {
+			p := float64(n) / float64(2*t.N)
+			sumP2 += p * p
+		}
+		if t.N > 1 {
+			t.Hexp = float64(2*t.N) / float64(2*t.N-1) * (1 - sumP2)
+		}
+
+		t.P = guoThompson(table, c, rng)
+		r = append(r, t)
+	}
+
+	var p []float64
+	for _, t := range r {
+		p = append(p, t.P)
+	}
+	for i, pAdj := range c.Correction.Adjust(p) {
+		r[i].PAdj = pAdj
+	}
+
+	return r
+}
+
+// logTableProb returns the log probability of a genotype table under HWE up to
+// a constant that only depends on the allele counts.
+func logTableProb(table [][]int) float64 {
+	var lp float64
+	for i := range table {
+		for j, n := range table[i] {
+			lg, _ := math.Lgamma(float64(n + 1))
+			lp -= lg
+			if i != j {
+				lp += float64(n) * math.Ln2
+			}
+		}
+	}
+	return lp
+}
+
+// guoThompson estimates the p-value of the exact HWE test of the genotype
+// table by the Markov chain of Guo and Thompson (1992). Each step proposes to
+// move two alleles between two genotypes, which keeps the allele counts, and
+// accepts the move by the Metropolis–Hastings ratio.
+func guoThompson(table [][]int, c ExactTestConf, rng *rand.Rand) float64 {
+
+	k := len(table)
+	if k < 2 {
+		return 1
+	}
+
+	cell := func(i, j int) *int {
+		if j > i {
+			i, j = j, i
+		}
+		return &table[i][j]
+	}
+
+	lpObs := logTableProb(table)
+	lp := lpObs
+
+	var hits int
+	for step := 0; step < c.Dememorization+c.Steps; step++ {
+
+		i1, i2 := rng.Intn(k), rng.Intn(k-1)
+		if i2 >= i1 {
+			i2++
+		}
+		j1, j2 := rng.Intn(k), rng.Intn(k-1)
+		if j2 >= j1 {
+			j2++
+		}
+
+		// +1 for (i1, j1) and (i2, j2), -1 for (i1, j2) and (i2, j1); cells
+		// may coincide, hence the net change is collected per cell
+		var moves []cellMove
+		for n, ij := range [4][2]int{{i1, j1}, {i2, j2}, {i1, j2}, {i2, j1}} {
+			moves = addMove(moves, cell(ij[0], ij[1]), ij[0] != ij[1], 1-n/2*2)
+		}
+
+		// change of the log probability, see logTableProb
+		var dlp float64
+		var valid = true
+		for _, m := range moves {
+			if *m.n+m.delta < 0 {
+				valid = false
+				break
+			}
+			lgOld, _ := math.Lgamma(float64(*m.n + 1))
+			lgNew, _ := math.Lgamma(float64(*m.n + m.delta + 1))
+			dlp += lgOld - lgNew
+			if m.het {
+				dlp += float64(m.delta) * math.Ln2
+			}
+		}
+
+		if valid && (dlp >= 0 || rng.Float64() < math.Exp(dlp)) {
+			for _, m := range moves {
+				*m.n += m.delta
+			}
+			lp += dlp
+		}
+
+		if step >= c.Dememorization && lp <= lpObs+1e-9 {
+			hits++
+		}
+	}
+
+	return float64(hits) / float64(c.Steps)
+}
+
+// cellMove is the change of a cell of a genotype table.
+type cellMove struct {
+	n     *int // count of the cell
+	het   bool // true if the cell holds heterozygotes
+	delta int
+}
+
+// addMove adds the change delta of cell n to moves.
+func addMove(moves []cellMove, n *int, het bool, delta int) []cellMove {
+	for i := range moves {
+		if moves[i].n == n {
+			moves[i].delta += delta
+			return moves
+		}
+	}
+	return append(moves, cellMove{n: n, het: het, delta: delta})
+}
+
+// LDTests tests each pair of autosomal loci of the reference profiles refs
+// for linkage equilibrium. The test statistic is the likelihood-ratio (G)
+// statistic of the contingency table of the genotypes at both loci; its null
+// distribution is estimated by permuting the genotypes of the second locus
+// between individuals (Zaykin et al. 1995). Syntenic locus pairs are flagged.
+func LDTests(refs []Sample, c ExactTestConf) []LDTest {
+
+	c = c.withDefaults()
+	rng := rand.New(rand.NewSource(c.Seed))
+	pg := newPopGenotypes(refs)
+
+	var r []LDTest
+	for i, l1 := range pg.loci {
+		for _, l2 := range pg.loci[i+1:] {
+
+			var g1, g2 []genotype
+			for ind := range refs {
+				g, ok1 := pg.genotypes[l1][ind]
+				h, ok2 := pg.genotypes[l2][ind]
+				if ok1 && ok2 {
+					g1 = append(g1, g)
+					g2 = append(g2, h)
+				}
+			}
+
+			t := LDTest{Locus1: l1, Locus2: l2, N: len(g1)}
+			t.Syntenic, t.Distance = Syntenic(l1, l2)
+			t.P = ldPermutationTest(g1, g2, c.Permutations, rng)
+			r = append(r, t)
+		}
+	}
+
+	var p []float64
+	for _, t := range r {
+		p = append(p, t.P)
+	}
+	for i, pAdj := range c.Correction.Adjust(p) {
+		r[i].PAdj = pAdj
+	}
+
+	return r
+}
+
+// ldPermutationTest returns the p-value of the permutation test of the
+// association of the genotypes g1 and g2 of the same individuals.
+func ldPermutationTest(g1, g2 []genotype, perms int, rng *rand.Rand) float64 {
+
+	if len(g1) < 2 {
+		return 1
+	}
+
+	gObs := gStatistic(g1, g2)
+	perm := make([]genotype, len(g2))
+	copy(perm, g2)
+
+	hits := 1 // the observed data is one of the permutations
+	for n := 0; n < perms; n++ {
+		rng.Shuffle(len(perm), func(i, j int) { perm[i], perm[j] = perm[j], perm[i] })
+		if gStatistic(g1, perm) >= gObs-1e-9 {
+			hits++
+		}
+	}
+
+	return float64(hits) / float64(perms+1)
+}
+
+// gStatistic returns the likelihood-ratio statistic G = 2 sum O ln(O/E) of the
+// contingency table of the genotypes g1 and g2.
+func gStatistic(g1, g2 []genotype) float64 {
+
+	rows := make(map[genotype]int)
+	cols := make(map[genotype]int)
+	cells := make(map[[2]genotype]int)
+	for i := range g1 {
+		rows[g1[i]]++
+		cols[g2[i]]++
+		cells[[2]genotype{g1[i], g2[i]}]++
+	}
+
+	n := float64(len(g1))
+	var g float64
+	for k, o := range cells {
+		e := float64(rows[k[0]]) * float64(cols[k[1]]) / n
+		g += float64(o) * math.Log(float64(o)/e)
+	}
+
+	return 2 * g
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"testing"
+)
+
+// biallelic returns n reference profiles of locus lID with nAA, nAB, and nBB
+// genotypes of the alleles 10 and 11.
+func biallelic(lID string, nAA, nAB, nBB int) []Sample {
+	var r []Sample
+	add := func(n int, a ...string) {
+		for i := 0; i < n; i++ {
+			l := Locus{ID: lID}
+			for _, id := range a {
+				l.Alleles = append(l.Alleles, Allele{ID: A2ID(id)})
+			}
+			r = append(r, Sample{Loci: []Locus{l}})
+		}
+	}
+	add(nAA, "10")
+	add(nAB, "10", "11")
+	add(nBB, "11")
+	return r
+}
+
+// exactHWE returns the exact HWE p-value of a biallelic locus by enumerating
+// all numbers of heterozygotes.
+func exactHWE(nAA, nAB, nBB int) float64 {
+	nA, n := 2*nAA+nAB, nAA+nAB+nBB
+	lp := func(h int) float64 {
+		aa, bb := (nA-h)/2, n-h-(nA-h)/2
+		la, _ := math.Lgamma(float64(aa + 1))
+		lh, _ := math.Lgamma(float64(h + 1))
+		lb, _ := math.Lgamma(float64(bb + 1))
+		return float64(h)*math.Ln2 - la - lh - lb
+	}
+
+	obs := lp(nAB)
+	var sum, p float64
+	for h := nA % 2; h <= nA && h <= 2*n-nA; h += 2 {
+		sum += math.Exp(lp(h))
+		if lp(h) <= obs+1e-9 {
+			p += math.Exp(lp(h))
+		}
+	}
+	return p / sum
+}
+
+// =============================================================================
+func TestHWETests(t *testing.T) {
+
+	type test struct {
+		nAA, nAB, nBB int
+	}
+
+	tests := []test{
+		{8, 2, 10},   // heterozygote deficit
+		{25, 50, 25}, // in equilibrium
+		{3, 14, 3},   // heterozygote excess
+	}
+
+	for i, tc := range tests {
+		res := HWETests(biallelic("VWA", tc.nAA, tc.nAB, tc.nBB), ExactTestConf{Seed: 1})
+		want := exactHWE(tc.nAA, tc.nAB, tc.nBB)
+		if len(res) != 1 || math.Abs(res[0].P-want) > 0.01 {
+			t.Fatalf("test %d: expected p = %v, got: %v", i+1, want, res)
+		}
+		if res[0].N != tc.nAA+tc.nAB+tc.nBB || res[0].Alleles != 2 ||
+			res[0].Hobs != float64(tc.nAB)/float64(res[0].N) {
+			t.Fatalf("test %d: unexpected result: %v", i+1, res[0])
+		}
+	}
+}
+
+// =============================================================================
+func TestLDTests(t *testing.T) {
+
+	// the genotypes at D12S391 repeat those at VWA, i.e. complete association
+	refs := biallelic("VWA", 10, 10, 10)
+	for i := range refs {
+		l := refs[i].Loci[0]
+		l.ID = "D12S391"
+		refs[i].Loci = append(refs[i].Loci, l)
+	}
+	// TH01 alternates independently of the other loci
+	for i := range refs {
+		id := []string{"6", "7", "9.3"}[i%3]
+		refs[i].Loci = append(refs[i].Loci, Locus{ID: "TH01", Alleles: []Allele{{ID: A2ID(id)}}})
+	}
+
+	res := LDTests(refs, ExactTestConf{Seed: 1, Permutations: 2000, Correction: BONFERRONI})
+	if len(res) != 3 {
+		t.Fatalf("expected 3 locus pairs, got: %v", res)
+	}
+
+	linked := res[0]
+	if linked.Locus1 != "VWA" || linked.Locus2 != "D12S391" || linked.P > 0.001 ||
+		!linked.Syntenic || linked.Distance != 6378000 || linked.PAdj != math.Min(3*linked.P, 1) {
+		t.Fatalf("test 1: unexpected result: %v", linked)
+	}
+	for _, r := range res[1:] {
+		if r.Syntenic || r.P < 0.05 {
+			t.Fatalf("expected no association, got: %v", r)
+		}
+	}
+}
+
+// =============================================================================
+func TestCorrection_Adjust(t *testing.T) {
+
+	p := []float64{0.01, 0.04, 0.03, 0.005}
+	tests := map[Correction][]float64{
+		NOCORRECTION: {0.01, 0.04, 0.03, 0.005},
+		BONFERRONI:   {0.04, 0.16, 0.12, 0.02},
+		HOLM:         {0.03, 0.06, 0.06, 0.02},
+		BH:           {0.02, 0.04, 0.04, 0.02},
+	}
+
+	for c, want := range tests {
+		res := c.Adjust(p)
+		for i := range want {
+			if math.Abs(res[i]-want[i]) > 1e-12 {
+				t.Fatalf("%v: expected: %v, got: %v", c, want, res)
+			}
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ESS holds the loci of the European Standard Set (ESS) as amended by Council
+// Resolution 2009/C 296/01.
+var ESS = []string{
+	"D3S1358", "VWA", "D8S1179", "D21S11", "D18S51", "TH01", "FGA",
+	"D1S1656", "D2S441", "D10S1248", "D12S391", "D22S1045",
+}
+
+// MinESSLoci is the minimum number of ESS loci with alleles a profile needs
+// to be exchanged under Council Decision 2008/616/JHA.
+const MinESSLoci = 6
+
+// essAliases maps common spellings of the ESS loci and amelogenin to their
+// ESS names. Names are compared in upper case without spaces, dashes, and
+// underscores.
+var essAliases = map[string]string{
+	"THO1":       "TH01",
+	"FIBRA":      "FGA",
+	"FIBRA(FGA)": "FGA",
+	"FGA(FIBRA)": "FGA",
+	"AM":         "AMEL",
+	"AMELOGENIN": "AMEL",
+}
+
+// Keys of Sample.Info set by ReadPruem and used by NewPruem.
+const (
+	PruemType   = "Pruem Type"   // profile type, see PruemReference and PruemStain
+	PruemNonESS = "Non-ESS Loci" // comma separated loci outside the ESS
+)
+
+// Prüm profile types.
+const (
+	PruemReference = "reference" // reference profile of a known person
+	PruemStain     = "stain"     // unidentified profile, may be a mixture
+)
+
+// NormalizeESS returns the ESS name of locus id (e.g. TH01 for THO1) and
+// whether it is an ESS locus. Amelogenin is normalised to AMEL but is not an
+// ESS locus. Other loci are returned in upper case.
+func NormalizeESS(id string) (string, bool) {
+
+	n := strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(id))
+	if a, ok := essAliases[n]; ok {
+		n = a
+	}
+
+	for _, e := range ESS {
+		if n == e {
+			return n, true
+		}
+	}
+	return n, false
+}
+
+// NonESSLoci returns the IDs of all loci of sample s that are neither ESS
+// loci nor amelogenin.
+func (s Sample) NonESSLoci() []string {
+	var r []string
+	for _, l := range s.Loci {
+		if n, ok := NormalizeESS(l.ID); !ok && n != "AMEL" {
+			r = append(r, l.ID)
+		}
+	}
+	return r
+}
+
+// Pruem is a Prüm DNA profile exchange message.
+type Pruem struct {
+	XMLName xml.Name `xml:"PRUEMDNX"`
+	// ISO 3166-1 alpha-2 code of the sending country, e.g. DE
+	Country   string `xml:"header>country"`
+	MessageID string `xml:"header>messageid"`
+	// timestamp in the format "2006-01-02T15:04:05Z07:00"
+	Date string `xml:"header>date"`
+	// slice of all profiles of the message
+	Profiles []PruemProfile `xml:"profile"`
+}
+
+// PruemProfile is a DNA profile of a Prüm message.
+type PruemProfile struct {
+	ID   string       `xml:"id,attr"`
+	Type string       `xml:"type,attr"`
+	Loci []PruemLocus `xml:"locus"`
+}
+
+// PruemLocus holds the alleles of a locus of a Prüm profile.
+type PruemLocus struct {
+	Name    string   `xml:"name,attr"`
+	Alleles []string `xml:"allele"`
+}
+
+// PruemOptions holds the header of a Prüm message and the default profile
+// type.
+type PruemOptions struct {
+	Country   string    // sending country, e.g. DE
+	MessageID string    // ID of the message
+	Time      time.Time // date of the message; the current time if zero
+	Type      string    // default profile type
+}
+
+// ReadPruem reads a Prüm XML file f. See ReadPruemFrom.
+func ReadPruem(f string) ([]Sample, error) {
+
+	return readFile(f, func(r io.Reader) ([]Sample, error) {
+		return ReadPruemFrom(r, f)
+	})
+}
+
+// ReadPruemFrom reads a Prüm XML message from r. Locus names are normalised
+// to the ESS names; loci outside the ESS are kept but listed in
+// Info[PruemNonESS]. The profile type is stored in Info[PruemType].
+func ReadPruemFrom(r io.Reader, source string) ([]Sample, error) {
+
+	var p Pruem
+	if err := xml.NewDecoder(r).Decode(&p); err != nil {
+		return nil, fmt.Errorf("cannot decode Prüm XML %v: %w", source, err)
+	}
+
+	var samples []Sample
+	for _, pp := range p.Profiles {
+
+		s := NewSample(pp.ID, source)
+		s.Info[PruemType] = pp.Type
+
+		for _, pl := range pp.Loci {
+			name, _ := NormalizeESS(pl.Name)
+			l := NewLocus(name)
+			for _, v := range pl.Alleles {
+				id, err := ParseAlleleID(v)
+				if err != nil {
+					return nil, fmt.Errorf("profile %v, locus %v: %w", pp.ID, pl.Name, err)
+				}
+				l.AddAllele(NewAllele(id))
+			}
+			s.AddLocus(l)
+		}
+
+		if nonESS := s.NonESSLoci(); len(nonESS) > 0 {
+			s.Info[PruemNonESS] = strings.Join(nonESS, ",")
+		}
+
+		s.UnknownKit()
+		samples = append(samples, s)
+	}
+
+	return samples, nil
+}
+
+// NewPruem builds a Prüm message of samples. Locus names are normalised to
+// the ESS names; loci outside the ESS and off-ladder alleles are not
+// exchanged. The profile type is taken from Info[PruemType] and falls back to
+// o.Type.
+func NewPruem(samples []Sample, o PruemOptions) Pruem {
+
+	t := o.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	p := Pruem{
+		Country:   o.Country,
+		MessageID: o.MessageID,
+		Date:      t.Format(time.RFC3339),
+	}
+
+	for _, s := range samples {
+		pp := PruemProfile{ID: s.ID, Type: infoOr(s, PruemType, o.Type)}
+		for _, l := range s.Loci {
+			name, ess := NormalizeESS(l.ID)
+			if !ess && name != "AMEL" {
+				continue
+			}
+			pl := PruemLocus{Name: name}
+			for _, a := range l.WithoutOffLadder().Alleles {
+				pl.Alleles = append(pl.Alleles, a.ID.String())
+			}
+			if len(pl.Alleles) > 0 {
+				pp.Loci = append(pp.Loci, pl)
+			}
+		}
+		p.Profiles = append(p.Profiles, pp)
+	}
+
+	return p
+}
+
+// countryPattern matches an ISO 3166-1 alpha-2 country code.
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Validate checks p before it is sent: the header is complete, profile IDs are
+// unique, all loci are ESS loci or amelogenin, each profile has at least
+// MinESSLoci ESS loci, reference profiles hold at most 2 alleles per locus,
+// and alleles are valid designations (no OL, wildcards, or null alleles). All
+// violations are returned.
+func (p Pruem) Validate() error {
+
+	var errs []error
+	fail := func(format string, a ...any) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	if !countryPattern.MatchString(p.Country) {
+		fail("invalid country code %q", p.Country)
+	}
+	if p.MessageID == "" {
+		fail("missing message ID")
+	}
+	if len(p.Profiles) == 0 {
+		fail("no profiles")
+	}
+
+	ids := make(map[string]bool)
+	for _, pp := range p.Profiles {
+
+		if pp.ID == "" {
+			fail("missing profile ID")
+		}
+		if ids[pp.ID] {
+			fail("profile %q: duplicate profile ID", pp.ID)
+		}
+		ids[pp.ID] = true
+
+		if pp.Type != PruemReference && pp.Type != PruemStain {
+			fail("profile %q: invalid profile type %q", pp.ID, pp.Type)
+		}
+
+		var essLoci int
+		loci := make(map[string]bool)
+		for _, pl := range pp.Loci {
+			name, ess := NormalizeESS(pl.Name)
+			switch {
+			case name != pl.Name || (!ess && name != "AMEL"):
+				fail("profile %q: non-ESS locus %v", pp.ID, pl.Name)
+			case loci[name]:
+				fail("profile %q: duplicate locus %v", pp.ID, pl.Name)
+			case ess && len(pl.Alleles) > 0:
+				essLoci++
+			}
+			loci[name] = true
+
+			if pp.Type == PruemReference && len(pl.Alleles) > 2 {
+				fail("profile %q, locus %v: %v alleles in a reference profile",
+					pp.ID, pl.Name, len(pl.Alleles))
+			}
+			for _, v := range pl.Alleles {
+				if err := validateAlleleValue(name, v); err != nil {
+					fail("profile %q, locus %v: Prüm: %v", pp.ID, pl.Name, err)
+				}
+			}
+		}
+
+		if essLoci < MinESSLoci {
+			fail("profile %q: %v ESS loci, expect at least %v", pp.ID, essLoci, MinESSLoci)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Write validates p and writes it to w. Nothing is written if the validation
+// fails.
+func (p Pruem) Write(w io.Writer) error {
+
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid Prüm message: %w", err)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ExportPruem writes samples as Prüm XML file f. See NewPruem and
+// Pruem.Validate. The file is removed if the validation or the write fails.
+func ExportPruem(samples []Sample, f string, o PruemOptions) error {
+
+	pF, err := os.Create(f)
+	if err != nil {
+		return fmt.Errorf("cannot create %v: %w", f, err)
+	}
+
+	err = NewPruem(samples, o).Write(pF)
+	if cErr := pF.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(f)
+	}
+	return err
+}
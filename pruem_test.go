@@ -0,0 +1,154 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+func Test_NormalizeESS(t *testing.T) {
+
+	type test struct {
+		in      string
+		want    string
+		wantESS bool
+	}
+
+	tests := []test{
+		{"vWA", "VWA", true},
+		{"THO1", "TH01", true},
+		{"FIBRA", "FGA", true},
+		{"d12s391", "D12S391", true},
+		{"Amelogenin", "AMEL", false},
+		{"SE33", "SE33", false},
+	}
+
+	for i, tc := range tests {
+		res, ess := NormalizeESS(tc.in)
+		if res != tc.want || ess != tc.wantESS {
+			t.Fatalf("test %d: expected: %v %v, got: %v %v", i+1, tc.want, tc.wantESS, res, ess)
+		}
+	}
+}
+
+// =============================================================================
+func Test_ReadPruem(t *testing.T) {
+
+	res, err := ReadPruem("testdata/pruem.xml")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := []Locus{
+		{ID: "D3S1358", Alleles: []Allele{{ID: A2ID("15")}, {ID: A2ID("16")}, {ID: A2ID("17")}}},
+		{ID: "VWA", Alleles: []Allele{{ID: A2ID("14")}, {ID: A2ID("17")}}},
+		{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("9.3")}}},
+		{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+		{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29.2")}}},
+	}
+
+	if len(res) != 1 || res[0].ID != "AT-S-17" || !reflect.DeepEqual(res[0].Loci, want) {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+	if res[0].Info[PruemType] != PruemStain || res[0].Info[PruemNonESS] != "SE33" {
+		t.Fatalf("unexpected info: %v", res[0].Info)
+	}
+}
+
+// =============================================================================
+func TestPruem_Write(t *testing.T) {
+
+	s := NewSample("DE-R-1", "test")
+	for i, id := range []string{"D3S1358", "vWA", "D8S1179", "D21S11", "D18S51", "TH01", "SE33"} {
+		s.Loci = append(s.Loci, Locus{ID: id, Alleles: []Allele{
+			{ID: AlleleID{Cat: REGULAR, Repeats: 10 + i}}, {ID: A2ID("OL"), Size: 200}}})
+	}
+
+	o := PruemOptions{Country: "DE", MessageID: "DE-1",
+		Time: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC), Type: PruemReference}
+
+	var buf bytes.Buffer
+	if err := NewPruem([]Sample{s}, o).Write(&buf); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	res, err := ReadPruemFrom(&buf, "upload")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// SE33 and OL are not exchanged, vWA is normalised
+	if len(res) != 1 || len(res[0].Loci) != 6 || res[0].Loci[1].ID != "VWA" ||
+		len(res[0].Loci[1].Alleles) != 1 || res[0].Info[PruemNonESS] != "" {
+		t.Fatalf("unexpected profile: %v", res)
+	}
+
+	// too few ESS loci
+	s.Loci = s.Loci[1:]
+	buf.Reset()
+	err = NewPruem([]Sample{s}, o).Write(&buf)
+	if err == nil || !strings.Contains(err.Error(), "5 ESS loci") || buf.Len() > 0 {
+		t.Fatalf("expected minimum loci error and no output, got: %v", err)
+	}
+}
+
+// =============================================================================
+func TestPruem_Validate(t *testing.T) {
+
+	valid := func() Pruem {
+		pp := PruemProfile{ID: "DE-R-1", Type: PruemReference}
+		for _, l := range ESS[:MinESSLoci] {
+			pp.Loci = append(pp.Loci, PruemLocus{Name: l, Alleles: []string{"12", "13"}})
+		}
+		return Pruem{Country: "DE", MessageID: "DE-1", Profiles: []PruemProfile{pp}}
+	}
+
+	type test struct {
+		modify func(p *Pruem)
+		want   string // part of the error message, empty if valid
+	}
+
+	tests := []test{
+		{func(p *Pruem) {}, ""},
+		{func(p *Pruem) { p.Country = "GER" }, "country code"},
+		{func(p *Pruem) { p.Profiles[0].Type = "suspect" }, "profile type"},
+		{func(p *Pruem) { p.Profiles[0].Loci[0].Name = "SE33" }, "non-ESS locus"},
+		{func(p *Pruem) { p.Profiles[0].Loci[0].Name = "vWA" }, "non-ESS locus"},
+		{func(p *Pruem) { p.Profiles[0].Loci[0].Alleles = nil }, "5 ESS loci"},
+		{func(p *Pruem) { p.Profiles[0].Loci[0].Alleles = []string{"12", "13", "14"} }, "reference profile"},
+		{func(p *Pruem) {
+			p.Profiles[0].Type = PruemStain
+			p.Profiles[0].Loci[0].Alleles = []string{"12", "13", "14"}
+		}, ""},
+		{func(p *Pruem) { p.Profiles[0].Loci[0].Alleles[0] = "F" }, `Prüm: invalid allele designation "F"`},
+	}
+
+	for i, tc := range tests {
+		p := valid()
+		tc.modify(&p)
+		err := p.Validate()
+		if tc.want == "" && err != nil {
+			t.Fatalf("test %d: expected no error, got: %v", i+1, err)
+		}
+		if tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Fatalf("test %d: expected error containing %q, got: %v", i+1, tc.want, err)
+		}
+	}
+
+	// invalid exports leave no file
+	f := filepath.Join(t.TempDir(), "invalid.xml")
+	if err := ExportPruem([]Sample{peaks("DE-R-1", "VWA:15")}, f, PruemOptions{}); err == nil {
+		t.Fatalf("expected error for missing country")
+	}
+	if _, err := os.Stat(f); !os.IsNotExist(err) {
+		t.Fatalf("expected no file %v, got: %v", f, err)
+	}
+}
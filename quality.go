@@ -0,0 +1,121 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Quality is the value of a process quality value (PQV) as reported by
+// GeneMapper ID-X, e.g. for allele number (AN) or allele display overflow
+// (ADO).
+type Quality int
+
+const (
+	NOQUALITY  Quality = iota // the PQV was not reported
+	PASS                      // green square in GeneMapper ID-X
+	CHECK                     // yellow triangle in GeneMapper ID-X
+	LOWQUALITY                // red octagon in GeneMapper ID-X
+)
+
+// String returns the quality as written in GeneMapper ID-X exports.
+func (q Quality) String() string {
+	switch q {
+	case PASS:
+		return "Pass"
+	case CHECK:
+		return "Check"
+	case LOWQUALITY:
+		return "Low Quality"
+	default: // NOQUALITY
+		return ""
+	}
+}
+
+// ParseQuality converts the PQV value s of a GeneMapper ID-X export to a
+// Quality. An empty value is NOQUALITY.
+func ParseQuality(s string) (Quality, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return NOQUALITY, nil
+	case "pass", "p":
+		return PASS, nil
+	case "check", "c":
+		return CHECK, nil
+	case "low quality", "lowquality", "low", "lq":
+		return LOWQUALITY, nil
+	default:
+		return NOQUALITY, fmt.Errorf("unknown quality value %q", s)
+	}
+}
+
+// PQVs holds process quality values by their column name (e.g. ADO).
+type PQVs map[string]Quality
+
+// Worst returns the worst quality amongst all PQVs in p. It returns NOQUALITY
+// if p is empty.
+func (p PQVs) Worst() Quality {
+	var w Quality
+	for _, q := range p {
+		if q > w {
+			w = q
+		}
+	}
+	return w
+}
+
+// names returns the PQV names of p in alphabetical order.
+func (p PQVs) names() []string {
+	var n []string
+	for k := range p {
+		n = append(n, k)
+	}
+	sort.Strings(n)
+	return n
+}
+
+// locusPQVs are the process quality values GeneMapper ID-X reports for every
+// marker of a sample, i.e. for the alleles called at this marker.
+var locusPQVs = []string{
+	"AN",  // allele number
+	"ADO", // allele display overflow
+	"AE",  // allele edit
+	"BIN", // out of bin allele
+	"OBA", // off-ladder or out of bin allele
+	"PHR", // peak height ratio
+	"LPH", // low peak height
+	"MPH", // max peak height
+	"SHP", // sharp peak
+	"SPA", // single peak artifact
+	"SPU", // spike
+	"OS",  // off-scale
+	"OMR", // outside marker range
+	"CC",  // control concordance
+}
+
+// samplePQVs are the process quality values GeneMapper ID-X reports once per
+// sample; they are repeated on every row of the sample in an export.
+var samplePQVs = []string{
+	"QS",   // quality of the sample
+	"SQ",   // sizing quality
+	"SOS",  // sample off-scale
+	"SSPK", // sample spike
+	"MIX",  // mixed source
+}
+
+// forgePQVs are the locus quality flags set by forge itself, e.g. DropOutPQV.
+// They are exported and read like the PQVs of GeneMapper ID-X.
+var forgePQVs = []string{DropOutPQV}
+
+// IsLowQuality returns true if any PQV of locus l is LOWQUALITY.
+func (l Locus) IsLowQuality() bool {
+	return l.PQVs.Worst() == LOWQUALITY
+}
+
+// IsLowQuality returns true if any sample-level PQV of s is LOWQUALITY.
+func (s Sample) IsLowQuality() bool {
+	return s.PQVs.Worst() == LOWQUALITY
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"testing"
+)
+
+// =============================================================================
+func Test_ParseQuality(t *testing.T) {
+
+	type test struct {
+		in      string
+		want    Quality
+		wantErr bool
+	}
+
+	tests := []test{
+		{"Pass", PASS, false},
+		{"check", CHECK, false},
+		{"Low Quality", LOWQUALITY, false},
+		{"", NOQUALITY, false},
+		{"Maybe", NOQUALITY, true},
+	}
+
+	for i, tc := range tests {
+		res, err := ParseQuality(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("test %d: unexpected error: %v", i+1, err)
+		}
+		if tc.want != res {
+			t.Fatalf("test %d: expected: %v, got: %v", i+1, tc.want, res)
+		}
+		if back, _ := ParseQuality(res.String()); back != res {
+			t.Fatalf("test %d (round trip): expected: %v, got: %v", i+1, res, back)
+		}
+	}
+}
+
+// =============================================================================
+func TestPQVs_Worst(t *testing.T) {
+
+	type test struct {
+		in   PQVs
+		want Quality
+	}
+
+	tests := []test{
+		{PQVs{"AN": PASS, "ADO": CHECK}, CHECK},
+		{PQVs{"AN": PASS, "ADO": CHECK, "AE": LOWQUALITY}, LOWQUALITY},
+		{nil, NOQUALITY},
+	}
+
+	for i, tc := range tests {
+		res := tc.in.Worst()
+		if tc.want != res {
+			t.Fatalf("test %d: expected: %v, got: %v", i+1, tc.want, res)
+		}
+	}
+}
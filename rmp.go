@@ -0,0 +1,144 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+)
+
+// GenotypeProb returns the match probability of the genotype of the alleles
+// with frequencies p and q (q is ignored if homozygous is true) with the
+// Balding–Nichols correction for the population structure theta, i.e.
+// NRC II recommendation 4.10:
+//
+//	homozygous:   [2θ + (1-θ)p][3θ + (1-θ)p] / [(1+θ)(1+2θ)]
+//	heterozygous: 2[θ + (1-θ)p][θ + (1-θ)q] / [(1+θ)(1+2θ)]
+//
+// For theta = 0 these are p² and 2pq (NRC II 4.1).
+func GenotypeProb(p, q float64, homozygous bool, theta float64) float64 {
+
+	d := (1 + theta) * (1 + 2*theta)
+	if homozygous {
+		return (2*theta + (1-theta)*p) * (3*theta + (1-theta)*p) / d
+	}
+
+	return 2 * (theta + (1-theta)*p) * (theta + (1-theta)*q) / d
+}
+
+// LocusRMP holds the match probability of the genotype at a locus.
+type LocusRMP struct {
+	Locus    string
+	Genotype [2]AlleleID // the alleles; both the same for homozygotes, F second for 2p
+	Freqs    [2]float64  // the allele frequencies used
+	RMP      float64     // random match probability; 0 if excluded
+	LR       float64     // likelihood ratio 1/RMP; 0 if excluded
+	// Excluded gives the reason why the locus does not contribute to the
+	// combined RMP, e.g. 'Y-linked'; it is empty for included loci.
+	Excluded string
+}
+
+// RMPResult holds the combined random match probability of a single source
+// sample and its per-locus breakdown.
+type RMPResult struct {
+	Sample string
+	Theta  float64
+	RMP    float64 // product of the RMPs of all included loci
+	LR     float64 // 1/RMP, i.e. the LR of the same source hypothesis
+	Loci   []LocusRMP
+}
+
+// RMP returns the random match probability of the genotype of single source
+// locus l given the allele frequencies f (including f.Fmin and f.MinRule for
+// rare alleles) and theta (see GenotypeProb). Loci that are not autosomal,
+// amelogenin, loci without frequency data, low-quality loci, and loci without
+// typed alleles are excluded. More than two typed alleles return an error. A
+// single allele p at a locus with possible drop-out or the wildcard F has the
+// match probability 2p (NRC II 4.1, without theta), otherwise it is
+// homozygous.
+func (l Locus) RMP(f Freqs, theta float64) (LocusRMP, error) {
+
+	r := LocusRMP{Locus: l.ID}
+
+	var typed []AlleleID
+	dropOut := l.PossibleDropOut()
+	for _, a := range l.Alleles {
+		switch {
+		case a.ID.IsTyped():
+			typed = append(typed, a.ID)
+		case a.ID.IsWildcard():
+			dropOut = true
+		}
+	}
+
+	switch {
+	case l.ID == "AMEL":
+		r.Excluded = "amelogenin"
+	case l.Linkage() != AUTOSOMAL:
+		r.Excluded = l.Linkage().String()
+	case !f.HasFlocus(l.ID):
+		r.Excluded = "no frequencies"
+	case l.IsLowQuality():
+		r.Excluded = "low quality"
+	case len(typed) == 0:
+		r.Excluded = "no alleles"
+	case len(typed) > 2:
+		return r, fmt.Errorf("locus %v: %v alleles, not a single source", l.ID, len(typed))
+	}
+	if r.Excluded != "" {
+		return r, nil
+	}
+
+	if len(typed) == 1 && dropOut { // 2p rule
+		r.Genotype = orderedGenotype(typed[0], wildcard)
+		r.Freqs[0] = f.Freq(l.ID, typed[0])
+		r.RMP = min(1, 2*r.Freqs[0])
+		r.LR = 1 / r.RMP
+		return r, nil
+	}
+
+	if len(typed) == 1 {
+		typed = append(typed, typed[0])
+	}
+	r.Genotype = [2]AlleleID{typed[0], typed[1]}
+	for i, id := range typed {
+		r.Freqs[i] = f.Freq(l.ID, id)
+	}
+
+	r.RMP = GenotypeProb(r.Freqs[0], r.Freqs[1], typed[0] == typed[1], theta)
+	r.LR = 1 / r.RMP
+
+	return r, nil
+}
+
+// RMP returns the combined random match probability of single source sample
+// s, the product of the RMPs of all included loci (see Locus.RMP), and the
+// likelihood ratio of the hypotheses 'the suspect is the source of s' vs.
+// 'an unrelated person is the source of s'. The result holds a breakdown of
+// all loci of s, including the excluded ones.
+func (s Sample) RMP(f Freqs, theta float64) (RMPResult, error) {
+
+	r := RMPResult{Sample: s.ID, Theta: theta, RMP: 1}
+
+	var included int
+	for _, l := range s.Loci {
+		lr, err := l.RMP(f, theta)
+		if err != nil {
+			return RMPResult{}, fmt.Errorf("sample %v: %w", s.ID, err)
+		}
+		r.Loci = append(r.Loci, lr)
+
+		if lr.Excluded == "" {
+			r.RMP *= lr.RMP
+			included++
+		}
+	}
+
+	if included == 0 {
+		return RMPResult{}, fmt.Errorf("sample %v: no locus with frequency data", s.ID)
+	}
+
+	r.LR = 1 / r.RMP
+
+	return r, nil
+}
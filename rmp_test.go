@@ -0,0 +1,100 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"testing"
+)
+
+// =============================================================================
+func Test_GenotypeProb(t *testing.T) {
+
+	type test struct {
+		p, q       float64
+		homozygous bool
+		theta      float64
+		want       float64
+	}
+
+	tests := []test{
+		{0.1, 0.1, true, 0, 0.01},  // p²
+		{0.1, 0.2, false, 0, 0.04}, // 2pq
+		{0.1, 0.1, true, 0.01, (0.02 + 0.099) * (0.03 + 0.099) / (1.01 * 1.02)},
+		{0.1, 0.2, false, 0.01, 2 * (0.01 + 0.099) * (0.01 + 0.198) / (1.01 * 1.02)},
+	}
+
+	for i, tc := range tests {
+		if res := GenotypeProb(tc.p, tc.q, tc.homozygous, tc.theta); math.Abs(res-tc.want) > 1e-12 {
+			t.Fatalf("test %d: expected: %v, got: %v", i+1, tc.want, res)
+		}
+	}
+}
+
+// =============================================================================
+func TestSample_RMP(t *testing.T) {
+
+	f := Freqs{Fmin: 0.001, Floci: []Flocus{
+		{ID: "VWA", Falleles: []Fallele{{ID: A2ID("14"), Freq: 0.1}, {ID: A2ID("17"), Freq: 0.2}}},
+		{ID: "TH01", Falleles: []Fallele{{ID: A2ID("6"), Freq: 0.25}}},
+		{ID: "D21S11", Falleles: []Fallele{{ID: A2ID("29"), Freq: 0.2}}},
+		{ID: "DXS10101", Falleles: []Fallele{{ID: A2ID("30"), Freq: 0.2}}},
+	}}
+
+	s := Sample{ID: "stain", Loci: []Locus{
+		{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+		{ID: "VWA", Alleles: []Allele{{ID: A2ID("14")}, {ID: A2ID("17")}}},
+		{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("OL")}}},
+		{ID: "D21S11", Alleles: []Allele{{ID: A2ID("29")}, {ID: A2ID("33.2")}}}, // Fmin
+		{ID: "DXS10101", Alleles: []Allele{{ID: A2ID("30")}}},
+		{ID: "DYS391", Alleles: []Allele{{ID: A2ID("10")}}},
+		{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}}},
+	}}
+
+	res, err := s.RMP(f, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := 0.04 * 0.0625 * 2 * 0.2 * 0.001
+	if math.Abs(res.RMP-want) > 1e-15 || math.Abs(res.LR-1/want) > 1e-3 {
+		t.Fatalf("expected RMP %v, got: %v", want, res)
+	}
+
+	excluded := []string{"amelogenin", "", "", "", "X-linked", "Y-linked", "no frequencies"}
+	if len(res.Loci) != len(excluded) {
+		t.Fatalf("expected %v loci, got: %v", len(excluded), res.Loci)
+	}
+	for i, e := range excluded {
+		if res.Loci[i].Excluded != e {
+			t.Fatalf("locus %v: expected exclusion %q, got: %q", res.Loci[i].Locus, e, res.Loci[i].Excluded)
+		}
+	}
+	if res.Loci[2].Genotype != [2]AlleleID{A2ID("6"), A2ID("6")} || res.Loci[3].Freqs[1] != 0.001 {
+		t.Fatalf("unexpected breakdown: %v", res.Loci)
+	}
+
+	// theta increases the RMP
+	resTheta, _ := s.RMP(f, 0.01)
+	if resTheta.RMP <= res.RMP {
+		t.Fatalf("expected larger RMP with theta, got: %v <= %v", resTheta.RMP, res.RMP)
+	}
+
+	// a single allele with possible drop-out or the wildcard F: 2p
+	for _, l := range []Locus{
+		{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}}, PQVs: PQVs{DropOutPQV: CHECK}},
+		{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}, {ID: wildcard}}},
+	} {
+		lr, err := l.RMP(f, 0)
+		if err != nil || lr.RMP != 0.5 || lr.Genotype != [2]AlleleID{A2ID("6"), wildcard} {
+			t.Fatalf("expected RMP 0.5 for 6,F, got: %v (%v)", lr, err)
+		}
+	}
+
+	// mixtures are not single source
+	s.Loci[1].Alleles = append(s.Loci[1].Alleles, Allele{ID: A2ID("18")})
+	if _, err := s.RMP(f, 0); err == nil {
+		t.Fatalf("expected error for three alleles")
+	}
+}
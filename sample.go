@@ -14,6 +14,15 @@ type Sample struct {
 	Kit Kit
 	// Source from where the sample was obtained, e.g. file name.
 	Source string
+	// File is the name of the sample file (e.g. the .fsa or .hid file).
+	File string
+	// Panel is the panel the sample was analysed with in Genemapper.
+	Panel string
+	// Run is the name of the run of the sample.
+	Run string
+	// PQVs holds the sample-level quality flags (e.g. SQ) as reported by
+	// GeneMapper ID-X.
+	PQVs PQVs
 	// Loci constitutes the number of loci constituting for this stain.
 	Loci []Locus
 }
@@ -41,48 +41,48 @@ func TestSample_AddLocus(t *testing.T) {
 	tests := []test{
 		{
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
 			}},
-			Locus{ID: "VWA", Alleles: []Allele{{ID: 21}, {ID: 23}}},
+			Locus{ID: "VWA", Alleles: []Allele{{ID: A2ID("21")}, {ID: A2ID("23")}}},
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "VWA", Alleles: []Allele{{ID: 21}, {ID: 23}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "VWA", Alleles: []Allele{{ID: A2ID("21")}, {ID: A2ID("23")}}},
 			}},
 		},
 		{
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
 			}},
 			Locus{},
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
 			}},
 		},
 		{
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
 			}},
 			// the empty locus must still be sampled to get the kit right
 			Locus{ID: "VWA"},
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
 				{ID: "VWA"},
 			}},
 		},
 		{
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18, Height: 100}, {ID: 29, Height: 100}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18"), Height: 100}, {ID: A2ID("29"), Height: 100}}},
 			}},
 			// the empty locus must still be sampled to get the kit right
-			Locus{ID: "SE33", Alleles: []Allele{{ID: 18, Height: 430}, {ID: 29, Height: 430}}},
+			Locus{ID: "SE33", Alleles: []Allele{{ID: A2ID("18"), Height: 430}, {ID: A2ID("29"), Height: 430}}},
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18, Height: 100}, {ID: 29, Height: 100}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18"), Height: 100}, {ID: A2ID("29"), Height: 100}}},
 			}},
 		},
 	}
@@ -107,18 +107,18 @@ func TestSample_Locus(t *testing.T) {
 	tests := []test{
 		{ // 1
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "VWA", Alleles: []Allele{{ID: 21}, {ID: 23}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "VWA", Alleles: []Allele{{ID: A2ID("21")}, {ID: A2ID("23")}}},
 			}},
 			"VWA",
-			Locus{ID: "VWA", Alleles: []Allele{{ID: 21}, {ID: 23}}},
+			Locus{ID: "VWA", Alleles: []Allele{{ID: A2ID("21")}, {ID: A2ID("23")}}},
 		},
 		{ // 2
 			Sample{Loci: []Locus{
-				{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "VWA", Alleles: []Allele{{ID: 21}, {ID: 23}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "VWA", Alleles: []Allele{{ID: A2ID("21")}, {ID: A2ID("23")}}},
 			}},
 			"FGA",
 			Locus{},
@@ -146,8 +146,8 @@ func TestSample_HasLocus(t *testing.T) {
 		{
 			Sample{
 				Loci: []Locus{
-					{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-					{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
+					{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+					{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
 				}},
 			"SE33",
 			true,
@@ -155,8 +155,8 @@ func TestSample_HasLocus(t *testing.T) {
 		{
 			Sample{
 				Loci: []Locus{
-					{ID: "SE33", Alleles: []Allele{{ID: 18}, {ID: 29}}},
-					{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
+					{ID: "SE33", Alleles: []Allele{{ID: A2ID("18")}, {ID: A2ID("29")}}},
+					{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
 				}},
 			"FGA",
 			false,
@@ -182,15 +182,15 @@ func TestSample_MaxAlleles(t *testing.T) {
 	tests := []test{
 		{
 			Sample{Loci: []Locus{
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 17}, {ID: 21.2}, {ID: 21.2}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21.2")}, {ID: A2ID("21.2")}}},
 			}},
 			3,
 		},
 		{
 			Sample{Loci: []Locus{
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 21.2}, {ID: 21.2}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("21.2")}, {ID: A2ID("21.2")}}},
 			}},
 			2,
 		},
@@ -219,33 +219,33 @@ func TestSample_MinContributor(t *testing.T) {
 	tests := []test{
 		{
 			Sample{Loci: []Locus{
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 17}, {ID: 21.2}, {ID: 21.2}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21.2")}, {ID: A2ID("21.2")}}},
 			}},
 			2,
 		},
 		{
 			Sample{Loci: []Locus{
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 17}, {ID: 21.2}, {ID: 21.2}}},
-				{ID: "TH01", Alleles: []Allele{{ID: 6}, {ID: 6.3}, {ID: 7}, {ID: 9.3}}},
-				{ID: "FGA", Alleles: []Allele{{ID: 17}, {ID: 21.2}, {ID: 21.2}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21.2")}, {ID: A2ID("21.2")}}},
+				{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("6.3")}, {ID: A2ID("7")}, {ID: A2ID("9.3")}}},
+				{ID: "FGA", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21.2")}, {ID: A2ID("21.2")}}},
 			}},
 			2,
 		},
 		{
 			Sample{Loci: []Locus{
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 17}, {ID: 21.2}, {ID: 21.2}}},
-				{ID: "TH01", Alleles: []Allele{{ID: 6}, {ID: 6.3}, {ID: 7}, {ID: 9}, {ID: 9.3}}},
-				{ID: "FGA", Alleles: []Allele{{ID: 17}, {ID: 21.2}, {ID: 21.2}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21.2")}, {ID: A2ID("21.2")}}},
+				{ID: "TH01", Alleles: []Allele{{ID: A2ID("6")}, {ID: A2ID("6.3")}, {ID: A2ID("7")}, {ID: A2ID("9")}, {ID: A2ID("9.3")}}},
+				{ID: "FGA", Alleles: []Allele{{ID: A2ID("17")}, {ID: A2ID("21.2")}, {ID: A2ID("21.2")}}},
 			}},
 			3,
 		},
 		{
 			Sample{Loci: []Locus{
-				{ID: "AMEL", Alleles: []Allele{{ID: -2}, {ID: -1}}},
-				{ID: "SE33", Alleles: []Allele{{ID: 21.2}, {ID: 21.2}}},
+				{ID: "AMEL", Alleles: []Allele{{ID: A2ID("X")}, {ID: A2ID("Y")}}},
+				{ID: "SE33", Alleles: []Allele{{ID: A2ID("21.2")}, {ID: A2ID("21.2")}}},
 			}},
 			1,
 		},
@@ -0,0 +1,407 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Hypothesis describes the contributors of a mixture under a proposition,
+// e.g. 'the suspect and one unknown person'.
+type Hypothesis struct {
+	// Known contributors, e.g. the suspect and the victim.
+	Known []Sample
+	// Number of unknown, unrelated contributors.
+	Unknowns int
+	// Drop-out probability per contributor: first the known contributors in
+	// the order of Known, then the unknowns.
+	DropOut []float64
+}
+
+// contributors returns the number of contributors of hypothesis h.
+func (h Hypothesis) contributors() int {
+	return len(h.Known) + h.Unknowns
+}
+
+// validate checks whether the drop-out probabilities of h are complete.
+func (h Hypothesis) validate() error {
+	if h.contributors() == 0 {
+		return fmt.Errorf("no contributors")
+	}
+	if len(h.DropOut) != h.contributors() {
+		return fmt.Errorf("%v drop-out probabilities for %v contributors",
+			len(h.DropOut), h.contributors())
+	}
+	for _, d := range h.DropOut {
+		if d < 0 || d > 1 {
+			return fmt.Errorf("drop-out probability %v not in [0, 1]", d)
+		}
+	}
+	return nil
+}
+
+// LocusLR holds the likelihoods of a locus under both hypotheses.
+type LocusLR struct {
+	Locus string
+	Hp    float64 // likelihood of the evidence under Hp
+	Hd    float64 // likelihood of the evidence under Hd
+	LR    float64 // Hp/Hd; 0 if excluded
+	// Excluded gives the reason why the locus does not contribute to the
+	// combined LR; it is empty for included loci.
+	Excluded string
+}
+
+// LRResult holds a combined likelihood ratio and its per-locus breakdown.
+type LRResult struct {
+	LR   float64 // product of the LRs of all included loci
+	Loci []LocusLR
+}
+
+// SemiContinuousLR returns the likelihood ratio of the evidence under the
+// hypotheses hp and hd by the semi-continuous (qualitative) model of LRmix
+// (Haned et al. 2012). Peak heights are not considered, only the presence of
+// alleles. An allele of the contributors is missing from a replicate with
+// the product of the drop-out probabilities of all its copies (d² for a
+// homozygous contributor); alleles not explained by the contributors are
+// drop-ins with probability dropIn times their frequency. The genotypes of
+// the unknowns are summed over with the Balding–Nichols correction theta,
+// conditioned on the known contributors of both hypotheses. Alleles that are
+// neither in the evidence nor of a known contributor are pooled.
+//
+// Each sample of evidence is a replicate of the same DNA extract; replicates
+// are independent observations of the same contributors. A replicate without
+// a locus does not contribute to the likelihood of that locus. Loci that are not
+// autosomal, amelogenin, loci without frequencies in f, and loci where a
+// known contributor is not typed are excluded.
+func SemiContinuousLR(evidence []Sample, hp, hd Hypothesis, f Freqs, theta, dropIn float64) (LRResult, error) {
+
+	if len(evidence) == 0 {
+		return LRResult{}, fmt.Errorf("no evidence")
+	}
+	if err := hp.validate(); err != nil {
+		return LRResult{}, fmt.Errorf("hp: %w", err)
+	}
+	if err := hd.validate(); err != nil {
+		return LRResult{}, fmt.Errorf("hd: %w", err)
+	}
+	if theta < 0 || theta >= 1 || dropIn < 0 || dropIn >= 1 {
+		return LRResult{}, fmt.Errorf("theta %v or drop-in rate %v not in [0, 1)", theta, dropIn)
+	}
+
+	// the known persons of both hypotheses condition the genotype
+	// probabilities of the unknowns
+	typed := knownPersons(hp, hd)
+
+	r := LRResult{LR: 1}
+	var included int
+	for _, lID := range evidenceLoci(evidence) {
+
+		ll := LocusLR{Locus: lID, Excluded: mixExcluded(lID, f, typed)}
+		if ll.Excluded == "" {
+			m := newSemiContLocus(evidence, typed, lID, f, theta, dropIn)
+			ll.Hp = m.likelihood(hp)
+			ll.Hd = m.likelihood(hd)
+			if ll.Hd == 0 {
+				return LRResult{}, fmt.Errorf("locus %v: evidence impossible under hd", lID)
+			}
+			ll.LR = ll.Hp / ll.Hd
+			r.LR *= ll.LR
+			included++
+		}
+
+		r.Loci = append(r.Loci, ll)
+	}
+
+	if included == 0 {
+		return LRResult{}, fmt.Errorf("no locus with frequency data")
+	}
+
+	return r, nil
+}
+
+// knownPersons returns the known contributors of both hypotheses without
+// duplicates.
+func knownPersons(hp, hd Hypothesis) []Sample {
+	typed := append([]Sample(nil), hp.Known...)
+	for _, k := range hd.Known {
+		if !containsSample(typed, k.ID) {
+			typed = append(typed, k)
+		}
+	}
+	return typed
+}
+
+// mixExcluded returns why locus lID is excluded from the LR of a mixture or
+// the empty string if it is included: loci that are not autosomal, amelogenin,
+// loci without frequencies in f, and loci where a known person is not typed
+// are excluded.
+func mixExcluded(lID string, f Freqs, typed []Sample) string {
+	switch {
+	case lID == "AMEL":
+		return "amelogenin"
+	case Locus{ID: lID}.Linkage() != AUTOSOMAL:
+		return Locus{ID: lID}.Linkage().String()
+	case !f.HasFlocus(lID):
+		return "no frequencies"
+	case !allTyped(typed, lID):
+		return "known contributor not typed"
+	}
+	return ""
+}
+
+// containsSample returns true if samples contains a sample with ID id.
+func containsSample(samples []Sample, id string) bool {
+	for _, s := range samples {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// evidenceLoci returns the IDs of all loci of the replicates in order of
+// appearance.
+func evidenceLoci(replicates []Sample) []string {
+	var ids []string
+	for _, s := range replicates {
+		for _, l := range s.Loci {
+			if !slices.Contains(ids, l.ID) {
+				ids = append(ids, l.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// allTyped returns true if all samples have typed alleles at locus lID.
+func allTyped(samples []Sample, lID string) bool {
+	for _, s := range samples {
+		if len(typedGenotype(s.Locus(lID))) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// typedGenotype returns the typed alleles of locus l; a single allele is
+// returned twice (homozygous).
+func typedGenotype(l Locus) []AlleleID {
+	var ids []AlleleID
+	for _, a := range l.Alleles {
+		if a.ID.IsTyped() {
+			ids = append(ids, a.ID)
+		}
+	}
+	if len(ids) == 1 {
+		ids = append(ids, ids[0])
+	}
+	return ids
+}
+
+// mixLocus holds the alleles, frequencies, and known genotypes of a locus of
+// a mixture model. Alleles are numbered; the last allele is the pooled allele
+// Q of all alleles that are neither in the evidence nor of a known person.
+type mixLocus struct {
+	ids     []AlleleID        // allele IDs, without Q
+	p       []float64         // allele frequencies, including Q
+	persons map[string][2]int // genotypes of the known persons by sample ID
+	nTyped  []int             // allele counts of the known persons
+	theta   float64
+}
+
+// newMixLocus prepares the mixture model of locus lID of the evidence and the
+// known persons typed.
+func newMixLocus(evidence, typed []Sample, lID string, f Freqs, theta float64) mixLocus {
+
+	m := mixLocus{persons: make(map[string][2]int), theta: theta}
+	for _, s := range evidence {
+		for _, a := range s.Locus(lID).Alleles {
+			if a.ID.IsTyped() {
+				m.add(a.ID)
+			}
+		}
+	}
+	for _, s := range typed {
+		g := typedGenotype(s.Locus(lID))
+		m.persons[s.ID] = [2]int{m.add(g[0]), m.add(g[1])}
+	}
+
+	var sum float64
+	for _, id := range m.ids {
+		m.p = append(m.p, f.Freq(lID, id))
+		sum += f.Freq(lID, id)
+	}
+	m.p = append(m.p, math.Max(0, 1-sum)) // Q
+
+	m.nTyped = make([]int, len(m.p))
+	for _, g := range m.persons {
+		m.nTyped[g[0]]++
+		m.nTyped[g[1]]++
+	}
+
+	return m
+}
+
+// add adds allele id to m unless present and returns its index.
+func (m *mixLocus) add(id AlleleID) int {
+	if i := m.index(id); i >= 0 {
+		return i
+	}
+	m.ids = append(m.ids, id)
+	return len(m.ids) - 1
+}
+
+// index returns the index of allele id or -1 if m does not hold it.
+func (m mixLocus) index(id AlleleID) int {
+	for i, x := range m.ids {
+		if x == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// genoConfig holds the genotypes of all contributors of a hypothesis and its
+// prior probability.
+type genoConfig struct {
+	genotypes [][2]int
+	prob      float64
+}
+
+// configs returns all genotype configurations of the contributors of
+// hypothesis h: the known contributors have their genotypes, the unknowns
+// all genotypes with a non-zero probability under the Balding–Nichols
+// sampling formula, conditioned on the known persons.
+func (m mixLocus) configs(h Hypothesis) []genoConfig {
+
+	genotypes := make([][2]int, h.contributors())
+	for i, k := range h.Known {
+		genotypes[i] = m.persons[k.ID]
+	}
+
+	counts := append([]int(nil), m.nTyped...)
+	var n int
+	for _, c := range counts {
+		n += c
+	}
+
+	// walk adds the configurations of the unknowns u, ... given the n
+	// alleles drawn so far
+	var r []genoConfig
+	var walk func(u int, n int, prob float64)
+	walk = func(u int, n int, prob float64) {
+		if u == h.contributors() {
+			r = append(r, genoConfig{append([][2]int(nil), genotypes...), prob})
+			return
+		}
+
+		for a := range m.p {
+			for b := a; b < len(m.p); b++ {
+				pa := m.draw(a, counts[a], n)
+				counts[a]++
+				pb := m.draw(b, counts[b], n+1)
+				counts[b]++
+
+				gp := pa * pb
+				if a != b {
+					gp *= 2
+				}
+				if gp > 0 {
+					genotypes[u] = [2]int{a, b}
+					walk(u+1, n+2, prob*gp)
+				}
+
+				counts[a]--
+				counts[b]--
+			}
+		}
+	}
+	walk(len(h.Known), n, 1)
+
+	return r
+}
+
+// draw returns the probability to draw allele a given that it has been seen na
+// times amongst n alleles: (na θ + (1-θ) p_a) / (1 + (n-1) θ).
+func (m mixLocus) draw(a, na, n int) float64 {
+	return (float64(na)*m.theta + (1-m.theta)*m.p[a]) / (1 + float64(n-1)*m.theta)
+}
+
+// semiContLocus holds the data of the semi-continuous model at a locus.
+type semiContLocus struct {
+	mixLocus
+	observed [][]bool // observed alleles per replicate
+	dropIn   float64
+}
+
+// newSemiContLocus prepares the semi-continuous model of locus lID.
+func newSemiContLocus(evidence, typed []Sample, lID string, f Freqs, theta, dropIn float64) semiContLocus {
+
+	m := semiContLocus{mixLocus: newMixLocus(evidence, typed, lID, f, theta), dropIn: dropIn}
+	for _, s := range evidence {
+		if !s.HasLocus(lID) { // the locus failed in this replicate
+			continue
+		}
+		obs := make([]bool, len(m.p))
+		for _, a := range s.Locus(lID).Alleles {
+			if a.ID.IsTyped() {
+				obs[m.index(a.ID)] = true
+			}
+		}
+		m.observed = append(m.observed, obs)
+	}
+
+	return m
+}
+
+// likelihood returns the probability of the evidence under hypothesis h,
+// summed over all genotypes of the unknowns.
+func (m semiContLocus) likelihood(h Hypothesis) float64 {
+	var r float64
+	for _, c := range m.configs(h) {
+		r += c.prob * m.evidenceProb(c.genotypes, h.DropOut)
+	}
+	return r
+}
+
+// evidenceProb returns the probability of the observed alleles of all
+// replicates given the genotypes of the contributors and their drop-out
+// probabilities d.
+func (m semiContLocus) evidenceProb(genotypes [][2]int, d []float64) float64 {
+
+	// probability that all copies of an allele drop out
+	allOut := make([]float64, len(m.p))
+	present := make([]bool, len(m.p))
+	for i := range allOut {
+		allOut[i] = 1
+	}
+	for c, g := range genotypes {
+		allOut[g[0]] *= d[c]
+		allOut[g[1]] *= d[c]
+		present[g[0]], present[g[1]] = true, true
+	}
+
+	prob := 1.0
+	for _, obs := range m.observed {
+		dropIns := false
+		for a := range m.p {
+			switch {
+			case present[a] && obs[a]:
+				prob *= 1 - allOut[a]
+			case present[a]:
+				prob *= allOut[a]
+			case obs[a]:
+				prob *= m.dropIn * m.p[a]
+				dropIns = true
+			}
+		}
+		if !dropIns {
+			prob *= 1 - m.dropIn
+		}
+	}
+
+	return prob
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"testing"
+)
+
+// scFreqs are the allele frequencies of the semi-continuous tests.
+var scFreqs = Freqs{Fmin: 0.001, Floci: []Flocus{
+	{ID: "VWA", Falleles: []Fallele{
+		{ID: A2ID("14"), Freq: 0.1}, {ID: A2ID("15"), Freq: 0.2}, {ID: A2ID("16"), Freq: 0.3},
+		{ID: A2ID("17"), Freq: 0.25}, {ID: A2ID("18"), Freq: 0.15},
+	}},
+}}
+
+// vwa returns a sample with ID id and alleles ids at VWA.
+func vwa(id string, ids ...string) Sample {
+	l := Locus{ID: "VWA"}
+	for _, a := range ids {
+		l.Alleles = append(l.Alleles, Allele{ID: A2ID(a)})
+	}
+	return Sample{ID: id, Loci: []Locus{l}}
+}
+
+// =============================================================================
+func Test_SemiContinuousLR(t *testing.T) {
+
+	suspect := vwa("suspect", "14", "15")
+	pa, pb, pc := 0.1, 0.2, 0.3
+	d, c := 0.3, 0.05
+
+	type test struct {
+		evidence []Sample
+		hp, hd   Hypothesis
+		theta    float64
+		dropIn   float64
+		wantHp   float64
+		wantHd   float64
+	}
+
+	tests := []test{
+		{ // 1: no drop-out, no drop-in: LR = 1/2pq
+			[]Sample{vwa("stain", "14", "15")},
+			Hypothesis{Known: []Sample{suspect}, DropOut: []float64{0}},
+			Hypothesis{Unknowns: 1, DropOut: []float64{0}},
+			0, 0,
+			1, 2 * pa * pb,
+		},
+		{ // 2: theta, conditioned on the suspect (NRC II 4.10)
+			[]Sample{vwa("stain", "14", "15")},
+			Hypothesis{Known: []Sample{suspect}, DropOut: []float64{0}},
+			Hypothesis{Unknowns: 1, DropOut: []float64{0}},
+			0.01, 0,
+			1, GenotypeProb(pa, pb, false, 0.01),
+		},
+		{ // 3: drop-out of allele 15 of the suspect
+			[]Sample{vwa("stain", "14")},
+			Hypothesis{Known: []Sample{suspect}, DropOut: []float64{d}},
+			Hypothesis{Unknowns: 1, DropOut: []float64{d}},
+			0, 0,
+			(1 - d) * d, pa*pa*(1-d*d) + 2*pa*(1-pa)*(1-d)*d,
+		},
+		{ // 4: drop-in of allele 16
+			[]Sample{vwa("stain", "14", "15", "16")},
+			Hypothesis{Known: []Sample{suspect}, DropOut: []float64{0}},
+			Hypothesis{Known: []Sample{suspect}, DropOut: []float64{0}},
+			0, c,
+			c * pc, c * pc,
+		},
+		{ // 5: replicates are independent observations
+			[]Sample{vwa("rep1", "14"), vwa("rep2", "14", "15")},
+			Hypothesis{Known: []Sample{suspect}, DropOut: []float64{d}},
+			Hypothesis{Known: []Sample{suspect}, DropOut: []float64{d}},
+			0, c,
+			(1 - d) * d * (1 - c) * (1 - d) * (1 - d) * (1 - c),
+			(1 - d) * d * (1 - c) * (1 - d) * (1 - d) * (1 - c),
+		},
+	}
+
+	for i, tc := range tests {
+		res, err := SemiContinuousLR(tc.evidence, tc.hp, tc.hd, scFreqs, tc.theta, tc.dropIn)
+		if err != nil {
+			t.Fatalf("test %d: expected no error, got: %v", i+1, err)
+		}
+		l := res.Loci[0]
+		if math.Abs(l.Hp-tc.wantHp) > 1e-12 || math.Abs(l.Hd-tc.wantHd) > 1e-12 ||
+			math.Abs(res.LR-tc.wantHp/tc.wantHd) > 1e-9*res.LR {
+			t.Fatalf("test %d: expected Hp %v, Hd %v, got: %v", i+1, tc.wantHp, tc.wantHd, res)
+		}
+	}
+}
+
+// =============================================================================
+func Test_SemiContinuousLR_Mixture(t *testing.T) {
+
+	// two person mixture of the suspect and the victim, no drop-out: the LR
+	// of 'suspect and victim' vs. 'victim and unknown' is 1/P(unknown
+	// explains the alleles 16 and 17 given the victim's 14 and 15)
+	evidence := []Sample{vwa("stain", "14", "15", "16", "17")}
+	suspect, victim := vwa("suspect", "16", "17"), vwa("victim", "14", "15")
+
+	hp := Hypothesis{Known: []Sample{suspect, victim}, DropOut: []float64{0, 0}}
+	hd := Hypothesis{Known: []Sample{victim}, Unknowns: 1, DropOut: []float64{0, 0}}
+
+	res, err := SemiContinuousLR(evidence, hp, hd, scFreqs, 0, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if want := 1 / (2 * 0.3 * 0.25); math.Abs(res.LR-want) > 1e-9 {
+		t.Fatalf("expected LR %v, got: %v", want, res)
+	}
+
+	// errors
+	if _, err := SemiContinuousLR(evidence, Hypothesis{Unknowns: 2, DropOut: []float64{0}}, hd, scFreqs, 0, 0); err == nil {
+		t.Fatalf("expected error for missing drop-out probability")
+	}
+	if _, err := SemiContinuousLR(nil, hp, hd, scFreqs, 0, 0); err == nil {
+		t.Fatalf("expected error for missing evidence")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Sequence holds the sequence information of an allele typed by massively
+// parallel sequencing (MPS). Two alleles of the same length (i.e. AlleleID)
+// but of different sequence are isoalleles.
+type Sequence struct {
+	// Repeat is the sequence of the repeat region, e.g. TCTA...TCTG.
+	Repeat string
+	// Bracket is the bracketed repeat notation as recommended by the ISFG,
+	// e.g. [TCTA]10 [TCTG]1 [TCTA]2.
+	Bracket string
+	// Flanks holds the variants of the flanking regions, e.g. rs73250432-T.
+	// Several variants are separated by commas.
+	Flanks string
+}
+
+// IsZero returns true if s holds no sequence information.
+func (s Sequence) IsZero() bool {
+	return s == Sequence{}
+}
+
+// LUS returns the longest uninterrupted stretch of s, i.e. the largest number
+// of repeats of a block of its bracketed notation (e.g. 10 for [TCTA]10
+// [TCTG]1 [TCTA]2). It returns 0 if the bracketed notation is unknown.
+func (s Sequence) LUS() int {
+	var lus int
+	for _, b := range strings.Fields(s.Bracket) {
+		i := strings.LastIndex(b, "]")
+		if !strings.HasPrefix(b, "[") || i < 0 {
+			continue
+		}
+		if n, err := strconv.Atoi(b[i+1:]); err == nil && n > lus {
+			lus = n
+		}
+	}
+	return lus
+}
+
+// key returns the part of s that identifies an isoallele. The bracketed
+// notation is derived from the repeat sequence, hence it is only used if the
+// repeat sequence is unknown.
+func (s Sequence) key() Sequence {
+	if s.Repeat != "" {
+		return Sequence{Repeat: s.Repeat, Flanks: s.Flanks}
+	}
+	return Sequence{Bracket: s.Bracket, Flanks: s.Flanks}
+}
+
+// MatchMode defines whether alleles are compared by length only or by length
+// and sequence.
+type MatchMode int
+
+const (
+	LENGTH   MatchMode = iota // alleles match if their IDs match (CE data)
+	SEQUENCE                  // alleles match if their IDs and sequences match
+)
+
+// String returns the match mode as string.
+func (m MatchMode) String() string {
+	switch m {
+	case SEQUENCE:
+		return "sequence"
+	default: // LENGTH
+		return "length"
+	}
+}
+
+// alleleKey identifies an allele under a match mode.
+type alleleKey struct {
+	ID  AlleleID
+	Seq Sequence
+}
+
+// key returns the key of allele a under match mode m.
+func (a Allele) key(m MatchMode) alleleKey {
+	if m == SEQUENCE {
+		return alleleKey{ID: a.ID, Seq: a.Seq.key()}
+	}
+	return alleleKey{ID: a.ID}
+}
+
+// HasAlleleMode returns true if locus l contains an allele matching a under
+// match mode m. In LENGTH mode this is the same as HasAllele(a.ID).
+func (l Locus) HasAlleleMode(a Allele, m MatchMode) bool {
+	for _, la := range l.Alleles {
+		if la.key(m) == a.key(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Isoalleles returns all alleles of locus l with ID id, i.e. all sequence
+// variants of the same length.
+func (l Locus) Isoalleles(id AlleleID) []Allele {
+	var r []Allele
+	for _, a := range l.Alleles {
+		if a.ID == id {
+			r = append(r, a)
+		}
+	}
+	return r
+}
+
+// BracketNotation returns the bracketed repeat notation of the repeat region
+// seq given the repeat motifs of the locus, e.g. [TCTA]10 [TCTG]1 TCA
+// [TCTA]2 for the motifs TCTA and TCTG. At each position the motif with the
+// longest run is chosen; stretches that do not match any motif are written as
+// they are.
+func BracketNotation(seq string, motifs []string) string {
+
+	var parts []string
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, literal.String())
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(seq); {
+
+		var best string
+		var bestN int
+		for _, m := range motifs {
+			if m == "" {
+				continue
+			}
+			n := 0
+			for strings.HasPrefix(seq[i+n*len(m):], m) {
+				n++
+			}
+			if n*len(m) > bestN*len(best) {
+				best, bestN = m, n
+			}
+		}
+
+		if bestN == 0 {
+			literal.WriteByte(seq[i])
+			i++
+			continue
+		}
+
+		flush()
+		parts = append(parts, "["+best+"]"+strconv.Itoa(bestN))
+		i += bestN * len(best)
+	}
+	flush()
+
+	return strings.Join(parts, " ")
+}
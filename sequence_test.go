@@ -0,0 +1,71 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"reflect"
+	"testing"
+)
+
+// two D12S391 19 isoalleles
+var (
+	d12iso1 = Allele{ID: A2ID("19"), Seq: Sequence{
+		Repeat:  "AGATAGATAGATAGATAGATAGATAGATAGATAGATAGATAGATAGATAGACAGATAGATAGATAGATAGATAGAT",
+		Bracket: "[AGAT]12 [AGAC]1 [AGAT]6"}}
+	d12iso2 = Allele{ID: A2ID("19"), Seq: Sequence{
+		Repeat:  "AGATAGATAGATAGATAGATAGATAGATAGATAGATAGATAGATAGACAGACAGATAGATAGATAGATAGATAGAT",
+		Bracket: "[AGAT]11 [AGAC]2 [AGAT]6"}}
+)
+
+// =============================================================================
+func Test_BracketNotation(t *testing.T) {
+
+	type test struct {
+		inSeq    string
+		inMotifs []string
+		want     string
+	}
+
+	tests := []test{
+		{d12iso1.Seq.Repeat, []string{"AGAT", "AGAC"}, d12iso1.Seq.Bracket},
+		{d12iso2.Seq.Repeat, []string{"AGAT", "AGAC"}, d12iso2.Seq.Bracket},
+		{"TCTATCTATCTATCAGTCTGTCTG", []string{"TCTA", "TCTG"}, "[TCTA]3 TCAG [TCTG]2"},
+		{"AATGAATGATGAATG", []string{"AATG"}, "[AATG]2 ATG [AATG]1"},
+		{"TCTATC", nil, "TCTATC"},
+		{"", []string{"TCTA"}, ""},
+	}
+
+	for i, tc := range tests {
+		res := BracketNotation(tc.inSeq, tc.inMotifs)
+		if tc.want != res {
+			t.Fatalf("test %d: expected: %v, got: %v", i+1, tc.want, res)
+		}
+	}
+}
+
+// =============================================================================
+func TestLocus_Isoalleles(t *testing.T) {
+
+	l := NewLocus("D12S391")
+	l.AddAllele(d12iso2)
+	l.AddAllele(Allele{ID: A2ID("18")})
+	l.AddAllele(d12iso1)
+	l.AddAllele(d12iso1) // duplicate
+
+	// isoalleles are sorted by their repeat sequence
+	want := Locus{ID: "D12S391", Alleles: []Allele{{ID: A2ID("18")}, d12iso2, d12iso1}}
+	if !reflect.DeepEqual(want, l) {
+		t.Fatalf("expected: %v, got: %v", want, l)
+	}
+
+	if res := l.Isoalleles(A2ID("19")); !reflect.DeepEqual([]Allele{d12iso2, d12iso1}, res) {
+		t.Fatalf("expected both isoalleles, got: %v", res)
+	}
+
+	l.RemoveAllele(d12iso1)
+	if !l.HasAllele(A2ID("19")) || l.HasAlleleMode(d12iso1, SEQUENCE) ||
+		!l.HasAlleleMode(d12iso1, LENGTH) || !l.HasAlleleMode(d12iso2, SEQUENCE) {
+		t.Fatalf("unexpected alleles after removal: %v", l)
+	}
+}
@@ -30,23 +30,23 @@ func (freqs Freqs) drawSample(p int) Sample {
 
 	var s []Sample
 	for i := 0; i < p; i++ {
-		s = append(s, freqs.drawPerson())
+		s = append(s, freqs.drawPerson(rand.Float64))
 	}
 
 	return Composite(s, ALLLINKAGE)
 }
 
 // drawPerson generates a sample of one person based on the allele
-// distribution freqs.
-func (freqs Freqs) drawPerson() Sample {
+// distribution freqs, drawing the quantiles of the alleles from u.
+func (freqs Freqs) drawPerson(u func() float64) Sample {
 
 	var loci []Locus
 	for _, l := range freqs.Floci {
 		loci = append(loci, Locus{
 			ID: l.ID,
 			Alleles: []Allele{
-				l.weightedAlleleDraw(),
-				l.weightedAlleleDraw(),
+				l.drawAllele(u()),
+				l.drawAllele(u()),
 			},
 		})
 	}
@@ -59,6 +59,12 @@ func (freqs Freqs) drawPerson() Sample {
 
 // weightedAlleleDraw draws an allele based on the allele frequencies freqs.
 func (l Flocus) weightedAlleleDraw() Allele {
+	return l.drawAllele(rand.Float64())
+}
+
+// drawAllele returns the allele at the quantile u in [0, 1) of the allele
+// frequencies of l.
+func (l Flocus) drawAllele(u float64) Allele {
 	if len(l.Falleles) == 0 {
 		return Allele{}
 	}
@@ -68,7 +74,7 @@ func (l Flocus) weightedAlleleDraw() Allele {
 		cdf += fa.Freq
 	}
 
-	r := rand.Float64() * cdf
+	r := u * cdf
 
 	for _, fa := range l.Falleles {
 		r -= fa.Freq
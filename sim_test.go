@@ -18,12 +18,12 @@ func Test_weightedAlleleDraw(t *testing.T) {
 
 	tests := []test{
 		{
-			Flocus{ID: "TH01", Falleles: []Fallele{{ID: 6, Freq: 0.2}, {ID: 9.3, Freq: 0.8}}},
-			[]Fallele{{ID: 6, Freq: 0.2}, {ID: 9.3, Freq: 0.8}},
+			Flocus{ID: "TH01", Falleles: []Fallele{{ID: A2ID("6"), Freq: 0.2}, {ID: A2ID("9.3"), Freq: 0.8}}},
+			[]Fallele{{ID: A2ID("6"), Freq: 0.2}, {ID: A2ID("9.3"), Freq: 0.8}},
 		},
 		{
-			Flocus{ID: "VWA", Falleles: []Fallele{{ID: 17, Freq: 0.2}, {ID: 17.2, Freq: 0.75}, {ID: 29, Freq: 0.05}}},
-			[]Fallele{{ID: 17, Freq: 0.2}, {ID: 17.2, Freq: 0.75}, {ID: 29, Freq: 0.05}},
+			Flocus{ID: "VWA", Falleles: []Fallele{{ID: A2ID("17"), Freq: 0.2}, {ID: A2ID("17.2"), Freq: 0.75}, {ID: A2ID("29"), Freq: 0.05}}},
+			[]Fallele{{ID: A2ID("17"), Freq: 0.2}, {ID: A2ID("17.2"), Freq: 0.75}, {ID: A2ID("29"), Freq: 0.05}},
 		},
 		{
 			Flocus{ID: "VWA"},
@@ -35,14 +35,14 @@ func Test_weightedAlleleDraw(t *testing.T) {
 
 	for i, tc := range tests {
 
-		simFreq := make(map[float64]float64)
+		simFreq := make(map[AlleleID]float64)
 		for x := 0; x < int(numOfSim); x++ {
 			simFreq[tc.inFlocus.weightedAlleleDraw().ID]++
 		}
 
 		var resFalleles []Fallele
 		for id, freq := range simFreq {
-			resFalleles = append(resFalleles, Fallele{id, freq / numOfSim})
+			resFalleles = append(resFalleles, Fallele{ID: id, Freq: freq / numOfSim})
 		}
 
 		// sum up the simulations; must sum up to 1
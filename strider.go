@@ -85,19 +85,22 @@ func ReadSTRiderXML(f string) (STRiderFreqs, error) {
 }
 
 // BuildPop builds a Freqs object for population p and minimal frequency fmin
-// from a STRider frequency dataset.
+// from a STRider frequency dataset. The sample size of the population is kept
+// in Flocus.N.
 func (sf STRiderFreqs) BuildPop(pop string, fmin float64) (Freqs, error) {
 
 	var fLoci []Flocus
 	for _, m := range sf.Markers {
 
 		var fAlleles []Fallele
+		var n int
 		for _, o := range m.Origins {
 
 			if o.Name != pop {
 				continue
 			}
 
+			n = o.Num
 			for _, f := range o.Frequencies {
 
 				freq, err := strconv.ParseFloat(f.Freq, 64)
@@ -106,7 +109,7 @@ func (sf STRiderFreqs) BuildPop(pop string, fmin float64) (Freqs, error) {
 				}
 
 				fAlleles = append(fAlleles, Fallele{
-					ID:   A2Float(f.Allele),
+					ID:   A2ID(f.Allele),
 					Freq: freq,
 				})
 			}
@@ -121,6 +124,7 @@ func (sf STRiderFreqs) BuildPop(pop string, fmin float64) (Freqs, error) {
 		fLoci = append(fLoci, Flocus{
 			ID:       m.Name,
 			Falleles: fAlleles,
+			N:        n,
 		})
 	}
 
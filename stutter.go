@@ -3,111 +3,196 @@
 
 package forge
 
-import "fmt"
+import "math"
+
+// StutterRatio describes the stutter peaks of an allele at a repeat offset,
+// e.g. -1 for back stutter, -2 for double-back stutter and +1 for forward
+// stutter. The expected ratio of the heights of the stutter peak and of its
+// parent allele is Ratio, or Intercept + Slope * LUS for a linear regression
+// on the longest uninterrupted stretch (LUS) of the parent allele if Slope is
+// set. Without sequence information, the repeat number of the parent allele
+// is used instead of the LUS.
+type StutterRatio struct {
+	Offset    int     `json:"Offset"`
+	Ratio     float64 `json:"Ratio,omitempty"`
+	Intercept float64 `json:"Intercept,omitempty"`
+	Slope     float64 `json:"Slope,omitempty"`
+	SD        float64 `json:"SD,omitempty"` // standard deviation of the ratio
+}
 
-// TrueAlleleHeights returns a sample s with allele heights of all loci
-// corrected for contributions from alleles ins plus/minus stutter positions.
-func (s Sample) TrueAlleleHeights(ms, ps float64) Sample {
+// Expected returns the expected stutter ratio of parent allele a. It is never
+// negative.
+func (r StutterRatio) Expected(a Allele) float64 {
+	if r.Slope == 0 {
+		return r.Ratio
+	}
+	x := float64(a.Seq.LUS())
+	if x == 0 {
+		x = a.ID.Float()
+	}
+	return math.Max(0, r.Intercept+r.Slope*x)
+}
 
-	r := NewSample(s.ID, "correctedAlleleHeight::"+s.Source)
+// StutterTable holds the stutter ratios of the loci of a kit by locus ID.
+type StutterTable map[string][]StutterRatio
+
+// StutterTable returns the stutter ratios of the STRs of kit k.
+func (k Kit) StutterTable() StutterTable {
+	t := make(StutterTable)
+	for _, str := range k.STRs {
+		if len(str.Stutter) > 0 {
+			t[str.ID] = str.Stutter
+		}
+	}
+	return t
+}
+
+// UniformStutter returns a stutter table with the back stutter ratio ms and
+// the forward stutter ratio ps for all loci.
+func UniformStutter(ms, ps float64) StutterTable {
+	return StutterTable{"": {{Offset: -1, Ratio: ms}, {Offset: 1, Ratio: ps}}}
+}
+
+// ratios returns the stutter ratios of locus id. A table from UniformStutter
+// holds the ratios of all loci.
+func (t StutterTable) ratios(id string) []StutterRatio {
+	if r, ok := t[id]; ok {
+		return r
+	}
+	return t[""]
+}
+
+// StutterConf holds the parameters of the stutter filter.
+type StutterConf struct {
+	// SDs is the number of standard deviations of the stutter ratios added to
+	// the expected ratios for the filter threshold.
+	SDs float64
+	// Remove removes filtered peaks; otherwise they are marked by the comment
+	// StutterComment.
+	Remove bool
+}
+
+// StutterComment marks peaks filtered as stutter.
+const StutterComment = "Stutter"
+
+// DefaultStutterConf holds the default parameters of the stutter filter.
+var DefaultStutterConf = StutterConf{SDs: 3}
+
+// FilterStutter filters the peaks of sample s in stutter position. A peak is
+// filtered if its height is at most the sum of the stutter thresholds of its
+// parent alleles, i.e. of the alleles at the repeat offsets of the stutter
+// ratios of table t. The threshold of a parent is its height times its
+// expected stutter ratio plus c.SDs standard deviations.
+func (s Sample) FilterStutter(t StutterTable, c StutterConf) Sample {
+
+	r := NewSample(s.ID, "stutterFiltered::"+s.Source)
 	for _, l := range s.Loci {
-		r.AddLocus(l.trueAlleleHeights(ms, ps))
+		nl := l.filterStutter(t.ratios(l.ID), c)
+		if len(nl.Alleles) > 0 {
+			r.AddLocus(nl)
+		}
 	}
 
 	r.AssignKit(s.Kit)
+	return r
+}
+
+// filterStutter filters the peaks of locus l in stutter position given the
+// stutter ratios rs. See FilterStutter.
+func (l Locus) filterStutter(rs []StutterRatio, c StutterConf) Locus {
+
+	r := l
+	r.Alleles = nil
+	for _, a := range l.Alleles {
+		var threshold float64
+		for _, p := range l.Alleles {
+			for _, sr := range rs {
+				if a.ID.HasRepeats() && p.ID.Shift(sr.Offset) == a.ID {
+					threshold += p.Height * (sr.Expected(p) + c.SDs*sr.SD)
+				}
+			}
+		}
+		if threshold > 0 && a.Height <= threshold {
+			if c.Remove {
+				continue
+			}
+			switch a.Comment {
+			case "":
+				a.Comment = StutterComment
+			case StutterComment:
+			default:
+				a.Comment += "; " + StutterComment
+			}
+		}
+		r.Alleles = append(r.Alleles, a)
+	}
 
 	return r
 }
 
-// trueAlleleHeights returns a Locus with allele heights corrected for
-// contributions from alleles ins plus/minus stutter positions.
-func (l Locus) trueAlleleHeights(ms, ps float64) Locus {
+// CorrectStutter returns sample s with the peak heights of all loci corrected
+// for the stutter of the other alleles given the stutter table t. At each
+// locus, the observed heights are the true heights plus the stutter of the
+// parent alleles (back, double-back, forward, ...), which is expected to be
+// the true height of the parent times its stutter ratio. The true heights
+// solve this linear system; negative heights are set to zero.
+func (s Sample) CorrectStutter(t StutterTable) Sample {
 
-	if len(l.Alleles) < 2 {
-		return l
+	r := NewSample(s.ID, "correctedAlleleHeight::"+s.Source)
+	for _, l := range s.Loci {
+		r.AddLocus(l.correctStutter(t.ratios(l.ID)))
 	}
 
-	r := NewLocus(l.ID)
-	for i, a := range l.Alleles {
-
-		// Are there more than i alleles at this locus? Only then we can
-		// investigate if there is an allele in the plus stutter position.
-		if len(l.Alleles)-1 > i && a.ID+1 == l.Alleles[i+1].ID {
+	r.AssignKit(s.Kit)
+	return r
+}
 
-			// true height of the allele in minus stutter position; is zero if
-			// there is no allele there.
-			var Aminus1_true float64
+// TrueAlleleHeights returns a sample s with allele heights of all loci
+// corrected for contributions from alleles ins plus/minus stutter positions,
+// given the back (minus) stutter ratio ms and the forward (plus) stutter ratio
+// ps of all loci. See CorrectStutter.
+func (s Sample) TrueAlleleHeights(ms, ps float64) Sample {
+	return s.CorrectStutter(UniformStutter(ms, ps))
+}
 
-			// There is an allele in minus stutter position; we need the true
-			// height of that allele (here, 'i' must not be the first allele
-			// in the loop).
-			if i > 0 && a.ID-1 == l.Alleles[i-1].ID {
-				Aminus1_true = r.Allele(l.Alleles[i-1].ID).Height
-			}
+// correctStutter returns locus l with the peak heights corrected for stutter
+// given the stutter ratios rs. See CorrectStutter.
+func (l Locus) correctStutter(rs []StutterRatio) Locus {
 
-			r.AddAllele(Allele{
-				ID:     a.ID,
-				Height: truePeak(a.Height, l.Alleles[i+1].Height, Aminus1_true, ms, ps),
-			})
+	if len(l.Alleles) < 2 || len(rs) == 0 {
+		return l
+	}
 
+	// observed = (I + S) true, S[i][j] the stutter ratio of parent j at i
+	n := len(l.Alleles)
+	a := make([][]float64, n)
+	b := make([]float64, n)
+	for i, x := range l.Alleles {
+		a[i] = make([]float64, n)
+		a[i][i] = 1
+		b[i] = x.Height
+		if !x.ID.HasRepeats() {
 			continue
 		}
-
-		// No allele in  plus stutter position but maybe in minus stutter position?
-		// If so, this is the last allele in the current set.
-		if i > 0 && a.ID-1 == l.Alleles[i-1].ID {
-			r.AddAllele(Allele{
-				ID:     a.ID,
-				Height: a.Height - r.Allele(l.Alleles[i-1].ID).Height*ps,
-			})
-			continue
+		for j, p := range l.Alleles {
+			for _, sr := range rs {
+				if p.ID.Shift(sr.Offset) == x.ID {
+					a[i][j] += sr.Expected(p)
+				}
+			}
 		}
+	}
 
-		// No alleles in plus- or minus stutter position; lonely allele.
-		r.AddAllele(a)
+	h, ok := solveLinear(a, b)
+	if !ok {
+		return l
 	}
 
+	r := l
+	r.Alleles = nil
+	for i, x := range l.Alleles {
+		x.Height = math.Max(0, h[i])
+		r.Alleles = append(r.Alleles, x)
+	}
 	return r
 }
-
-// truePeak returns the true height of peak A, A_true, given its observed
-// height, A_obs, the observed height of the successor peak, B_obs, as well as
-// the minus- and plus stutter ratios, ms and ps. Aminus1_true is the true height
-// (determined in the previous iteration of this function) of a putative
-// predeccessor peak to the observed A (in -1 stutter position to A). Its
-// contribution to A_obs is calculated using ps. If A is the first peak in a
-// set of peaks, Aminus1_true = 0.
-//
-// Termininology: _obs:  observed peak height
-//                _true: true peack height
-//                _fwd:  forward stutter
-//                _back: back stutter
-//
-// Solution for A_true = A_obs - B_fwd:
-// -------------------------------------
-//
-// B_fwd = B_true * ms                           [ B_true = B_obs - A_back ]
-//       = (B_obs - A_back) * ms                 [ A_back = ps * A_true ]
-//       = (B_obs - ps * A_true) * ms            [ A_true = A_obs - B_fwd ]
-//       = (B_obs - ps * (A_obs - B_fwd)) * ms
-//       = (B_obs - ps*A_obs + ps*B_fwd) * ms
-//       = ms*B_obs - ms*ps*A_obs + ms*ps*B_fwd
-//
-// B_fwd - ms*ps*B_fwd = ms*B_obs - ms*ps*A_obs
-//    B_fwd(1 - ms*ps) = ms*B_obs - ms*ps*A_obs
-//               B_fwd = (ms*B_obs - ms*ps*A_obs) / (1 - ms*ps)
-//               B_fwd = ms(B_obs - ps*A_obs) / (1 - ms*ps)
-//
-// A_obs corrected for back stutter of previous peak = A_obs - Aminus1_true * ps.
-//
-// Hence, the function returns the evaluation of the term A_obs - B_fwd.
-func truePeak(A_obs, B_obs, Aminus1_true, ms, ps float64) float64 {
-
-	fmt.Println("(A_obs - Aminus1_true*ps):", A_obs-Aminus1_true*ps)
-	fmt.Println("(ps*A_obs):", ps*A_obs)
-	fmt.Println("(B_obs-ps*A_obs):", B_obs-ps*A_obs)
-	fmt.Println("ms*(B_obs-ps*A_obs):", ms*(B_obs-ps*A_obs))
-	fmt.Println("(1-ms*ps):", 1-ms*ps)
-
-	return (A_obs - Aminus1_true*ps) - ms*(B_obs-ps*A_obs)/(1-ms*ps)
-}
@@ -1,29 +1,149 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
 package forge
 
-/*
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
 // =============================================================================
-func TestSample_truePeak(t *testing.T) {
+func TestSample_TrueAlleleHeights(t *testing.T) {
 
 	type test struct {
-		A_obs, B_obs, Aminus1_true, ms, ps, want float64
+		inSample Sample
+		ms, ps   float64
+		want     []float64
 	}
 
 	tests := []test{
 		{ // ===================== test 1 =====================
-			A_obs:        400,
-			B_obs:        1000,
-			Aminus1_true: 0,
-			ms:           0.1,
-			ps:           0.1,
-			want:         300,
+			// A_obs = A + 0.1 B, B_obs = B + 0.1 A
+			peaks("s", "L:14:400", "L:15:1000"),
+			0.1, 0.1,
+			[]float64{400 - 0.1*(1000-0.1*400)/(1-0.01), 1000 - 0.1*(400-0.1*1000)/(1-0.01)},
+		},
+		{ // ===================== test 2 =====================
+			peaks("s", "L:13:100", "L:15:1000"),
+			0.1, 0,
+			[]float64{100, 1000},
+		},
+		{ // ===================== test 3 =====================
+			peaks("s", "L:14:80", "L:15:1000"),
+			0.1, 0,
+			[]float64{0, 1000},
 		},
 	}
 
 	for i, tc := range tests {
-		res := truePeak(tc.A_obs, tc.B_obs, tc.Aminus1_true, tc.ms, tc.ps)
-		if tc.want != res {
-			t.Fatalf("test %d: expected: %v, got: %v", i+1, tc.want, res)
+		res := tc.inSample.TrueAlleleHeights(tc.ms, tc.ps).Loci[0]
+		for j, a := range res.Alleles {
+			if math.Abs(a.Height-tc.want[j]) > 1e-9 {
+				t.Fatalf("test %d: expected: %v, got: %v", i+1, tc.want, res.Alleles)
+			}
+		}
+	}
+}
+
+// =============================================================================
+func TestSample_CorrectStutter(t *testing.T) {
+
+	table := StutterTable{"VWA": {
+		{Offset: -1, Ratio: 0.1},
+		{Offset: -2, Ratio: 0.01},
+		{Offset: 1, Ratio: 0.02},
+	}}
+
+	// true heights 1000 (16) and 500 (18)
+	s := peaks("s", "VWA:14:10", "VWA:15:100", "VWA:16:1005", "VWA:17:70", "VWA:18:500", "VWA:19:10",
+		"TH01:6:100", "TH01:7:1000")
+
+	want := []float64{0, 0, 1000, 0, 500, 0}
+	res := s.CorrectStutter(table)
+	for i, a := range res.Locus("VWA").Alleles {
+		if math.Abs(a.Height-want[i]) > 1e-9 {
+			t.Fatalf("expected: %v, got: %v", want, res.Locus("VWA").Alleles)
 		}
 	}
+	// loci without stutter ratios are unchanged
+	if !reflect.DeepEqual(res.Locus("TH01"), s.Locus("TH01")) {
+		t.Fatalf("expected: %v, got: %v", s.Locus("TH01"), res.Locus("TH01"))
+	}
+}
+
+// =============================================================================
+func TestSample_FilterStutter(t *testing.T) {
+
+	table := StutterTable{"VWA": {
+		{Offset: -1, Intercept: -0.05, Slope: 0.01, SD: 0.01}, // 0.11 for 16
+		{Offset: 1, Ratio: 0.02},
+	}}
+
+	s := peaks("s", "VWA:15:145", "VWA:16:1000", "VWA:17:30", "VWA:18:200", "VWA:19:3")
+
+	res := s.FilterStutter(table, StutterConf{SDs: 3}).Locus("VWA")
+	var got []string
+	for _, a := range res.Alleles {
+		got = append(got, a.Comment)
+	}
+	// 15 exceeds 0.14 of 16; 17 is back stutter of 18 (0.16*200) plus forward
+	// stutter of 16 (0.02*1000)
+	want := []string{"", "", StutterComment, "", StutterComment}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
+
+	res = s.FilterStutter(table, StutterConf{Remove: true}).Locus("VWA")
+	got = nil
+	for _, a := range res.Alleles {
+		got = append(got, a.ID.String())
+	}
+	want = []string{"15", "16", "18"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
+}
+
+// =============================================================================
+func TestStutterRatio_Expected(t *testing.T) {
+
+	r := StutterRatio{Offset: -1, Intercept: -0.02, Slope: 0.01}
+	a := Allele{ID: A2ID("13"), Seq: Sequence{Bracket: "[TCTA]10 [TCTG]1 [TCTA]2"}}
+
+	if got := r.Expected(a); math.Abs(got-0.08) > 1e-12 {
+		t.Fatalf("expected: %v, got: %v", 0.08, got)
+	}
+	a.Seq = Sequence{}
+	if got := r.Expected(a); math.Abs(got-0.11) > 1e-12 {
+		t.Fatalf("expected: %v, got: %v", 0.11, got)
+	}
+	if got := (StutterRatio{Ratio: 0.1}).Expected(a); got != 0.1 {
+		t.Fatalf("expected: %v, got: %v", 0.1, got)
+	}
+}
+
+// =============================================================================
+func TestKit_StutterTable(t *testing.T) {
+
+	dir := t.TempDir()
+	f := filepath.Join(dir, "kit.json")
+	json := `{"ID": "Test", "STRs": [
+		{"ID": "vWA", "Dye": "blue", "Stutter": [{"Offset": -1, "Intercept": 0.01, "Slope": 0.005, "SD": 0.01}]},
+		{"ID": "TH01", "Dye": "green"}]}`
+	if err := os.WriteFile(f, []byte(json), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := readKitFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StutterTable{"VWA": {{Offset: -1, Intercept: 0.01, Slope: 0.005, SD: 0.01}}}
+	if got := k.StutterTable(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
 }
-*/
@@ -0,0 +1,136 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"maps"
+	"strings"
+)
+
+// Threshold holds the analytical threshold (AT) and the stochastic threshold
+// (ST) in rfu. Peaks below AT are not alleles; alleles below ST may have lost
+// their sister allele by drop-out.
+type Threshold struct {
+	AT float64
+	ST float64
+}
+
+// Thresholds holds the thresholds of a lab. The threshold of a locus is that
+// of Loci, else that of the dye of the locus in Dyes, else Default. Dyes are
+// compared case-insensitively, and the one-letter codes of GeneMapper (e.g.
+// "B") stand for the dye names of the kit files (e.g. "blue").
+type Thresholds struct {
+	Default Threshold
+	Dyes    map[string]Threshold
+	Loci    map[string]Threshold
+}
+
+// DropOutPQV is the PQV that flags loci with possible allelic drop-out. It is
+// set by forge only; GeneMapper ID-X does not report drop-out.
+const DropOutPQV = "PossibleDropOut"
+
+// For returns the threshold of locus l of kit k. The dye of l is l.Dye or,
+// if it has no threshold, the dye of the STR of k.
+func (t Thresholds) For(l Locus, k Kit) Threshold {
+
+	if th, ok := t.Loci[l.ID]; ok {
+		return th
+	}
+
+	dyes := []string{l.Dye}
+	for _, str := range k.STRs {
+		if str.ID == l.ID {
+			dyes = append(dyes, str.Dye)
+		}
+	}
+	for _, dye := range dyes {
+		for d, th := range t.Dyes {
+			if dye != "" && dyeName(d) == dyeName(dye) {
+				return th
+			}
+		}
+	}
+
+	return t.Default
+}
+
+// gmDyes maps the one-letter dye codes of GeneMapper to the dye names.
+var gmDyes = map[string]string{
+	"b": "blue", "g": "green", "y": "yellow", "r": "red", "o": "orange", "p": "purple",
+}
+
+// dyeName returns the lower case name of dye d, e.g. "blue" for "B".
+func dyeName(d string) string {
+	d = strings.ToLower(strings.TrimSpace(d))
+	if n, ok := gmDyes[d]; ok {
+		return n
+	}
+	return d
+}
+
+// ApplyThresholds returns sample s with the peaks below the analytical
+// threshold of their locus removed. Loci with an allele below the stochastic
+// threshold are flagged with possible drop-out (the PQV DropOutPQV is set to
+// CHECK unless it is worse). Alleles without peak height are kept; loci
+// without alleles are removed.
+func (s Sample) ApplyThresholds(t Thresholds) Sample {
+
+	r := s
+	r.Loci = nil
+	for _, l := range s.Loci {
+		nl := l.applyThreshold(t.For(l, s.Kit))
+		if len(nl.Alleles) > 0 {
+			r.Loci = append(r.Loci, nl)
+		}
+	}
+
+	return r
+}
+
+// applyThreshold removes the peaks of locus l below th.AT and flags l with
+// possible drop-out if an allele is below th.ST.
+func (l Locus) applyThreshold(th Threshold) Locus {
+
+	r := l
+	r.Alleles = nil
+	var dropOut bool
+	for _, a := range l.Alleles {
+		if a.Height > 0 && a.Height < th.AT {
+			continue
+		}
+		if a.Height > 0 && a.Height < th.ST && a.ID.IsTyped() {
+			dropOut = true
+		}
+		r.Alleles = append(r.Alleles, a)
+	}
+
+	if dropOut && r.PQVs[DropOutPQV] < CHECK {
+		r.PQVs = maps.Clone(r.PQVs)
+		if r.PQVs == nil {
+			r.PQVs = make(PQVs)
+		}
+		r.PQVs[DropOutPQV] = CHECK
+	}
+
+	return r
+}
+
+// PossibleDropOut returns true if locus l is flagged with possible allelic
+// drop-out, i.e. its PQV DropOutPQV is CHECK or LOWQUALITY.
+func (l Locus) PossibleDropOut() bool {
+	return l.PQVs[DropOutPQV] >= CHECK
+}
+
+// WithoutDropOutLoci returns sample s without the loci flagged with possible
+// drop-out.
+func (s Sample) WithoutDropOutLoci() Sample {
+	r := s
+	r.Loci = nil
+	for _, l := range s.Loci {
+		if !l.PossibleDropOut() {
+			r.Loci = append(r.Loci, l)
+		}
+	}
+	return r
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// labThresholds are the thresholds used in the tests.
+var labThresholds = Thresholds{
+	Default: Threshold{AT: 50, ST: 200},
+	Dyes:    map[string]Threshold{"B": {AT: 80, ST: 300}, "red": {AT: 100, ST: 400}},
+	Loci:    map[string]Threshold{"SE33": {AT: 120, ST: 500}},
+}
+
+// =============================================================================
+func TestThresholds_For(t *testing.T) {
+
+	kit := Kit{ID: "Test", STRs: []STR{{ID: "VWA", Dye: "blue"}, {ID: "FGA", Dye: "Red"}}}
+
+	type test struct {
+		inLocus Locus
+		want    Threshold
+	}
+
+	tests := []test{
+		{Locus{ID: "SE33", Dye: "B"}, Threshold{AT: 120, ST: 500}}, // locus first
+		{Locus{ID: "D3S1358", Dye: "b"}, Threshold{AT: 80, ST: 300}},
+		{Locus{ID: "FGA"}, Threshold{AT: 100, ST: 400}}, // dye of the kit
+		{Locus{ID: "VWA"}, Threshold{AT: 80, ST: 300}},  // blue is B
+		{Locus{ID: "TH01"}, Threshold{AT: 50, ST: 200}},
+		{Locus{ID: "D8S1179", Dye: "R"}, Threshold{AT: 100, ST: 400}},  // GeneMapper dye, kit-named threshold
+		{Locus{ID: "FGA", Dye: "yellow"}, Threshold{AT: 100, ST: 400}}, // dye of the kit if l.Dye has none
+		{Locus{ID: "D3S1358", Dye: "G"}, Threshold{AT: 50, ST: 200}},
+	}
+
+	for i, tt := range tests {
+		if got := labThresholds.For(tt.inLocus, kit); got != tt.want {
+			t.Fatalf("test %d: expected: %v, got: %v", i, tt.want, got)
+		}
+	}
+}
+
+// =============================================================================
+func TestSample_ApplyThresholds(t *testing.T) {
+
+	s := peaks("stain", "VWA:14:40", "VWA:16:1500", "VWA:17:1400",
+		"TH01:6:150", "TH01:9.3:900", "D8S1179:12:30", "FGA:20")
+	s.Loci[1].PQVs = PQVs{"AN": PASS}
+
+	r := s.ApplyThresholds(labThresholds)
+
+	var got [][]string
+	for _, l := range r.Loci {
+		var ids []string
+		for _, a := range l.Alleles {
+			ids = append(ids, a.ID.String())
+		}
+		got = append(got, ids)
+	}
+	want := [][]string{{"16", "17"}, {"6", "9.3"}, {"20"}} // D8S1179 has no allele left
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected: %v, got: %v", want, got)
+	}
+
+	if r.Locus("VWA").PossibleDropOut() || !r.Locus("TH01").PossibleDropOut() || r.Locus("FGA").PossibleDropOut() {
+		t.Fatalf("expected: possible drop-out at TH01 only, got: %v", r.Loci)
+	}
+	if s.Locus("TH01").PossibleDropOut() || r.Locus("TH01").PQVs["AN"] != PASS {
+		t.Fatalf("expected: PQVs of the input unchanged and kept, got: %v and %v",
+			s.Locus("TH01").PQVs, r.Locus("TH01").PQVs)
+	}
+
+	// the CPI skips loci with possible drop-out
+	f := Freqs{Fmin: 0.001, Floci: []Flocus{
+		{ID: "VWA", Falleles: []Fallele{{ID: A2ID("16"), Freq: 0.2}, {ID: A2ID("17"), Freq: 0.3}}},
+		{ID: "TH01", Falleles: []Fallele{{ID: A2ID("6"), Freq: 0.2}, {ID: A2ID("9.3"), Freq: 0.3}}},
+	}}
+	if cpi := r.CPI(f, 0); math.Abs(cpi-0.25) > 1e-12 {
+		t.Fatalf("expected: %v, got: %v", 0.25, cpi)
+	}
+}
+
+// =============================================================================
+func TestSample_ApplyThresholds_export(t *testing.T) {
+
+	// the drop-out flag survives the export and the import
+	r := peaks("stain", "TH01:6:150", "TH01:9.3:900").ApplyThresholds(labThresholds)
+	d, err := buildCSV(r, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rows []string
+	for _, row := range d {
+		rows = append(rows, strings.Join(row, "\t"))
+	}
+
+	res, err := ReadGMFrom(strings.NewReader(strings.Join(rows, "\n")+"\n"), "export", "Sample Name", "", nil)
+	if err != nil || len(res) != 1 || !res[0].Locus("TH01").PossibleDropOut() {
+		t.Fatalf("expected: TH01 with possible drop-out, got: %v (%v)", res, err)
+	}
+}
+
+// =============================================================================
+func TestLocus_PossibleDropOut(t *testing.T) {
+
+	flagged := func(l Locus) Locus {
+		l.PQVs = PQVs{DropOutPQV: CHECK}
+		return l
+	}
+
+	// no homozygous major component with possible drop-out
+	l := peaks("s", "L:15:3000", "L:16:200").Loci[0]
+	if mc := l.MajorComponent(0.67, 2.9, 1500, 500); len(mc.Alleles) != 1 {
+		t.Fatalf("expected: homozygous major component, got: %v", mc)
+	}
+	if mc := flagged(l).MajorComponent(0.67, 2.9, 1500, 500); len(mc.Alleles) != 0 {
+		t.Fatalf("expected: no major component, got: %v", mc)
+	}
+	wl := flagged(l).WeightedMajorComponent(0.67, 2.9, 1500, 500)
+	if len(wl.Genotypes) != 2 || wl.Genotypes[1].Genotype[1] != wildcard {
+		t.Fatalf("expected: 15,15 and 15,F, got: %v", wl)
+	}
+
+	// a single allele with possible drop-out matches a heterozygote
+	single := peaks("s", "L:15").Loci[0]
+	het := peaks("s", "L:15", "L:16").Loci[0]
+	if got := matchLoci(single, het, LENGTH); got != fuzzy {
+		t.Fatalf("expected: %v, got: %v", fuzzy, got)
+	}
+	if got := matchLoci(flagged(single), het, LENGTH); got != match {
+		t.Fatalf("expected: %v, got: %v", match, got)
+	}
+	if got := matchLoci(het, flagged(single), LENGTH); got != match {
+		t.Fatalf("expected: %v, got: %v", match, got)
+	}
+
+	// ADO of GeneMapper ID-X is allele display overflow, not drop-out
+	overflow := l
+	overflow.PQVs = PQVs{"ADO": CHECK}
+	if overflow.PossibleDropOut() {
+		t.Fatalf("expected: ADO not read as drop-out")
+	}
+}
@@ -12,7 +12,18 @@ import (
 func (s Sample) InferUnknownPersons(heteroImbalance, majorCompRatio,
 	minHomozygous, weakSignal float64) []Sample {
 
+	UPs, _ := s.inferUnknownPersons(heteroImbalance, majorCompRatio, minHomozygous, weakSignal, false)
+	return UPs
+}
+
+// inferUnknownPersons peels the unknown persons off sample s, see
+// InferUnknownPersons. If weighted is true, it returns their weighted
+// profiles, too (see InferWeightedUnknownPersons).
+func (s Sample) inferUnknownPersons(heteroImbalance, majorCompRatio,
+	minHomozygous, weakSignal float64, weighted bool) ([]Sample, []WeightedProfile) {
+
 	var UPs []Sample
+	var WUPs []WeightedProfile
 	source := s.ID
 	upNr := 1
 	for {
@@ -39,6 +50,10 @@ func (s Sample) InferUnknownPersons(heteroImbalance, majorCompRatio,
 		// 4. build new UP object and append to the up list
 		up := newUP(s.ID+"::UP"+strconv.Itoa(upNr), source, autosomalLoci)
 		UPs = append(UPs, up)
+		if weighted {
+			WUPs = append(WUPs, s.weightedUP(up.ID, source, heteroImbalance,
+				majorCompRatio, minHomozygous, weakSignal))
+		}
 		upNr++
 
 		// 5. remove the obtained up from the stain and start over
@@ -46,7 +61,7 @@ func (s Sample) InferUnknownPersons(heteroImbalance, majorCompRatio,
 		source += "::REM"
 	}
 
-	return UPs
+	return UPs, WUPs
 }
 
 // newUP builds a new sample for an unknown person.
@@ -67,7 +82,8 @@ func newUP(id, source string, loci []Locus) Sample {
 }
 
 // RemovePersonFromSample removes the alleles of profile p from sample s and
-// returns a sample containing the remaining alleles.
+// returns a sample containing the remaining alleles. See SubtractKnown for
+// keeping the alleles shared with p.
 func (s Sample) RemovePersonFromSample(p Sample) Sample {
 
 	ns := NewSample(s.ID, s.Source+"::REM:"+p.ID)
@@ -118,7 +134,7 @@ func (s Sample) Sex() string {
 			case 0:
 				return "na"
 			case 1:
-				if l.Alleles[0].ID == -2 {
+				if l.Alleles[0].ID.Cat == XALLELE {
 					return "female"
 				}
 				return "na" // single allele is Y, something is weird
@@ -143,20 +159,17 @@ const (
 // SamePerson evaluates whether the person profiles p1 and p2 differ in not more
 // alleles than accepted.
 func SamePerson(p1, p2 Sample) bool {
+	return SamePersonMode(p1, p2, LENGTH)
+}
+
+// SamePersonMode is SamePerson with alleles compared under match mode m. In
+// SEQUENCE mode isoalleles do not match.
+func SamePersonMode(p1, p2 Sample, m MatchMode) bool {
 
 	if p1.MaxAlleles() > 2 || p2.MaxAlleles() > 2 {
 		return false
 	}
 
-	// We accept a maximum of a quarter of the number of alleles from the
-	// shortest profile as tolerance and still call it the same profile.
-	// i.e. is the profile is 16 STRs long, we accept 4 mismatches; if it is
-	// 8 STRs long we accept only 2 mismatches.
-	tolerance := len(p1.Loci) / 4
-	if len(p2.Loci) < len(p1.Loci) {
-		tolerance = len(p2.Loci) / 4
-	}
-
 	count := make(map[mismatch]int)
 	for _, l1 := range p1.Loci {
 
@@ -166,30 +179,44 @@ func SamePerson(p1, p2 Sample) bool {
 
 		// count the number of matches, nomatches, and fuzzymatches between the
 		// loci of p1 and p2.
-		count[matchLoci(l1, p2.Locus(l1.ID))]++
+		count[matchLoci(l1, p2.Locus(l1.ID), m)]++
 	}
 
+	return withinTolerance(count, len(p1.Loci), len(p2.Loci))
+}
+
+// withinTolerance reports whether the mismatches counted in count are
+// tolerable for profiles of n1 and n2 loci.
+func withinTolerance(count map[mismatch]int, n1, n2 int) bool {
+
+	// We accept a maximum of a quarter of the number of alleles from the
+	// shortest profile as tolerance and still call it the same profile.
+	// i.e. is the profile is 16 STRs long, we accept 4 mismatches; if it is
+	// 8 STRs long we accept only 2 mismatches.
+	tolerance := min(n1, n2) / 4
+
 	// add the fuzzy matches to the nomatch batch but give them only half the
 	// weight.
-	count[nomatch] += count[fuzzy] / 2
-	if count[nomatch] > tolerance {
-		return false
-	}
-
-	return true
+	return count[nomatch]+count[fuzzy]/2 <= tolerance
 }
 
-// matchLoci
+// matchLoci compares the alleles of l1 and l2 under match mode m.
 // The caller (SamePerson) guarantees that l1 and l2 do not have more than two
-// alleles at loci l1 and l2 and that neither locus is empty.
-func matchLoci(l1, l2 Locus) mismatch {
+// alleles at loci l1 and l2 and that neither locus is empty. A single allele
+// at a locus flagged with possible drop-out (see ApplyThresholds) fully
+// matches a heterozygous locus with this allele.
+func matchLoci(l1, l2 Locus, m MatchMode) mismatch {
 
 	switch {
 
 	case len(l1.Alleles) == 1 && len(l2.Alleles) == 2:
 		// one allele is already not matching; result can only be fuzzy or
-		// nomatch
-		if l2.HasAllele(l1.Alleles[0].ID) {
+		// nomatch, or match with possible drop-out
+		if l2.HasAlleleMode(l1.Alleles[0], m) {
+			if l1.PossibleDropOut() {
+				// e.g. L1 15,F; L2 15,16
+				return match
+			}
 			// soft match, e.g. L1 15,16; L2 15
 			return fuzzy
 		}
@@ -197,16 +224,19 @@ func matchLoci(l1, l2 Locus) mismatch {
 
 	case len(l1.Alleles) == 2 && len(l2.Alleles) == 1:
 		// one allele is already not matching; result can only be fuzzy or
-		// nomatch
-		if l1.HasAllele(l2.Alleles[0].ID) {
+		// nomatch, or match with possible drop-out
+		if l1.HasAlleleMode(l2.Alleles[0], m) {
+			if l2.PossibleDropOut() {
+				return match
+			}
 			// soft match, e.g. L1 15,16; L2 15
 			return fuzzy
 		}
 		return nomatch
 
 	case len(l1.Alleles) == 2 && len(l2.Alleles) == 2:
-		if l2.HasAllele(l1.Alleles[0].ID) {
-			if l2.HasAllele(l1.Alleles[1].ID) {
+		if l2.HasAlleleMode(l1.Alleles[0], m) {
+			if l2.HasAlleleMode(l1.Alleles[1], m) {
 				// both alleles match, e.g. L1 15,16; L2 15,16
 				return match
 			}
@@ -217,7 +247,7 @@ func matchLoci(l1, l2 Locus) mismatch {
 
 	default:
 		// only one scenario left: both loci have exactly one allele.
-		if l2.HasAllele(l1.Alleles[0].ID) {
+		if l2.HasAlleleMode(l1.Alleles[0], m) {
 			return match
 		}
 		return nomatch
@@ -244,7 +274,7 @@ func UniteUPs(ups []Sample, id string) Sample {
 			// Conflict can only arise from UPs with 1 allele at this locus,
 			// if there are more than one UP with 1 allele and if these have
 			// not the same allele ID.
-			alleleIDs := make(map[float64]int)
+			alleleIDs := make(map[AlleleID]int)
 			for _, up := range ups {
 				if len(up.Locus(lc.ID).Alleles) == 1 {
 					alleleIDs[up.Locus(lc.ID).Alleles[0].ID]++
@@ -0,0 +1,578 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// XMarker is an X-STR of a linkage group with its genetic position.
+type XMarker struct {
+	ID    string
+	CM    float64 // approximate sex-averaged genetic position in cM
+	Group int     // linkage group
+}
+
+// ArgusX12 holds the markers of the Investigator Argus X-12 kit in the order
+// of their positions. The four linkage groups are inherited independently;
+// recombination within a group follows from the distances of its markers.
+var ArgusX12 = []XMarker{
+	{ID: "DXS10148", CM: 8.93, Group: 1},
+	{ID: "DXS10135", CM: 9.35, Group: 1},
+	{ID: "DXS8378", CM: 10.64, Group: 1},
+	{ID: "DXS7132", CM: 64.60, Group: 2},
+	{ID: "DXS10079", CM: 66.30, Group: 2},
+	{ID: "DXS10074", CM: 67.30, Group: 2},
+	{ID: "DXS10103", CM: 138.60, Group: 3},
+	{ID: "HPRTB", CM: 139.10, Group: 3},
+	{ID: "DXS10101", CM: 140.50, Group: 3},
+	{ID: "DXS10146", CM: 184.00, Group: 4},
+	{ID: "DXS10134", CM: 184.70, Group: 4},
+	{ID: "DXS7423", CM: 184.80, Group: 4},
+}
+
+// XMarkerOf returns the Argus X-12 marker with ID id and true, or false if
+// there is none.
+func XMarkerOf(id string) (XMarker, bool) {
+	for _, m := range ArgusX12 {
+		if m.ID == strings.ToUpper(id) {
+			return m, true
+		}
+	}
+	return XMarker{}, false
+}
+
+// XLinkageGroup returns the markers of linkage group g in order of their
+// positions.
+func XLinkageGroup(g int) []XMarker {
+	var r []XMarker
+	for _, m := range ArgusX12 {
+		if m.Group == g {
+			r = append(r, m)
+		}
+	}
+	return r
+}
+
+// recombination returns the recombination fraction of the markers at the
+// genetic distance d cM by Haldane's map function.
+func recombination(d float64) float64 {
+	return (1 - math.Exp(-2*math.Abs(d)/100)) / 2
+}
+
+// XHaplotype is a haplotype of a linkage group and its count.
+type XHaplotype struct {
+	Alleles []AlleleID // alleles in the order of the markers of the group
+	Count   int
+	Freq    float64
+}
+
+// XHaploFreqs holds the haplotype frequencies of a linkage group.
+type XHaploFreqs struct {
+	Group      int
+	Loci       []string
+	N          int          // number of haplotypes
+	Haplotypes []XHaplotype // observed haplotypes by decreasing count
+}
+
+// BuildXHaploFreqs returns the haplotype frequency tables of all linkage
+// groups from the male references (see Sample.Sex), whose X-STRs are phase
+// known. Males not typed at all markers of a group do not contribute to its
+// table; males with two alleles at an X-STR return an error.
+func BuildXHaploFreqs(refs []Sample) ([]XHaploFreqs, error) {
+
+	var r []XHaploFreqs
+	for g := 1; g <= 4; g++ {
+		f := XHaploFreqs{Group: g}
+		for _, m := range XLinkageGroup(g) {
+			f.Loci = append(f.Loci, m.ID)
+		}
+
+		idx := make(map[string]int)
+		for _, s := range refs {
+			if s.Sex() != "male" {
+				continue
+			}
+			h, err := maleXHaplotype(s, f.Loci)
+			if err != nil {
+				return nil, err
+			}
+			if h == nil {
+				continue
+			}
+			key := fmt.Sprint(h)
+			if i, ok := idx[key]; ok {
+				f.Haplotypes[i].Count++
+			} else {
+				idx[key] = len(f.Haplotypes)
+				f.Haplotypes = append(f.Haplotypes, XHaplotype{Alleles: h, Count: 1})
+			}
+			f.N++
+		}
+
+		for i := range f.Haplotypes {
+			f.Haplotypes[i].Freq = float64(f.Haplotypes[i].Count) / float64(f.N)
+		}
+		sort.SliceStable(f.Haplotypes, func(i, j int) bool {
+			return f.Haplotypes[i].Count > f.Haplotypes[j].Count
+		})
+		r = append(r, f)
+	}
+
+	return r, nil
+}
+
+// maleXHaplotype returns the alleles of male s at the loci or nil if s is
+// not typed at all loci.
+func maleXHaplotype(s Sample, loci []string) ([]AlleleID, error) {
+	var h []AlleleID
+	for _, id := range loci {
+		a := typedAlleles(s.Locus(id))
+		switch len(a) {
+		case 0:
+			return nil, nil
+		case 1:
+			h = append(h, a[0])
+		default:
+			return nil, fmt.Errorf("male %v, locus %v: %v alleles", s.ID, id, len(a))
+		}
+	}
+	return h, nil
+}
+
+// AlleleFreq returns the frequency of allele a at locus lID amongst the
+// haplotypes of f, but at least 1/2N.
+func (f XHaploFreqs) AlleleFreq(lID string, a AlleleID) float64 {
+	j := indexOf(f.Loci, lID)
+	if j < 0 || f.N == 0 {
+		return 0
+	}
+	var n int
+	for _, h := range f.Haplotypes {
+		if h.Alleles[j] == a {
+			n += h.Count
+		}
+	}
+	return math.Max(float64(n), 0.5) / float64(f.N)
+}
+
+// indexOf returns the index of s in ss or -1.
+func indexOf(ss []string, s string) int {
+	for i, x := range ss {
+		if x == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// XKinshipLR returns the likelihood ratio of the X-STR profiles under the
+// pedigrees ped1 (Hp) and ped2 (Hd) for each linkage group of the Argus X-12
+// markers with haplotype frequencies in freqs. Males carry one X haplotype
+// from their mother; females carry the haplotype of their father and a
+// haplotype of their mother that recombines between the markers of a group
+// (Haldane's map function). Founder haplotypes have the frequencies of the
+// haplotypes of their group, smoothed by one pseudo-haplotype with the
+// product of the allele frequencies; haplotypes of alleles not in the
+// profiles are pooled. Markers of a group typed in none of the profiles are
+// skipped. Groups are independent; mutations are not considered. Persons in
+// both pedigrees must have the same sex, e.g. Unrelated(...).Males(...) for
+// unrelated men.
+func XKinshipLR(profiles []Sample, ped1, ped2 Pedigree, freqs []XHaploFreqs) (LRResult, error) {
+
+	var sorted [2][]PedPerson
+	for i, p := range []Pedigree{ped1, ped2} {
+		var err error
+		if sorted[i], err = p.sorted(); err != nil {
+			return LRResult{}, fmt.Errorf("pedigree %v: %w", p.Name, err)
+		}
+		for _, s := range profiles {
+			if _, ok := p.Person(s.ID); !ok {
+				return LRResult{}, fmt.Errorf("pedigree %v: no person %v", p.Name, s.ID)
+			}
+		}
+	}
+
+	// both pedigrees must agree on the sex of the persons
+	for _, x := range ped1.Persons {
+		if y, ok := ped2.Person(x.ID); ok && x.Male != y.Male {
+			return LRResult{}, fmt.Errorf("person %v: sex differs in pedigrees %v and %v", x.ID, ped1.Name, ped2.Name)
+		}
+	}
+
+	// males are hemizygous
+	for _, s := range profiles {
+		if p, _ := ped1.Person(s.ID); !p.Male {
+			continue
+		}
+		for _, m := range ArgusX12 {
+			if n := len(typedAlleles(s.Locus(m.ID))); n > 1 {
+				return LRResult{}, fmt.Errorf("male %v, locus %v: %v alleles", s.ID, m.ID, n)
+			}
+		}
+	}
+
+	r := LRResult{LR: 1}
+	var included int
+	for g := 1; g <= 4; g++ {
+
+		ll := LocusLR{Locus: fmt.Sprintf("LG%v", g)}
+		f, ok := xFreqsOf(freqs, g)
+		var loci []XMarker
+		for _, m := range XLinkageGroup(g) {
+			for _, s := range profiles {
+				if len(typedAlleles(s.Locus(m.ID))) > 0 {
+					loci = append(loci, m)
+					break
+				}
+			}
+		}
+		switch {
+		case len(loci) == 0:
+			ll.Excluded = "not typed"
+		case !ok || f.N == 0:
+			ll.Excluded = "no haplotype frequencies"
+		}
+
+		if ll.Excluded == "" {
+			x, err := newXGroup(profiles, loci, f)
+			if err != nil {
+				return LRResult{}, err
+			}
+			ll.Hp = x.likelihood(sorted[0])
+			ll.Hd = x.likelihood(sorted[1])
+			if ll.Hd == 0 {
+				return LRResult{}, fmt.Errorf("%v: profiles impossible under pedigree %v", ll.Locus, ped2.Name)
+			}
+			ll.LR = ll.Hp / ll.Hd
+			r.LR *= ll.LR
+			included++
+		}
+
+		r.Loci = append(r.Loci, ll)
+	}
+
+	if included == 0 {
+		return LRResult{}, fmt.Errorf("no typed linkage group with haplotype frequencies")
+	}
+
+	return r, nil
+}
+
+// XHalfSisterIndex returns the X-STR LR of 'a and b are paternal half
+// sisters' vs. 'a and b are unrelated'. Paternal half sisters share the X
+// haplotype of their father.
+func XHalfSisterIndex(a, b Sample, freqs []XHaploFreqs) (LRResult, error) {
+	sisters := Pedigree{Name: "paternal half sisters", Persons: []PedPerson{
+		{ID: "(father)", Male: true}, {ID: "(mother 1)"}, {ID: "(mother 2)"},
+		{ID: a.ID, Father: "(father)", Mother: "(mother 1)"},
+		{ID: b.ID, Father: "(father)", Mother: "(mother 2)"},
+	}}
+	return XKinshipLR([]Sample{a, b}, sisters, Unrelated(a.ID, b.ID), freqs)
+}
+
+// XGrandmotherIndex returns the X-STR LR of 'grandmother is the paternal
+// grandmother of granddaughter' vs. 'both are unrelated'.
+func XGrandmotherIndex(grandmother, granddaughter Sample, freqs []XHaploFreqs) (LRResult, error) {
+	ped := Pedigree{Name: "paternal grandmother", Persons: []PedPerson{
+		{ID: "(grandfather)", Male: true}, {ID: grandmother.ID},
+		{ID: "(father)", Father: "(grandfather)", Mother: grandmother.ID, Male: true},
+		{ID: "(mother)"},
+		{ID: granddaughter.ID, Father: "(father)", Mother: "(mother)"},
+	}}
+	return XKinshipLR([]Sample{grandmother, granddaughter}, ped,
+		Unrelated(grandmother.ID, granddaughter.ID), freqs)
+}
+
+// xFreqsOf returns the haplotype frequencies of linkage group g.
+func xFreqsOf(freqs []XHaploFreqs, g int) (XHaploFreqs, bool) {
+	for _, f := range freqs {
+		if f.Group == g {
+			return f, true
+		}
+	}
+	return XHaploFreqs{}, false
+}
+
+// xGroup holds the data of the pedigree likelihood of a linkage group.
+// Haplotypes are numbered in mixed radix of the allele numbers of the loci;
+// the last allele of each locus is the pooled allele Q.
+type xGroup struct {
+	alleles [][]AlleleID       // alleles of the profiles by locus, without Q
+	recomb  []float64          // recombination fractions to the previous locus
+	freq    []float64          // haplotype frequencies
+	gamete  []float64          // haplotype distribution of the gametes of a random female
+	typed   map[string][][]int // alleles of the typed persons by locus
+}
+
+// newXGroup prepares the pedigree likelihood of the linkage group of the
+// markers loci with the haplotype frequencies f.
+func newXGroup(profiles []Sample, loci []XMarker, f XHaploFreqs) (xGroup, error) {
+
+	x := xGroup{typed: make(map[string][][]int)}
+	for j, m := range loci {
+		var ids []AlleleID
+		for _, s := range profiles {
+			for _, a := range typedAlleles(s.Locus(m.ID)) {
+				if indexOfID(ids, a) < 0 {
+					ids = append(ids, a)
+				}
+			}
+		}
+		x.alleles = append(x.alleles, ids)
+		if j == 0 {
+			x.recomb = append(x.recomb, 0.5)
+		} else {
+			x.recomb = append(x.recomb, recombination(m.CM-loci[j-1].CM))
+		}
+	}
+
+	for _, s := range profiles {
+		var g [][]int
+		for _, m := range loci {
+			var a []int
+			for _, id := range typedAlleles(s.Locus(m.ID)) {
+				a = append(a, indexOfID(x.alleles[len(g)], id))
+			}
+			if len(a) > 2 {
+				return xGroup{}, fmt.Errorf("sample %v, locus %v: %v alleles", s.ID, m.ID, len(a))
+			}
+			g = append(g, a)
+		}
+		x.typed[s.ID] = g
+	}
+
+	// haplotype frequencies: counts of the database haplotypes and one
+	// pseudo-haplotype with the product of the allele frequencies
+	x.freq = make([]float64, x.haplotypes())
+	for h := range x.freq {
+		prod := 1.0
+		for j, a := range x.decode(h) {
+			if a < len(x.alleles[j]) {
+				prod *= f.AlleleFreq(loci[j].ID, x.alleles[j][a])
+			} else {
+				var sum float64
+				for _, id := range x.alleles[j] {
+					sum += f.AlleleFreq(loci[j].ID, id)
+				}
+				prod *= math.Max(0, 1-sum)
+			}
+		}
+		x.freq[h] = prod / float64(f.N+1)
+	}
+	for _, dbh := range f.Haplotypes {
+		h := 0
+		for j, m := range loci {
+			a := indexOfID(x.alleles[j], dbh.Alleles[indexOf(f.Loci, m.ID)])
+			if a < 0 {
+				a = len(x.alleles[j])
+			}
+			h = h*(len(x.alleles[j])+1) + a
+		}
+		x.freq[h] += float64(dbh.Count) / float64(f.N+1)
+	}
+
+	// the gametes of all pairs of haplotypes by the loci drawn from the
+	// second haplotype
+	x.gamete = make([]float64, len(x.freq))
+	for h1, f1 := range x.freq {
+		a1 := x.decode(h1)
+		for h2, f2 := range x.freq {
+			a2 := x.decode(h2)
+			for pattern := 0; pattern < 1<<len(loci); pattern++ {
+				t, prob := 0, 0.5
+				for j := range loci {
+					from := pattern >> j & 1
+					a := a1[j]
+					if from == 1 {
+						a = a2[j]
+					}
+					t = t*(len(x.alleles[j])+1) + a
+					if j > 0 && from != pattern>>(j-1)&1 {
+						prob *= x.recomb[j]
+					} else if j > 0 {
+						prob *= 1 - x.recomb[j]
+					}
+				}
+				x.gamete[t] += f1 * f2 * prob
+			}
+		}
+	}
+
+	return x, nil
+}
+
+// indexOfID returns the index of id in ids or -1.
+func indexOfID(ids []AlleleID, id AlleleID) int {
+	for i, x := range ids {
+		if x == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// haplotypes returns the number of haplotypes.
+func (x xGroup) haplotypes() int {
+	n := 1
+	for _, a := range x.alleles {
+		n *= len(a) + 1
+	}
+	return n
+}
+
+// decode returns the allele numbers of haplotype h.
+func (x xGroup) decode(h int) []int {
+	r := make([]int, len(x.alleles))
+	for j := len(x.alleles) - 1; j >= 0; j-- {
+		r[j] = h % (len(x.alleles[j]) + 1)
+		h /= len(x.alleles[j]) + 1
+	}
+	return r
+}
+
+// transmit returns the probability that a female with the haplotypes h1 and
+// h2 transmits haplotype t.
+func (x xGroup) transmit(h1, h2, t int) float64 {
+	a1, a2, at := x.decode(h1), x.decode(h2), x.decode(t)
+
+	// p[s] is the probability of the transmitted alleles so far with the
+	// current locus from haplotype s
+	var p [2]float64
+	for j := range at {
+		var next [2]float64
+		for s, a := range [2][]int{a1, a2} {
+			if a[j] != at[j] {
+				continue
+			}
+			if j == 0 {
+				next[s] = 0.5
+			} else {
+				next[s] = p[s]*(1-x.recomb[j]) + p[1-s]*x.recomb[j]
+			}
+		}
+		p = next
+	}
+	return p[0] + p[1]
+}
+
+// xState is the X chromosome state of a person: the haplotype of a male, or
+// the paternal and maternal haplotypes of a female.
+type xState [2]int
+
+// states returns the possible states of person p.
+func (x xGroup) states(p PedPerson) []xState {
+
+	g, typed := x.typed[p.ID]
+	var r []xState
+	for h := 0; h < len(x.freq); h++ {
+		if p.Male {
+			if !typed || x.consistent(g, x.decode(h), nil) {
+				r = append(r, xState{h, -1})
+			}
+			continue
+		}
+		for m := 0; m < len(x.freq); m++ {
+			if !typed || x.consistent(g, x.decode(h), x.decode(m)) {
+				r = append(r, xState{h, m})
+			}
+		}
+	}
+	return r
+}
+
+// consistent returns true if the haplotypes a and b (nil for males) show the
+// typed alleles g.
+func (x xGroup) consistent(g [][]int, a, b []int) bool {
+	for j, obs := range g {
+		switch {
+		case len(obs) == 0:
+		case b == nil:
+			if len(obs) != 1 || a[j] != obs[0] {
+				return false
+			}
+		case len(obs) == 1:
+			if a[j] != obs[0] || b[j] != obs[0] {
+				return false
+			}
+		default:
+			if !(a[j] == obs[0] && b[j] == obs[1] || a[j] == obs[1] && b[j] == obs[0]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// likelihood returns the probability of the typed X-STR profiles given the
+// persons sorted with parents before their children. Untyped female founders
+// with at most one child transmit a random gamete and are not enumerated.
+func (x xGroup) likelihood(persons []PedPerson) float64 {
+
+	children := make(map[string]int)
+	for _, p := range persons {
+		if !p.isFounder() {
+			children[p.Father]++
+			children[p.Mother]++
+		}
+	}
+	random := make(map[string]bool)
+	for _, p := range persons {
+		_, typed := x.typed[p.ID]
+		if p.isFounder() && !p.Male && !typed && children[p.ID] <= 1 {
+			random[p.ID] = true
+		}
+	}
+
+	states := make(map[string]xState)
+
+	// maternal returns the probability that the mother of p transmits t
+	maternal := func(p PedPerson, t int) float64 {
+		if random[p.Mother] {
+			return x.gamete[t]
+		}
+		m := states[p.Mother]
+		return x.transmit(m[0], m[1], t)
+	}
+
+	var walk func(i int) float64
+	walk = func(i int) float64 {
+		if i == len(persons) {
+			return 1
+		}
+		p := persons[i]
+		if random[p.ID] {
+			return walk(i + 1)
+		}
+
+		var r float64
+		for _, s := range x.states(p) {
+			var prob float64
+			switch {
+			case p.isFounder() && p.Male:
+				prob = x.freq[s[0]]
+			case p.isFounder():
+				prob = x.freq[s[0]] * x.freq[s[1]]
+			case p.Male:
+				prob = maternal(p, s[0])
+			default:
+				if states[p.Father][0] != s[0] {
+					continue
+				}
+				prob = maternal(p, s[1])
+			}
+			if prob == 0 {
+				continue
+			}
+			states[p.ID] = s
+			r += prob * walk(i+1)
+		}
+		return r
+	}
+
+	return walk(0)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// xRefs returns male references with LG1 haplotypes: 10 times 20-20-10,
+// 6 times 21-20-11, and 4 times 22-21-12.
+func xRefs() []Sample {
+	var refs []Sample
+	for i := 0; i < 20; i++ {
+		h := [3]string{"20", "20", "10"}
+		switch {
+		case i >= 16:
+			h = [3]string{"22", "21", "12"}
+		case i >= 10:
+			h = [3]string{"21", "20", "11"}
+		}
+		refs = append(refs, peaks(fmt.Sprint("M", i), "AMEL:X", "AMEL:Y",
+			"DXS10148:"+h[0], "DXS10135:"+h[1], "DXS8378:"+h[2]))
+	}
+	return append(refs, peaks("F", "AMEL:X", "DXS10148:20", "DXS10148:21"))
+}
+
+// =============================================================================
+func Test_BuildXHaploFreqs(t *testing.T) {
+
+	freqs, err := BuildXHaploFreqs(xRefs())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(freqs) != 4 || freqs[0].N != 20 || len(freqs[0].Haplotypes) != 3 || freqs[1].N != 0 {
+		t.Fatalf("unexpected frequencies: %+v", freqs)
+	}
+	if h := freqs[0].Haplotypes[0]; h.Count != 10 || h.Freq != 0.5 || h.Alleles[2] != A2ID("10") {
+		t.Fatalf("expected: 20-20-10 with count 10, got: %+v", h)
+	}
+	if f := freqs[0].AlleleFreq("DXS10135", A2ID("20")); f != 0.8 {
+		t.Fatalf("expected: 0.8, got: %v", f)
+	}
+
+	bad := append(xRefs(), peaks("M", "AMEL:X", "AMEL:Y", "DXS10148:20", "DXS10148:21",
+		"DXS10135:20", "DXS8378:10"))
+	if _, err := BuildXHaploFreqs(bad); err == nil {
+		t.Fatalf("expected error for heterozygous male")
+	}
+}
+
+// =============================================================================
+func Test_XKinshipLR(t *testing.T) {
+
+	freqs, _ := BuildXHaploFreqs(xRefs())
+	p := func(a string) float64 { return freqs[0].AlleleFreq("DXS10148", A2ID(a)) }
+
+	type test struct {
+		lr   func() (LRResult, error)
+		want float64
+	}
+
+	tests := []test{
+		{ // 1: paternal half sisters share the X of their father: 1/p
+			func() (LRResult, error) {
+				return XHalfSisterIndex(peaks("A", "DXS10148:20"), peaks("B", "DXS10148:20"), freqs)
+			}, 1 / p("20"),
+		},
+		{ // 2: no shared allele: excluded
+			func() (LRResult, error) {
+				return XHalfSisterIndex(peaks("A", "DXS10148:20"), peaks("B", "DXS10148:21", "DXS10148:22"), freqs)
+			}, 0,
+		},
+		{ // 3: paternal grandmother a/b, granddaughter a/c: 1/4p(a)
+			func() (LRResult, error) {
+				return XGrandmotherIndex(peaks("GM", "DXS10148:20", "DXS10148:21"),
+					peaks("GD", "DXS10148:20", "DXS10148:22"), freqs)
+			}, 1 / (4 * p("20")),
+		},
+	}
+
+	for i, test := range tests {
+		r, err := test.lr()
+		if err != nil || math.Abs(r.LR-test.want) > 1e-9*test.want {
+			t.Fatalf("test %d: expected: %v, got: %v (%v)", i+1, test.want, r.LR, err)
+		}
+		if len(r.Loci) != 4 || r.Loci[0].Locus != "LG1" || r.Loci[1].Excluded != "not typed" {
+			t.Fatalf("test %d: unexpected linkage groups: %+v", i+1, r.Loci)
+		}
+	}
+
+	// sharing a complete haplotype supports half sisters more than sharing
+	// the alleles of unlinked markers
+	a := peaks("A", "DXS10148:22", "DXS10135:21", "DXS8378:12", "DXS10148:20", "DXS10135:20", "DXS8378:10")
+	b := peaks("B", "DXS10148:22", "DXS10135:21", "DXS8378:12", "DXS10148:21", "DXS10135:20", "DXS8378:11")
+	r, err := XHalfSisterIndex(a, b, freqs)
+	if err != nil || r.LR <= 1 {
+		t.Fatalf("expected LR > 1, got: %v (%v)", r.LR, err)
+	}
+
+	ped := Pedigree{Name: "father", Persons: []PedPerson{{ID: "F", Male: true}, {ID: "M"},
+		{ID: "D", Father: "F", Mother: "M"}}}
+	if _, err := XKinshipLR([]Sample{peaks("F", "DXS10148:20", "DXS10148:21"), peaks("D", "DXS10148:20")},
+		ped, Unrelated("F", "D").Males("F"), freqs); err == nil {
+		t.Fatalf("expected error for heterozygous male")
+	}
+
+	// father 21, daughter 20/21: 1/2p
+	fd := []Sample{peaks("F", "DXS10148:21"), peaks("D", "DXS10148:20", "DXS10148:21")}
+	r, err = XKinshipLR(fd, ped, Unrelated("F", "D").Males("F"), freqs)
+	if want := 1 / (2 * p("21")); err != nil || math.Abs(r.LR-want) > 1e-9*want {
+		t.Fatalf("father-daughter: expected: %v, got: %v (%v)", want, r.LR, err)
+	}
+	if _, err := XKinshipLR(fd, ped, Unrelated("F", "D"), freqs); err == nil {
+		t.Fatalf("expected error for sex differing in the pedigrees")
+	}
+}
+
+// =============================================================================
+func Test_xGroup_transmit(t *testing.T) {
+
+	freqs, _ := BuildXHaploFreqs(xRefs())
+	mother := peaks("M", "DXS10148:20", "DXS10148:21", "DXS10135:20", "DXS10135:21",
+		"DXS8378:10", "DXS8378:11")
+	x, err := newXGroup([]Sample{mother}, XLinkageGroup(1), freqs[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// haplotypes 20-20-10 and 21-21-11; the child receives 20-20-11
+	enc := func(a ...int) int { return (a[0]*3+a[1])*3 + a[2] }
+	r1, r2 := recombination(9.35-8.93), recombination(10.64-9.35)
+	want := 0.5 * (1 - r1) * r2
+	if res := x.transmit(enc(0, 0, 0), enc(1, 1, 1), enc(0, 0, 1)); math.Abs(res-want) > 1e-12 {
+		t.Fatalf("expected: %v, got: %v", want, res)
+	}
+
+	var sum float64
+	for _, g := range x.gamete {
+		sum += g
+	}
+	if math.Abs(sum-1) > 0.05 {
+		t.Fatalf("expected gamete probabilities summing to about 1, got: %v", sum)
+	}
+}
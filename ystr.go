@@ -0,0 +1,564 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// YMultiCopy holds the number of copies of the multi-copy Y-STR loci.
+var YMultiCopy = map[string]int{
+	"DYS385":   2,
+	"DYF387S1": 2,
+}
+
+// RMYSTR holds the rapidly mutating Y-STR loci (mutation rates > 1e-2,
+// Ballantyne et al. 2010).
+var RMYSTR = []string{
+	"DYF387S1", "DYF399S1", "DYF403S1A", "DYF403S1B", "DYF404S1", "DYS449",
+	"DYS518", "DYS526A", "DYS526B", "DYS547", "DYS570", "DYS576", "DYS612",
+	"DYS626", "DYS627",
+}
+
+// IsRMYSTR returns true if the Y locus with ID id is a rapidly mutating Y-STR.
+func IsRMYSTR(id string) bool {
+	return slices.Contains(RMYSTR, NormalizeYLocus(id))
+}
+
+// NormalizeYLocus returns the Y locus ID id in upper case without the copy
+// suffix of multi-copy loci, e.g. DYS385 for 'DYS385 a/b' or 'DYS385ab'.
+func NormalizeYLocus(id string) string {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	for _, suffix := range []string{" A/B", "A/B", " AB", "AB"} {
+		if base := strings.TrimSuffix(id, suffix); base != id {
+			if _, ok := YMultiCopy[base]; ok {
+				return base
+			}
+		}
+	}
+	return id
+}
+
+// Haplotype holds the Y-STR alleles of a male by normalized locus ID (see
+// NormalizeYLocus). Multi-copy loci hold as many alleles as copies, sorted;
+// a single allele is duplicated.
+type Haplotype struct {
+	ID   string
+	Loci map[string][]AlleleID
+}
+
+// Haplotype returns the Y-STR haplotype of s: the typed alleles of all
+// Y-linked loci. Other loci are ignored.
+func (s Sample) Haplotype() Haplotype {
+
+	h := Haplotype{ID: s.ID, Loci: make(map[string][]AlleleID)}
+	for _, l := range s.Loci {
+		id := NormalizeYLocus(l.ID)
+		if (Locus{ID: id}).Linkage() != YLINKED {
+			continue
+		}
+		ids := typedAlleles(l)
+		if len(ids) == 0 {
+			continue
+		}
+		if n := YMultiCopy[id]; len(ids) == 1 && n > 1 {
+			for len(ids) < n {
+				ids = append(ids, ids[0])
+			}
+		}
+		h.Loci[id] = ids
+	}
+
+	return h
+}
+
+// LocusIDs returns the sorted IDs of the loci of h.
+func (h Haplotype) LocusIDs() []string {
+	var ids []string
+	for id := range h.Loci {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RMYLoci returns the sorted IDs of the rapidly mutating loci of h.
+func (h Haplotype) RMYLoci() []string {
+	var ids []string
+	for _, id := range h.LocusIDs() {
+		if IsRMYSTR(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// key returns the alleles of h at the loci as string.
+func (h Haplotype) key(loci []string) string {
+	var b strings.Builder
+	for _, id := range loci {
+		b.WriteString(id)
+		for _, a := range h.Loci[id] {
+			b.WriteString(" " + a.String())
+		}
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+// hasLoci returns true if h is typed at all loci.
+func (h Haplotype) hasLoci(loci []string) bool {
+	for _, id := range loci {
+		if _, ok := h.Loci[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// YComparison holds the differences of two haplotypes at their shared loci.
+type YComparison struct {
+	Loci       int      // number of shared loci
+	Mismatches int      // number of shared loci with different alleles
+	Steps      int      // number of repeat steps of all differences
+	Mismatched []string // loci with different alleles
+	RMY        []string // rapidly mutating loci amongst Mismatched
+}
+
+// CompareHaplotypes compares the haplotypes h1 and h2 at their shared loci.
+// Alleles are compared in order; a difference of n repeats counts as n steps,
+// a difference of the fractional repeat as one further step, and missing or
+// extra alleles of multi-copy loci as one step each. Mismatches at rapidly
+// mutating loci are expected between paternal relatives and flagged in RMY.
+func CompareHaplotypes(h1, h2 Haplotype) YComparison {
+
+	var r YComparison
+	for _, id := range h1.LocusIDs() {
+		a2, ok := h2.Loci[id]
+		if !ok {
+			continue
+		}
+		a1 := h1.Loci[id]
+		r.Loci++
+		if slices.Equal(a1, a2) {
+			continue
+		}
+
+		r.Mismatches++
+		r.Mismatched = append(r.Mismatched, id)
+		if IsRMYSTR(id) {
+			r.RMY = append(r.RMY, id)
+		}
+		for i := 0; i < len(a1) || i < len(a2); i++ {
+			switch {
+			case i >= len(a1) || i >= len(a2):
+				r.Steps++
+			default:
+				d := a1[i].Repeats - a2[i].Repeats
+				if d < 0 {
+					d = -d
+				}
+				r.Steps += d
+				if a1[i].Partial != a2[i].Partial {
+					r.Steps++
+				}
+			}
+		}
+	}
+
+	return r
+}
+
+// HaplotypeDB is a database of Y-STR haplotypes of a population.
+type HaplotypeDB struct {
+	Pop        string
+	Haplotypes []Haplotype
+}
+
+// BuildHaplotypeDB returns the database of the haplotypes of all references
+// with Y-STRs.
+func BuildHaplotypeDB(refs []Sample, pop string) HaplotypeDB {
+	db := HaplotypeDB{Pop: pop}
+	for _, s := range refs {
+		if h := s.Haplotype(); len(h.Loci) > 0 {
+			db.Haplotypes = append(db.Haplotypes, h)
+		}
+	}
+	return db
+}
+
+// YFreq holds the frequency of a haplotype.
+type YFreq struct {
+	Loci  []string // loci of the comparison
+	Count int      // number of matching haplotypes in the database
+	N     int      // number of haplotypes typed at all loci
+	Freq  float64  // Count/N
+	Lower float64  // lower bound of the confidence interval
+	Upper float64  // upper bound of the confidence interval
+}
+
+// CountingFreq returns the frequency of haplotype h by the counting method
+// over the database haplotypes typed at all loci of h, with the two-sided
+// Clopper–Pearson confidence interval at level (e.g. 0.95). For a haplotype
+// not observed, the upper bound is 1 - ((1-level)/2)^(1/N).
+func (db HaplotypeDB) CountingFreq(h Haplotype, level float64) (YFreq, error) {
+
+	if level <= 0 || level >= 1 {
+		return YFreq{}, fmt.Errorf("confidence level %v not in (0, 1)", level)
+	}
+
+	r := YFreq{Loci: h.LocusIDs()}
+	if len(r.Loci) == 0 {
+		return YFreq{}, fmt.Errorf("haplotype %v: no Y loci", h.ID)
+	}
+
+	key := h.key(r.Loci)
+	for _, x := range db.Haplotypes {
+		if !x.hasLoci(r.Loci) {
+			continue
+		}
+		r.N++
+		if x.key(r.Loci) == key {
+			r.Count++
+		}
+	}
+	if r.N == 0 {
+		return YFreq{}, fmt.Errorf("haplotype %v: no database haplotype typed at all loci", h.ID)
+	}
+
+	r.Freq = float64(r.Count) / float64(r.N)
+	r.Lower, r.Upper = clopperPearson(r.Count, r.N, 1-level)
+
+	return r, nil
+}
+
+// clopperPearson returns the exact two-sided confidence interval of a
+// binomial proportion of x in n at significance level alpha.
+func clopperPearson(x, n int, alpha float64) (float64, float64) {
+
+	// bisect returns p in [0, 1] with f(p) = target for f decreasing in p
+	bisect := func(f func(p float64) float64, target float64) float64 {
+		lo, hi := 0.0, 1.0
+		for i := 0; i < 100; i++ {
+			mid := (lo + hi) / 2
+			if f(mid) > target {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		return (lo + hi) / 2
+	}
+
+	lower, upper := 0.0, 1.0
+	if x > 0 { // P(X >= x) = alpha/2
+		lower = bisect(func(p float64) float64 { return binomialCDF(x-1, n, p) }, 1-alpha/2)
+	}
+	if x < n { // P(X <= x) = alpha/2
+		upper = bisect(func(p float64) float64 { return binomialCDF(x, n, p) }, alpha/2)
+	}
+
+	return lower, upper
+}
+
+// binomialCDF returns P(X <= x) for X ~ Binomial(n, p).
+func binomialCDF(x, n int, p float64) float64 {
+	switch {
+	case x < 0:
+		return 0
+	case x >= n || p == 0:
+		return 1
+	case p == 1:
+		return 0
+	}
+	lgn, _ := math.Lgamma(float64(n + 1))
+	var sum float64
+	for k := 0; k <= x; k++ {
+		lgk, _ := math.Lgamma(float64(k + 1))
+		lgnk, _ := math.Lgamma(float64(n - k + 1))
+		sum += math.Exp(lgn - lgk - lgnk + float64(k)*math.Log(p) + float64(n-k)*math.Log(1-p))
+	}
+	return math.Min(sum, 1)
+}
+
+// DiscreteLaplace is a mixture of discrete Laplace distributions of the
+// repeat numbers of Y-STR haplotypes (Andersen et al. 2013). Each cluster has
+// a central haplotype and a dispersion per locus; the loci of a cluster are
+// independent.
+type DiscreteLaplace struct {
+	// Loci of the model; the copies of multi-copy loci are numbered, e.g.
+	// DYS385.1 and DYS385.2.
+	Loci    []string
+	Weights []float64   // weights of the clusters
+	Centers [][]int     // central repeat numbers by cluster and locus
+	Disp    [][]float64 // dispersion parameters by cluster and locus
+	LogLik  float64
+	BIC     float64
+}
+
+// dlMaxClusters is the largest number of clusters selected by BIC.
+const dlMaxClusters = 5
+
+// FitDiscreteLaplace fits a discrete Laplace mixture with the number of
+// clusters to the haplotypes of db by the EM algorithm; with clusters = 0,
+// the number of clusters with the lowest BIC up to 5 is chosen. The model
+// uses the loci typed in all haplotypes with the right number of copies;
+// seed seeds the choice of the initial centers.
+func (db HaplotypeDB) FitDiscreteLaplace(clusters int, seed int64) (DiscreteLaplace, error) {
+
+	loci := db.commonLoci()
+	if len(loci) == 0 {
+		return DiscreteLaplace{}, fmt.Errorf("no locus typed in all haplotypes")
+	}
+
+	var data [][]int
+	for _, h := range db.Haplotypes {
+		if v, ok := dlVector(h, loci); ok {
+			data = append(data, v)
+		}
+	}
+	if len(data) < 2 {
+		return DiscreteLaplace{}, fmt.Errorf("%v haplotypes, need at least 2", len(data))
+	}
+
+	if clusters > 0 {
+		m := fitDL(data, clusters, seed)
+		m.Loci = dlLoci(loci)
+		return m, nil
+	}
+
+	var best DiscreteLaplace
+	for c := 1; c <= dlMaxClusters && c <= len(data); c++ {
+		m := fitDL(data, c, seed)
+		if c == 1 || m.BIC < best.BIC {
+			best = m
+		}
+	}
+	best.Loci = dlLoci(loci)
+
+	return best, nil
+}
+
+// commonLoci returns the sorted loci typed in all haplotypes of db.
+func (db HaplotypeDB) commonLoci() []string {
+	if len(db.Haplotypes) == 0 {
+		return nil
+	}
+	var loci []string
+	for _, id := range db.Haplotypes[0].LocusIDs() {
+		n := 0
+		for _, h := range db.Haplotypes {
+			if a, ok := h.Loci[id]; ok && len(a) == dlCopies(id) {
+				n++
+			}
+		}
+		if n == len(db.Haplotypes) {
+			loci = append(loci, id)
+		}
+	}
+	return loci
+}
+
+// dlCopies returns the number of copies of locus id.
+func dlCopies(id string) int {
+	if n, ok := YMultiCopy[id]; ok {
+		return n
+	}
+	return 1
+}
+
+// dlLoci returns the loci with numbered copies of multi-copy loci.
+func dlLoci(loci []string) []string {
+	var r []string
+	for _, id := range loci {
+		if n := dlCopies(id); n > 1 {
+			for i := 1; i <= n; i++ {
+				r = append(r, fmt.Sprintf("%v.%v", id, i))
+			}
+		} else {
+			r = append(r, id)
+		}
+	}
+	return r
+}
+
+// dlVector returns the repeat numbers of h at the loci or false if h is not
+// typed at all loci with the right number of copies.
+func dlVector(h Haplotype, loci []string) ([]int, bool) {
+	var v []int
+	for _, id := range loci {
+		a, ok := h.Loci[id]
+		if !ok || len(a) != dlCopies(id) {
+			return nil, false
+		}
+		for _, x := range a {
+			v = append(v, x.Repeats)
+		}
+	}
+	return v, true
+}
+
+// dlLogProb returns the log probability of a difference of d repeats with
+// dispersion p: log((1-p)/(1+p) p^|d|).
+func dlLogProb(d int, p float64) float64 {
+	if d < 0 {
+		d = -d
+	}
+	return math.Log((1-p)/(1+p)) + float64(d)*math.Log(p)
+}
+
+// dlMinDisp is the smallest dispersion of a locus without variation.
+const dlMinDisp = 1e-6
+
+// fitDL fits a discrete Laplace mixture with c clusters to data.
+func fitDL(data [][]int, c int, seed int64) DiscreteLaplace {
+
+	n, l := len(data), len(data[0])
+	rng := rand.New(rand.NewSource(seed))
+
+	// initial centers: a random haplotype and then the haplotypes farthest
+	// from all centers chosen
+	m := DiscreteLaplace{Weights: make([]float64, c)}
+	m.Centers = append(m.Centers, append([]int(nil), data[rng.Intn(n)]...))
+	for len(m.Centers) < c {
+		far, farDist := 0, -1
+		for i, x := range data {
+			d := math.MaxInt
+			for _, y := range m.Centers {
+				d = min(d, l1Distance(x, y))
+			}
+			if d > farDist {
+				far, farDist = i, d
+			}
+		}
+		m.Centers = append(m.Centers, append([]int(nil), data[far]...))
+	}
+	for k := range m.Weights {
+		m.Weights[k] = 1 / float64(c)
+		disp := make([]float64, l)
+		for j := range disp {
+			disp[j] = 0.3
+		}
+		m.Disp = append(m.Disp, disp)
+	}
+
+	resp := make([][]float64, n)
+	for i := range resp {
+		resp[i] = make([]float64, c)
+	}
+
+	prev := math.Inf(-1)
+	for iter := 0; iter < 500; iter++ {
+
+		// E-step
+		m.LogLik = 0
+		lp := make([]float64, c)
+		for i, x := range data {
+			for k := range lp {
+				lp[k] = math.Log(m.Weights[k])
+				for j := range x {
+					lp[k] += dlLogProb(x[j]-m.Centers[k][j], m.Disp[k][j])
+				}
+			}
+			norm := logSumExp(lp)
+			m.LogLik += norm
+			for k := range lp {
+				resp[i][k] = math.Exp(lp[k] - norm)
+			}
+		}
+		if m.LogLik-prev < 1e-8 {
+			break
+		}
+		prev = m.LogLik
+
+		// M-step: weights, weighted medians and dispersions
+		for k := 0; k < c; k++ {
+			var w float64
+			for i := range data {
+				w += resp[i][k]
+			}
+			m.Weights[k] = math.Max(w/float64(n), 1e-10)
+			for j := 0; j < l; j++ {
+				m.Centers[k][j] = weightedMedian(data, resp, j, k)
+				var dev float64
+				for i, x := range data {
+					dev += resp[i][k] * math.Abs(float64(x[j]-m.Centers[k][j]))
+				}
+				if w > 0 {
+					dev /= w
+				}
+				m.Disp[k][j] = math.Max(dlMinDisp, (math.Sqrt(1+dev*dev)-1)/math.Max(dev, dlMinDisp))
+			}
+		}
+	}
+
+	params := float64(c-1) + 2*float64(c*l)
+	m.BIC = -2*m.LogLik + params*math.Log(float64(n))
+
+	return m
+}
+
+// l1Distance returns the sum of the absolute differences of x and y.
+func l1Distance(x, y []int) int {
+	var d int
+	for i := range x {
+		if x[i] > y[i] {
+			d += x[i] - y[i]
+		} else {
+			d += y[i] - x[i]
+		}
+	}
+	return d
+}
+
+// weightedMedian returns the median of the values at locus j weighted by the
+// responsibilities of cluster k.
+func weightedMedian(data [][]int, resp [][]float64, j, k int) int {
+	idx := make([]int, len(data))
+	var total float64
+	for i := range idx {
+		idx[i] = i
+		total += resp[i][k]
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return data[idx[a]][j] < data[idx[b]][j] })
+	var sum float64
+	for _, i := range idx {
+		sum += resp[i][k]
+		if sum >= total/2 {
+			return data[i][j]
+		}
+	}
+	return data[idx[len(idx)-1]][j]
+}
+
+// Freq returns the frequency of haplotype h under the discrete Laplace model.
+// h must be typed at all loci of the model.
+func (m DiscreteLaplace) Freq(h Haplotype) (float64, error) {
+
+	var loci []string
+	for _, id := range m.Loci {
+		base, _, _ := strings.Cut(id, ".")
+		if !slices.Contains(loci, base) {
+			loci = append(loci, base)
+		}
+	}
+	x, ok := dlVector(h, loci)
+	if !ok {
+		return 0, fmt.Errorf("haplotype %v: not typed at all loci of the model", h.ID)
+	}
+
+	lp := make([]float64, len(m.Weights))
+	for k := range lp {
+		lp[k] = math.Log(m.Weights[k])
+		for j := range x {
+			lp[k] += dlLogProb(x[j]-m.Centers[k][j], m.Disp[k][j])
+		}
+	}
+	return math.Exp(logSumExp(lp)), nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2017-2022 Roland Schultheiß. All rights reserved.
+// License information can be found in the LICENSE file.
+
+package forge
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// =============================================================================
+func Test_NormalizeYLocus(t *testing.T) {
+
+	tests := map[string]string{
+		"DYS385 a/b": "DYS385",
+		"DYS385ab":   "DYS385",
+		"dyf387s1":   "DYF387S1",
+		"DYS19":      "DYS19",
+		"DYS526AB":   "DYS526AB",
+	}
+
+	for in, want := range tests {
+		if res := NormalizeYLocus(in); res != want {
+			t.Fatalf("%v: expected: %v, got: %v", in, want, res)
+		}
+	}
+}
+
+// =============================================================================
+func TestSample_Haplotype(t *testing.T) {
+
+	s := peaks("male", "DYS19:14", "DYS385 a/b:11", "DYF387S1:35", "DYF387S1:38",
+		"VWA:14", "VWA:17", "DYS576:18")
+
+	want := map[string][]AlleleID{
+		"DYS19":    {A2ID("14")},
+		"DYS385":   {A2ID("11"), A2ID("11")},
+		"DYF387S1": {A2ID("35"), A2ID("38")},
+		"DYS576":   {A2ID("18")},
+	}
+	h := s.Haplotype()
+	if !reflect.DeepEqual(h.Loci, want) {
+		t.Fatalf("expected: %v, got: %v", want, h.Loci)
+	}
+	if res := h.RMYLoci(); !reflect.DeepEqual(res, []string{"DYF387S1", "DYS576"}) {
+		t.Fatalf("expected RM-Y loci DYF387S1, DYS576, got: %v", res)
+	}
+}
+
+// =============================================================================
+func Test_CompareHaplotypes(t *testing.T) {
+
+	h1 := peaks("1", "DYS19:14", "DYS385:11", "DYS385:14", "DYS576:18", "DYS390:23").Haplotype()
+	h2 := peaks("2", "DYS19:14", "DYS385:11", "DYS385:15", "DYS576:20", "DYS391:10").Haplotype()
+
+	want := YComparison{Loci: 3, Mismatches: 2, Steps: 3,
+		Mismatched: []string{"DYS385", "DYS576"}, RMY: []string{"DYS576"}}
+	if res := CompareHaplotypes(h1, h2); !reflect.DeepEqual(res, want) {
+		t.Fatalf("expected: %+v, got: %+v", want, res)
+	}
+}
+
+// =============================================================================
+func TestHaplotypeDB_CountingFreq(t *testing.T) {
+
+	var refs []Sample
+	for i := 0; i < 100; i++ {
+		s := peaks(fmt.Sprint(i), "DYS19:"+fmt.Sprint(13+i%5), "DYS390:"+fmt.Sprint(21+i%7))
+		refs = append(refs, s)
+	}
+	refs = append(refs, peaks("female", "VWA:14"), peaks("partial", "DYS19:13"))
+	db := BuildHaplotypeDB(refs, "test")
+	if len(db.Haplotypes) != 101 {
+		t.Fatalf("expected 101 haplotypes, got: %v", len(db.Haplotypes))
+	}
+
+	// not observed in 100 haplotypes: upper bound 1 - 0.025^(1/100)
+	r, err := db.CountingFreq(peaks("q", "DYS19:20", "DYS390:21").Haplotype(), 0.95)
+	if err != nil || r.Count != 0 || r.N != 100 || math.Abs(r.Upper-(1-math.Pow(0.025, 0.01))) > 1e-9 {
+		t.Fatalf("test 1: unexpected result %+v (%v)", r, err)
+	}
+
+	// 13/21 is observed for i = 0, 35, 70
+	r, err = db.CountingFreq(peaks("q", "DYS19:13", "DYS390:21").Haplotype(), 0.95)
+	if err != nil || r.Count != 3 || r.Freq != 0.03 || r.Lower > 0.03 || r.Upper < 0.03 ||
+		math.Abs(r.Lower-0.00623) > 1e-4 || math.Abs(r.Upper-0.08518) > 1e-4 {
+		t.Fatalf("test 2: unexpected result %+v (%v)", r, err)
+	}
+
+	if _, err := db.CountingFreq(peaks("q", "VWA:14").Haplotype(), 0.95); err == nil {
+		t.Fatalf("test 3: expected error for haplotype without Y loci")
+	}
+}
+
+// =============================================================================
+func TestHaplotypeDB_FitDiscreteLaplace(t *testing.T) {
+
+	// two haplogroups around 14-23-11/14 and 17-25-13/17
+	rng := rand.New(rand.NewSource(1))
+	step := func() int { return []int{-1, 0, 0, 0, 0, 1}[rng.Intn(6)] }
+	var refs []Sample
+	for i := 0; i < 200; i++ {
+		c := [4]int{14, 23, 11, 14}
+		if i%2 == 1 {
+			c = [4]int{17, 25, 13, 17}
+		}
+		refs = append(refs, peaks(fmt.Sprint(i), fmt.Sprintf("DYS19:%v", c[0]+step()),
+			fmt.Sprintf("DYS390:%v", c[1]+step()), fmt.Sprintf("DYS385:%v", c[2]+step()),
+			fmt.Sprintf("DYS385:%v", c[3]+step())))
+	}
+	db := BuildHaplotypeDB(refs, "test")
+
+	m, err := db.FitDiscreteLaplace(0, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Weights) != 2 || !reflect.DeepEqual(m.Loci, []string{"DYS19", "DYS385.1", "DYS385.2", "DYS390"}) {
+		t.Fatalf("expected 2 clusters of 4 loci, got: %v %v", m.Weights, m.Loci)
+	}
+
+	// central haplotypes are frequent, haplotypes between the clusters rare
+	// but not impossible
+	center, _ := m.Freq(peaks("c", "DYS19:14", "DYS390:23", "DYS385:11", "DYS385:14").Haplotype())
+	rare, _ := m.Freq(peaks("r", "DYS19:16", "DYS390:24", "DYS385:12", "DYS385:16").Haplotype())
+	if center < 0.05 || rare <= 0 || rare > 1e-3 {
+		t.Fatalf("unexpected frequencies: center %v, rare %v", center, rare)
+	}
+
+	if _, err := m.Freq(peaks("x", "DYS19:14").Haplotype()); err == nil {
+		t.Fatalf("expected error for incomplete haplotype")
+	}
+}